@@ -0,0 +1,155 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ItemResult is the result of writing one item of a bulk write with the
+// WithItemResults(...) option.
+type ItemResult struct {
+	// Index of the item in the bulk write's slice of items
+	Index int
+
+	// Error is the reason the item wasn't written, which is nil when the
+	// item was written.
+	Error error
+
+	// RowsAffected by writing the item
+	RowsAffected int64
+}
+
+// errBatchRowsAffected is returned by a batch whose rows affected can't be
+// attributed to its items, so it's rolled back and its items are written one
+// at a time.
+var errBatchRowsAffected = errors.New("batch rows affected don't match its items")
+
+// writeItemFn writes the items of a bulk write, returning the rows affected
+type writeItemFn func(ctx context.Context, rw *RW, items interface{}, opt ...Option) (int64, error)
+
+// writeItemsWithResults writes the items on a best effort basis for the
+// WithItemResults(...) option, and returns the total rows affected.
+//
+// The items are written in batches of WithBatchSize.  Each batch is written in
+// a transaction (or a savepoint when the rw is already in a transaction), and
+// when the batch fails or some of its items didn't affect a row, it's rolled
+// back and its items are written one at a time.  Within a transaction, each
+// item is written in its own savepoint, so a failed item doesn't abort the
+// transaction.  The before and after write funcs are called once with all the
+// items.
+func (rw *RW) writeItemsWithResults(ctx context.Context, items reflect.Value, opt []Option, write writeItemFn) (_ int64, retErr error) {
+	const op = "dbw.writeItemsWithResults"
+	opts := GetOpts(opt...)
+	var foundType reflect.Type
+	for i := 0; i < items.Len(); i++ {
+		item := items.Index(i).Interface()
+		if i == 0 {
+			foundType = reflect.TypeOf(item)
+		}
+		switch currentType := reflect.TypeOf(item); {
+		case isNil(item) || currentType == nil:
+			return noRowsAffected, fmt.Errorf("%s: unable to determine type of item %d: %w", op, i, ErrInvalidParameter)
+		case foundType != currentType:
+			return noRowsAffected, fmt.Errorf("%s: items contain disparate types. item %d is not a %s: %w", op, i, foundType.Name(), ErrInvalidParameter)
+		}
+	}
+	if opts.WithBeforeWrite != nil {
+		if err := opts.WithBeforeWrite(items.Interface()); err != nil {
+			return noRowsAffected, fmt.Errorf("%s: error before write: %w", op, err)
+		}
+	}
+	rw, ctx, done, err := rw.startTimeout(ctx, opts)
+	if err != nil {
+		return noRowsAffected, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { retErr = done(retErr) }()
+
+	// the batches and items are written without the options which apply to
+	// the whole bulk write.
+	opt = append(opt[:len(opt):len(opt)], WithBeforeWrite(nil), WithAfterWrite(nil), WithReturnRowsAffected(nil), WithItemResults(nil))
+	// isolate runs fn in a transaction, or a savepoint when the rw is already
+	// in a transaction.
+	isolate := func(fn func(*RW) error) error {
+		if rw.IsTx() {
+			return rw.savepoint(ctx, fn)
+		}
+		_, err := rw.DoTx(ctx, func(error) bool { return false }, 0, ConstBackoff{}, func(_ Reader, w Writer) error {
+			return fn(w.(*RW))
+		})
+		return err
+	}
+	batchSize := opts.WithBatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	if opts.WithOnConflict != nil && !opts.WithOnConflict.updates() {
+		// the rows affected by a batch which does nothing on conflict aren't
+		// reported reliably when the insert returns columns, so the items
+		// are written one at a time.
+		batchSize = 1
+	}
+
+	results := make([]ItemResult, items.Len())
+	var rowsAffected int64
+	for start := 0; start < items.Len(); start += batchSize {
+		end := start + batchSize
+		if end > items.Len() {
+			end = items.Len()
+		}
+		if end-start > 1 {
+			err := isolate(func(w *RW) error {
+				n, err := write(ctx, w, items.Slice(start, end).Interface(), opt...)
+				switch {
+				case err != nil:
+					return err
+				case n != int64(end-start):
+					return errBatchRowsAffected
+				}
+				return nil
+			})
+			if err == nil {
+				for i := start; i < end; i++ {
+					results[i] = ItemResult{Index: i, RowsAffected: 1}
+				}
+				rowsAffected += int64(end - start)
+				continue
+			}
+		}
+		for i := start; i < end; i++ {
+			var n int64
+			writeItem := func(w *RW) error {
+				var err error
+				n, err = write(ctx, w, items.Slice(i, i+1).Interface(), opt...)
+				return err
+			}
+			var err error
+			switch {
+			case rw.IsTx():
+				err = rw.savepoint(ctx, writeItem)
+			default:
+				err = writeItem(rw)
+			}
+			if err != nil {
+				n = 0
+			}
+			results[i] = ItemResult{Index: i, Error: err, RowsAffected: n}
+			rowsAffected += n
+		}
+	}
+
+	*opts.WithItemResults = results
+	if opts.WithRowsAffected != nil {
+		*opts.WithRowsAffected = rowsAffected
+	}
+	if rowsAffected > 0 && opts.WithAfterWrite != nil {
+		if err := opts.WithAfterWrite(items.Interface(), int(rowsAffected)); err != nil {
+			return rowsAffected, fmt.Errorf("%s: error after write: %w", op, err)
+		}
+	}
+	return rowsAffected, nil
+}
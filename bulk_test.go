@@ -0,0 +1,120 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/go-dbw"
+	"github.com/hashicorp/go-dbw/internal/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDb_WithItemResults(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	conn, _ := dbw.TestSetup(t)
+	rw := dbw.New(conn)
+
+	// newUsers returns users to create: a new user, a user which fails its
+	// VetForWrite, a user with the primary key of an existing user and another
+	// new user.
+	newUsers := func(t *testing.T) []*dbtest.TestUser {
+		t.Helper()
+		existing := testUser(t, rw, "", "", "")
+		vetFails := testUser(t, nil, "fail-VetForWrite", "", "")
+		dup := testUser(t, nil, "", "", "")
+		dup.PublicId = existing.PublicId
+		return []*dbtest.TestUser{testUser(t, nil, "", "", ""), vetFails, dup, testUser(t, nil, "", "", "")}
+	}
+	assertCreated := func(t *testing.T, rw *dbw.RW, users []*dbtest.TestUser, results []dbw.ItemResult) {
+		t.Helper()
+		assert, require := assert.New(t), require.New(t)
+		require.Len(results, len(users))
+		for i, r := range results {
+			assert.Equal(i, r.Index)
+		}
+		assert.NoError(results[0].Error)
+		assert.Equal(int64(1), results[0].RowsAffected)
+		assert.ErrorIs(results[1].Error, dbw.ErrInvalidParameter)
+		assert.Equal(int64(0), results[1].RowsAffected)
+		assert.Error(results[2].Error)
+		assert.Equal(int64(0), results[2].RowsAffected)
+		assert.NoError(results[3].Error)
+		assert.Equal(int64(1), results[3].RowsAffected)
+		for _, i := range []int{0, 3} {
+			found := dbtest.AllocTestUser()
+			found.PublicId = users[i].PublicId
+			assert.NoError(rw.LookupByPublicId(testCtx, &found))
+		}
+	}
+
+	t.Run("create-items", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		users := newUsers(t)
+		var results []dbw.ItemResult
+		var rowsAffected int64
+		var afterRows int
+		err := rw.CreateItems(testCtx, users,
+			dbw.WithItemResults(&results),
+			dbw.WithBatchSize(2),
+			dbw.WithReturnRowsAffected(&rowsAffected),
+			dbw.WithAfterWrite(func(_ interface{}, n int) error {
+				afterRows = n
+				return nil
+			}),
+		)
+		require.NoError(err)
+		assert.Equal(int64(2), rowsAffected)
+		assert.Equal(2, afterRows)
+		assertCreated(t, rw, users, results)
+	})
+	t.Run("create-items-in-tx", func(t *testing.T) {
+		require := require.New(t)
+		users := newUsers(t)
+		var results []dbw.ItemResult
+		_, err := rw.DoTx(testCtx, func(error) bool { return false }, 0, dbw.ExpBackoff{}, func(_ dbw.Reader, w dbw.Writer) error {
+			return w.CreateItems(testCtx, users, dbw.WithItemResults(&results), dbw.WithBatchSize(2))
+		})
+		require.NoError(err)
+		assertCreated(t, rw, users, results)
+	})
+	t.Run("create-items-batch", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		users := []*dbtest.TestUser{testUser(t, nil, "", "", ""), testUser(t, nil, "", "", ""), testUser(t, nil, "", "", "")}
+		var results []dbw.ItemResult
+		require.NoError(rw.CreateItems(testCtx, users, dbw.WithItemResults(&results)))
+		assert.Equal([]dbw.ItemResult{{Index: 0, RowsAffected: 1}, {Index: 1, RowsAffected: 1}, {Index: 2, RowsAffected: 1}}, results)
+	})
+	t.Run("create-items-on-conflict", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		existing := testUser(t, rw, "", "", "")
+		dup := testUser(t, nil, "", "", "")
+		dup.PublicId = existing.PublicId
+		users := []*dbtest.TestUser{testUser(t, nil, "", "", ""), dup}
+		var results []dbw.ItemResult
+		onConflict := &dbw.OnConflict{Target: dbw.Columns{"public_id"}, Action: dbw.DoNothing(true)}
+		require.NoError(rw.CreateItems(testCtx, users, dbw.WithItemResults(&results), dbw.WithOnConflict(onConflict)))
+		assert.Equal([]dbw.ItemResult{{Index: 0, RowsAffected: 1}, {Index: 1, RowsAffected: 0}}, results)
+	})
+	t.Run("delete-items", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		missing := testUser(t, nil, "", "", "")
+		users := []*dbtest.TestUser{testUser(t, rw, "", "", ""), missing, testUser(t, rw, "", "", "")}
+		var results []dbw.ItemResult
+		rowsDeleted, err := rw.DeleteItems(testCtx, users, dbw.WithItemResults(&results))
+		require.NoError(err)
+		assert.Equal(2, rowsDeleted)
+		assert.Equal([]dbw.ItemResult{{Index: 0, RowsAffected: 1}, {Index: 1, RowsAffected: 0}, {Index: 2, RowsAffected: 1}}, results)
+	})
+	t.Run("disparate-types", func(t *testing.T) {
+		assert := assert.New(t)
+		var results []dbw.ItemResult
+		err := rw.CreateItems(testCtx, []interface{}{testUser(t, nil, "", "", ""), testCar(t, nil)}, dbw.WithItemResults(&results))
+		assert.ErrorIs(err, dbw.ErrInvalidParameter)
+		assert.Nil(results)
+	})
+}
@@ -27,7 +27,13 @@ type Column struct {
 	Table string
 }
 
-func (c *Column) toAssignment(column string) clause.Assignment {
+func (c *Column) toAssignment(d Dialect, column string) clause.Assignment {
+	if c.Table == excludedTable {
+		return clause.Assignment{
+			Column: clause.Column{Name: column},
+			Value:  d.Excluded(c.Name),
+		}
+	}
 	return clause.Assignment{
 		Column: clause.Column{Name: column},
 		Value:  clause.Column{Table: c.Table, Name: c.Name},
@@ -85,6 +91,11 @@ func SetColumnValues(columnValues map[string]interface{}) []ColumnValue {
 	return assignments
 }
 
+// excludedTable is the Table of a Column which references the row proposed
+// for insertion by an upsert.  The dialect translates it to its own reference
+// (see Dialect.Excluded)
+const excludedTable = "excluded"
+
 // SetColumns defines a list of column (names) to update using the set of
 // proposed insert columns during an on conflict update.
 func SetColumns(names []string) []ColumnValue {
@@ -92,7 +103,7 @@ func SetColumns(names []string) []ColumnValue {
 	for idx, name := range names {
 		assignments[idx] = ColumnValue{
 			Column: name,
-			Value:  Column{Name: name, Table: "excluded"},
+			Value:  Column{Name: name, Table: excludedTable},
 		}
 	}
 	return assignments
@@ -115,6 +126,16 @@ type OnConflict struct {
 	Action interface{}
 }
 
+// updates returns true if the on conflict action updates the conflicting
+// record.
+func (oc *OnConflict) updates() bool {
+	if oc == nil {
+		return false
+	}
+	_, doNothing := oc.Action.(DoNothing)
+	return !doNothing
+}
+
 // Constraint defines database constraint name
 type Constraint string
 
@@ -127,3 +148,48 @@ type DoNothing bool
 // UpdateAll defines an "on conflict" action of updating all columns using the
 // proposed insert column values
 type UpdateAll bool
+
+// LockStrength defines the strength of a row lock. See WithLock(...)
+type LockStrength string
+
+const (
+	// ForUpdate locks the rows as though they were being updated
+	ForUpdate LockStrength = "UPDATE"
+
+	// ForNoKeyUpdate locks the rows like ForUpdate, but doesn't block
+	// ForKeyShare locks
+	ForNoKeyUpdate LockStrength = "NO KEY UPDATE"
+
+	// ForShare locks the rows with a shared lock, which blocks updates and
+	// deletes but not other shared locks
+	ForShare LockStrength = "SHARE"
+
+	// ForKeyShare locks the rows like ForShare, but only blocks updates of the
+	// rows' keys
+	ForKeyShare LockStrength = "KEY SHARE"
+)
+
+// LockWait defines how a row lock waits for rows which are already locked.
+// See WithLock(...)
+type LockWait string
+
+const (
+	// NoWait returns an error rather than waiting for rows which are already
+	// locked
+	NoWait LockWait = "NOWAIT"
+
+	// SkipLocked skips rows which are already locked rather than waiting for
+	// them
+	SkipLocked LockWait = "SKIP LOCKED"
+)
+
+// RowLock defines a pessimistic row lock for a read operation. See
+// WithLock(...)
+type RowLock struct {
+	// Strength of the lock
+	Strength LockStrength
+
+	// Wait specifies how the lock waits for rows which are already locked.
+	// The default is to wait until they're unlocked.
+	Wait LockWait
+}
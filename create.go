@@ -78,7 +78,20 @@ func NonCreatableFields() []string {
 
 // Create a resource in the db with options: WithDebug, WithLookup,
 // WithReturnRowsAffected, OnConflict, WithBeforeWrite, WithAfterWrite,
-// WithVersion, WithTable, and WithWhere.
+// WithVersion, WithTable, WithWhere, WithGenerateId, WithIdGenerator,
+// WithReturning and WithTimeout.
+//
+// WithReturning scans the columns returned by the insert into the resource,
+// which populates values set by the database (defaults, triggers, etc) without
+// the extra query of WithLookup.
+//
+// WithGenerateId will set an empty primary key to an ID generated with the
+// option's prefix, using the db's IdGenerator unless it's overridden with
+// WithIdGenerator.
+//
+// Managed create and update timestamp fields are set to the current time (see
+// InitCreateTimestampFields and InitUpdateTimestampFields) and managed update
+// timestamps are also set by OnConflict updates.
 //
 // OnConflict specifies alternative actions to take when an insert results in a
 // unique constraint or exclusion constraint error. If WithVersion is used with
@@ -89,7 +102,7 @@ func NonCreatableFields() []string {
 // error. WithWhere allows specifying an additional constraint on the on
 // conflict operation in addition to the on conflict target policy (columns or
 // constraint).
-func (rw *RW) Create(ctx context.Context, i interface{}, opt ...Option) error {
+func (rw *RW) Create(ctx context.Context, i interface{}, opt ...Option) (retErr error) {
 	const op = "dbw.Create"
 	if rw.underlying == nil {
 		return fmt.Errorf("%s: missing underlying db: %w", op, ErrInvalidParameter)
@@ -106,6 +119,20 @@ func (rw *RW) Create(ctx context.Context, i interface{}, opt ...Option) error {
 	// db to manage them
 	setFieldsToNil(i, NonCreatableFields())
 
+	ts, err := rw.timestampsFor(i)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	now := rw.underlying.now()
+	if err := ts.setCreate(ctx, i, now); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if opts.WithGenerateId != "" {
+		if err := rw.generateId(ctx, i, opts.WithGenerateId, opts.WithIdGenerator); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
 	if !opts.WithSkipVetForWrite {
 		if vetter, ok := i.(VetForWriter); ok {
 			if err := vetter.VetForWrite(ctx, rw, CreateOp); err != nil {
@@ -114,6 +141,11 @@ func (rw *RW) Create(ctx context.Context, i interface{}, opt ...Option) error {
 		}
 	}
 
+	rw, ctx, done, err := rw.startTimeout(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { retErr = done(retErr) }()
 	db := rw.underlying.wrapped.WithContext(ctx)
 	if opts.WithOnConflict != nil {
 		c := clause.OnConflict{}
@@ -145,7 +177,7 @@ func (rw *RW) Create(ctx context.Context, i interface{}, opt ...Option) error {
 				}
 				switch sv := s.Value.(type) {
 				case Column:
-					set = append(set, sv.toAssignment(s.Column))
+					set = append(set, sv.toAssignment(rw.underlying.dialect(), s.Column))
 				case ExprValue:
 					set = append(set, sv.toAssignment(s.Column))
 				default:
@@ -164,6 +196,10 @@ func (rw *RW) Create(ctx context.Context, i interface{}, opt ...Option) error {
 			whereConditions := db.Statement.BuildCondition(where, args...)
 			c.Where = clause.Where{Exprs: whereConditions}
 		}
+		ts.onConflict(&c, now)
+		if err := rw.validateOnConflict(i, &c); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
 		db = db.Clauses(c)
 	}
 	if opts.WithDebug {
@@ -172,6 +208,9 @@ func (rw *RW) Create(ctx context.Context, i interface{}, opt ...Option) error {
 	if opts.WithTable != "" {
 		db = db.Table(opts.WithTable)
 	}
+	if db, err = rw.withReturning(db, i, opts); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 	if opts.WithBeforeWrite != nil {
 		if err := opts.WithBeforeWrite(i); err != nil {
 			return fmt.Errorf("%s: error before write: %w", op, err)
@@ -184,6 +223,9 @@ func (rw *RW) Create(ctx context.Context, i interface{}, opt ...Option) error {
 	if opts.WithRowsAffected != nil {
 		*opts.WithRowsAffected = tx.RowsAffected
 	}
+	if tx.RowsAffected > 0 && opts.WithOnConflict.updates() {
+		rw.invalidateLookupCache(ctx, opts.WithTable, i)
+	}
 	if tx.RowsAffected > 0 && opts.WithAfterWrite != nil {
 		if err := opts.WithAfterWrite(i, int(tx.RowsAffected)); err != nil {
 			return fmt.Errorf("%s: error after write: %w", op, err)
@@ -197,9 +239,21 @@ func (rw *RW) Create(ctx context.Context, i interface{}, opt ...Option) error {
 
 // CreateItems will create multiple items of the same type. Supported options:
 // WithBatchSize, WithDebug, WithBeforeWrite, WithAfterWrite,
-// WithReturnRowsAffected, OnConflict, WithVersion, WithTable, and WithWhere.
-// WithLookup is not a supported option.
-func (rw *RW) CreateItems(ctx context.Context, createItems interface{}, opt ...Option) error {
+// WithReturnRowsAffected, OnConflict, WithVersion, WithTable, WithWhere,
+// WithGenerateId, WithIdGenerator, WithReturning and WithTimeout. WithLookup
+// is not a supported option, but WithReturning populates each item with the
+// columns returned by its insert.
+//
+// WithItemResults writes the items on a best effort basis, so items which fail
+// (including their VetForWrite) don't fail the others, and returns the result
+// of each item.  The items are written in batches, and a batch which fails is
+// rolled back and its items are written one at a time.  Within a
+// transaction, batches and items are isolated with savepoints.  Items with a
+// DoNothing OnConflict are always written one at a time, so an item which
+// conflicts reports no rows affected.
+// Managed timestamp fields and generated IDs are set the same as they are for
+// Create(...)
+func (rw *RW) CreateItems(ctx context.Context, createItems interface{}, opt ...Option) (retErr error) {
 	const op = "dbw.CreateItems"
 	switch {
 	case rw.underlying == nil:
@@ -222,7 +276,15 @@ func (rw *RW) CreateItems(ctx context.Context, createItems interface{}, opt ...O
 	case opts.WithLookup:
 		return fmt.Errorf("%s: with lookup not a supported option: %w", op, ErrInvalidParameter)
 	}
+	if opts.WithItemResults != nil {
+		if _, err := rw.writeItemsWithResults(ctx, valCreateItems, opt, writeCreateItems); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		return nil
+	}
 	var foundType reflect.Type
+	var ts timestamps
+	now := rw.underlying.now()
 	for i := 0; i < valCreateItems.Len(); i++ {
 		// verify that createItems are all the same type and do some bits on each item
 		if i == 0 {
@@ -240,6 +302,21 @@ func (rw *RW) CreateItems(ctx context.Context, createItems interface{}, opt ...O
 		// db to manage them
 		setFieldsToNil(valCreateItems.Index(i).Interface(), NonCreatableFields())
 
+		if i == 0 {
+			var err error
+			if ts, err = rw.timestampsFor(valCreateItems.Index(i).Interface()); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+		if err := ts.setCreate(ctx, valCreateItems.Index(i).Interface(), now); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if opts.WithGenerateId != "" {
+			if err := rw.generateId(ctx, valCreateItems.Index(i).Interface(), opts.WithGenerateId, opts.WithIdGenerator); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+
 		// vet each item
 		if !opts.WithSkipVetForWrite {
 			if vetter, ok := valCreateItems.Index(i).Interface().(VetForWriter); ok {
@@ -256,6 +333,11 @@ func (rw *RW) CreateItems(ctx context.Context, createItems interface{}, opt ...O
 		}
 	}
 
+	rw, ctx, done, err := rw.startTimeout(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { retErr = done(retErr) }()
 	db := rw.underlying.wrapped.WithContext(ctx)
 	if opts.WithOnConflict != nil {
 		c := clause.OnConflict{}
@@ -287,7 +369,7 @@ func (rw *RW) CreateItems(ctx context.Context, createItems interface{}, opt ...O
 				}
 				switch sv := s.Value.(type) {
 				case Column:
-					set = append(set, sv.toAssignment(s.Column))
+					set = append(set, sv.toAssignment(rw.underlying.dialect(), s.Column))
 				case ExprValue:
 					set = append(set, sv.toAssignment(s.Column))
 				default:
@@ -309,6 +391,10 @@ func (rw *RW) CreateItems(ctx context.Context, createItems interface{}, opt ...O
 			whereConditions := db.Statement.BuildCondition(where, args...)
 			c.Where = clause.Where{Exprs: whereConditions}
 		}
+		ts.onConflict(&c, now)
+		if err := rw.validateOnConflict(valCreateItems.Index(0).Interface(), &c); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
 		db = db.Clauses(c)
 	}
 	if opts.WithDebug {
@@ -317,6 +403,9 @@ func (rw *RW) CreateItems(ctx context.Context, createItems interface{}, opt ...O
 	if opts.WithTable != "" {
 		db = db.Table(opts.WithTable)
 	}
+	if db, err = rw.withReturning(db, valCreateItems.Index(0).Interface(), opts); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 
 	tx := db.CreateInBatches(createItems, opts.WithBatchSize)
 	if tx.Error != nil {
@@ -325,6 +414,9 @@ func (rw *RW) CreateItems(ctx context.Context, createItems interface{}, opt ...O
 	if opts.WithRowsAffected != nil {
 		*opts.WithRowsAffected = tx.RowsAffected
 	}
+	if tx.RowsAffected > 0 && opts.WithOnConflict.updates() {
+		rw.invalidateLookupCache(ctx, opts.WithTable, sliceItems(valCreateItems)...)
+	}
 	if tx.RowsAffected > 0 && opts.WithAfterWrite != nil {
 		if err := opts.WithAfterWrite(createItems, int(tx.RowsAffected)); err != nil {
 			return fmt.Errorf("%s: error after write: %w", op, err)
@@ -333,6 +425,13 @@ func (rw *RW) CreateItems(ctx context.Context, createItems interface{}, opt ...O
 	return nil
 }
 
+// writeCreateItems is the writeItemFn of CreateItems(...)
+func writeCreateItems(ctx context.Context, rw *RW, items interface{}, opt ...Option) (int64, error) {
+	var rowsAffected int64
+	err := rw.CreateItems(ctx, items, append(opt[:len(opt):len(opt)], WithReturnRowsAffected(&rowsAffected))...)
+	return rowsAffected, err
+}
+
 func setFieldsToNil(i interface{}, fieldNames []string) {
 	// Note: error cases are not handled
 	_ = Clear(i, fieldNames, 2)
@@ -391,3 +490,17 @@ func clear(v reflect.Value, fields map[string]bool, depth int) {
 		}
 	}
 }
+
+// validateOnConflict validates the on conflict clause for the resource using
+// the dialect (see Dialect.OnConflict)
+func (rw *RW) validateOnConflict(i interface{}, c *clause.OnConflict) error {
+	const op = "dbw.validateOnConflict"
+	stmt := rw.underlying.wrapped.Model(i).Statement
+	if err := stmt.Parse(i); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := rw.underlying.dialect().OnConflict(c, stmt.Schema.PrimaryFieldDBNames); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
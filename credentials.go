@@ -0,0 +1,268 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Credentials are the credentials used to connect to a database, which are
+// provided by a CredentialsProvider.
+type Credentials struct {
+	// Username to connect with
+	Username string
+
+	// Password to connect with
+	Password string
+
+	// ConnectionUrl optionally replaces the connection url of a Sqlite
+	// database.  It's not supported for Postgres, since its username and
+	// password are the only credentials which can be rotated.
+	ConnectionUrl string
+
+	// Expiration of the credentials' lease.  Connections which were opened
+	// with the credentials are closed once they expire, instead of being
+	// reused.  A zero Expiration never expires.
+	Expiration time.Time
+}
+
+// CredentialsProvider provides the credentials for a new connection.  When
+// refresh is true, the previous credentials were rejected by the database, so
+// the provider must not return cached credentials.  A provider is called
+// concurrently, so it must be safe for concurrent use.
+type CredentialsProvider func(ctx context.Context, refresh bool) (Credentials, error)
+
+// OpenWithCredentials will open a long-lived database connection using
+// credentials from a provider, which supports rotating credentials:
+//   - every new connection uses the credentials returned by the provider.
+//   - connections are closed once their credentials' lease expires.
+//   - when a new connection's credentials are rejected, the provider is called
+//     to refresh them, and the connection is retried once.
+//
+// Postgres and Sqlite are supported.  For Postgres, the credentials' username
+// and password replace the connectionUrl's user and password.  For Sqlite,
+// the credentials' ConnectionUrl replaces the connectionUrl, and the username
+// and password are used for sqlite user authentication (which requires the
+// sqlite_userauth build tag).  The options supported by Open(...) are
+// supported, including the sqlite options like WithSqliteSingleWriter.
+func OpenWithCredentials(dbType DbType, connectionUrl string, provider CredentialsProvider, opt ...Option) (*DB, error) {
+	const op = "dbw.OpenWithCredentials"
+	switch {
+	case connectionUrl == "":
+		return nil, fmt.Errorf("%s: missing connection url: %w", op, ErrInvalidParameter)
+	case provider == nil:
+		return nil, fmt.Errorf("%s: missing credentials provider: %w", op, ErrInvalidParameter)
+	}
+	d, _ := LookupDialect(dbType.String())
+	cd, ok := d.(CredentialsDialect)
+	if !ok {
+		return nil, fmt.Errorf("%s: unable to open %s database type: %w", op, dbType, ErrInvalidParameter)
+	}
+	dialect, readers, err := cd.CredentialsDialector(connectionUrl, provider, GetOpts(opt...))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	db, err := openDialector(dialect, readers, opt...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return db, nil
+}
+
+// CredentialsDialector returns a postgres gorm.Dialector whose connections
+// replace the connectionUrl's user and password with the credentials'.
+func (d postgresDialect) CredentialsDialector(connectionUrl string, provider CredentialsProvider, _ Options) (gorm.Dialector, *sql.DB, error) {
+	const op = "dbw.(postgresDialect).CredentialsDialector"
+	config, err := pgx.ParseConfig(connectionUrl)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	next := stdlib.GetConnector(*config, stdlib.OptionBeforeConnect(postgresBeforeConnect))
+	c := &credentialsConnector{
+		provider: provider,
+		dialect:  d,
+		driver:   next.Driver(),
+		connect: func(ctx context.Context, creds Credentials) (driver.Conn, error) {
+			if creds.ConnectionUrl != "" {
+				return nil, fmt.Errorf("credentials connection url is not supported for postgres: %w", ErrInvalidParameter)
+			}
+			conn, err := next.Connect(context.WithValue(ctx, credentialsCtxKey{}, creds))
+			if err != nil {
+				return nil, err
+			}
+			if pgConn, ok := conn.(*stdlib.Conn); ok {
+				return &pgCredentialsConn{Conn: pgConn, lease: credentialsLease(creds.Expiration)}, nil
+			}
+			return conn, nil
+		},
+	}
+	return postgres.New(postgres.Config{Conn: sql.OpenDB(c)}), nil, nil
+}
+
+// CredentialsDialector returns a sqlite gorm.Dialector whose connections use
+// the credentials' ConnectionUrl, when it's set, and the credentials'
+// username and password for sqlite user authentication.  The readers are
+// returned when WithSqliteSingleWriter is used.
+func (d sqliteDialect) CredentialsDialector(connectionUrl string, provider CredentialsProvider, opts Options) (gorm.Dialector, *sql.DB, error) {
+	const op = "dbw.(sqliteDialect).CredentialsDialector"
+	writer, readers, err := sqliteDBs(connectionUrl, opts, func(sd *sqlite3.SQLiteDriver) driver.Connector {
+		return &credentialsConnector{
+			provider: provider,
+			dialect:  d,
+			driver:   sd,
+			connect: func(_ context.Context, creds Credentials) (driver.Conn, error) {
+				dsn := connectionUrl
+				if creds.ConnectionUrl != "" {
+					dsn = creds.ConnectionUrl
+				}
+				if creds.Username != "" {
+					dsn = sqliteAuthDsn(dsn, creds.Username, creds.Password)
+				}
+				conn, err := sd.Open(dsn)
+				if err != nil {
+					return nil, err
+				}
+				return &sqliteCredentialsConn{SQLiteConn: conn.(*sqlite3.SQLiteConn), lease: credentialsLease(creds.Expiration)}, nil
+			},
+		}
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return sqlite.New(sqlite.Config{Conn: writer}), readers, nil
+}
+
+// credentialsCtxKey is the context key of the credentials for a new postgres
+// connection, which are set by postgresBeforeConnect(...)
+type credentialsCtxKey struct{}
+
+func postgresBeforeConnect(ctx context.Context, config *pgx.ConnConfig) error {
+	creds, ok := ctx.Value(credentialsCtxKey{}).(Credentials)
+	if !ok {
+		return fmt.Errorf("missing credentials: %w", ErrInternal)
+	}
+	if creds.Username != "" {
+		config.User = creds.Username
+	}
+	if creds.Password != "" {
+		config.Password = creds.Password
+	}
+	return nil
+}
+
+// sqliteAuthDsn returns the dsn with the sqlite user authentication params
+func sqliteAuthDsn(dsn, username, password string) string {
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return fmt.Sprintf("%s%s_auth_user=%s&_auth_pass=%s", dsn, sep, url.QueryEscape(username), url.QueryEscape(password))
+}
+
+// credentialsConnector is a driver.Connector which connects using the
+// credentials from a provider.
+type credentialsConnector struct {
+	provider CredentialsProvider
+	dialect  Dialect
+	driver   driver.Driver
+	connect  func(context.Context, Credentials) (driver.Conn, error)
+}
+
+var _ driver.Connector = (*credentialsConnector)(nil)
+
+// Connect using the provider's credentials.  If they're rejected, then
+// they're refreshed and the connection is retried once.
+func (c *credentialsConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	const op = "dbw.(credentialsConnector).Connect"
+	conn, err := c.connectWith(ctx, false)
+	if err != nil && c.dialect.IsAuthError(err) {
+		conn, err = c.connectWith(ctx, true)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return conn, nil
+}
+
+func (c *credentialsConnector) connectWith(ctx context.Context, refresh bool) (driver.Conn, error) {
+	creds, err := c.provider(ctx, refresh)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get credentials: %w", err)
+	}
+	return c.connect(ctx, creds)
+}
+
+// Driver returns the underlying driver
+func (c *credentialsConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// credentialsLease is the expiration of a connection's credentials
+type credentialsLease time.Time
+
+// IsValid returns false once the lease expires, so database/sql closes the
+// connection rather than returning it to the pool.
+func (l credentialsLease) IsValid() bool {
+	exp := time.Time(l)
+	return exp.IsZero() || time.Now().Before(exp)
+}
+
+// pgCredentialsConn is a postgres connection with a credentials lease
+type pgCredentialsConn struct {
+	*stdlib.Conn
+	lease credentialsLease
+}
+
+// IsValid returns false once the connection's lease expires
+func (c *pgCredentialsConn) IsValid() bool {
+	return c.lease.IsValid()
+}
+
+// ResetSession returns driver.ErrBadConn once the connection's lease expires,
+// so database/sql doesn't reuse the idle connection.
+func (c *pgCredentialsConn) ResetSession(ctx context.Context) error {
+	if !c.lease.IsValid() {
+		return driver.ErrBadConn
+	}
+	return c.Conn.ResetSession(ctx)
+}
+
+// sqliteCredentialsConn is a sqlite connection with a credentials lease
+type sqliteCredentialsConn struct {
+	*sqlite3.SQLiteConn
+	lease credentialsLease
+}
+
+// sqliteConn returns the wrapped sqlite connection, which is needed for the
+// sqlite backup API.
+func (c *sqliteCredentialsConn) sqliteConn() *sqlite3.SQLiteConn {
+	return c.SQLiteConn
+}
+
+// IsValid returns false once the connection's lease expires
+func (c *sqliteCredentialsConn) IsValid() bool {
+	return c.lease.IsValid()
+}
+
+// ResetSession returns driver.ErrBadConn once the connection's lease expires,
+// so database/sql doesn't reuse the idle connection.
+func (c *sqliteCredentialsConn) ResetSession(_ context.Context) error {
+	if !c.lease.IsValid() {
+		return driver.ErrBadConn
+	}
+	return nil
+}
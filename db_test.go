@@ -186,6 +186,8 @@ func TestDB_StringToDbType(t *testing.T) {
 	}{
 		{name: "postgres", want: dbw.Postgres},
 		{name: "sqlite", want: dbw.Sqlite},
+		{name: "mysql", want: dbw.MySQL},
+		{name: "sqlserver", want: dbw.SqlServer},
 		{name: "unknown", want: dbw.UnknownDB, wantErr: true},
 	}
 	for _, tt := range tests {
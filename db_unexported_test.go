@@ -5,12 +5,19 @@ package dbw
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
 	"errors"
+	"path/filepath"
 	"testing"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm/logger"
 )
 
@@ -53,3 +60,64 @@ func TestDB_gormLogger(t *testing.T) {
 		assert.NotEmpty(t, buf.Bytes())
 	})
 }
+
+func Test_openDialector(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name            string
+		opts            []Option
+		wantErrContains string
+	}{
+		{
+			name:            "negative-timeout",
+			opts:            []Option{WithTimeout(-1)},
+			wantErrContains: "timeout must not be negative",
+		},
+		{
+			name:            "invalid-pool",
+			opts:            []Option{WithMinOpenConnections(3), WithMaxOpenConnections(2)},
+			wantErrContains: "max_open_connections must be unlimited by setting 0 or at least 3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert, require := assert.New(t), require.New(t)
+			dsn := filepath.Join(t.TempDir(), "open.db")
+			opts := append([]Option{WithSqliteSingleWriter(true)}, tt.opts...)
+			writer, readers, err := sqliteDBs(dsn, GetOpts(opts...), func(d *sqlite3.SQLiteDriver) driver.Connector {
+				return &sqliteConnector{driver: d, dsn: dsn}
+			})
+			require.NoError(err)
+			require.NotNil(readers)
+
+			_, err = openDialector(sqlite.New(sqlite.Config{Conn: writer}), readers, opts...)
+			require.Error(err)
+			assert.ErrorContains(err, tt.wantErrContains)
+			// both of the pools were closed by the failed open
+			assert.ErrorContains(writer.Ping(), "database is closed")
+			assert.ErrorContains(readers.Ping(), "database is closed")
+		})
+	}
+}
+
+func Test_closeOnOpenErr(t *testing.T) {
+	t.Parallel()
+	assert, require := assert.New(t), require.New(t)
+	db, err := OpenWith(sqlite.Open(filepath.Join(t.TempDir(), "close.db")))
+	require.NoError(err)
+	underlying, err := db.wrapped.DB()
+	require.NoError(err)
+	pool, err := configurePool(context.Background(), underlying, GetOpts(WithMinOpenConnections(1)))
+	require.NoError(err)
+	readers, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "readers.db"))
+	require.NoError(err)
+
+	closeOnOpenErr(db.wrapped, pool, readers)
+	select {
+	case <-pool.done:
+	default:
+		assert.Fail("the pool's keep-alive is still running")
+	}
+	assert.ErrorContains(underlying.Ping(), "database is closed")
+	assert.ErrorContains(readers.Ping(), "database is closed")
+}
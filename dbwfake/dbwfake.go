@@ -0,0 +1,1283 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+// Package dbwfake provides an in-memory, map-backed fake of the dbw.Reader and
+// dbw.Writer interfaces for unit tests.
+//
+// The fake understands gorm primary keys and table names (including
+// TableName() and the WithTable option), and it supports Create, CreateItems,
+// Update with field masks, Delete, DeleteItems, LookupBy, LookupByPublicId,
+// LookupWhere, SearchWhere and DoTx with rollback semantics.  Errors can be
+// injected for any operation (see ErrorInjector).
+//
+// The fake only knows about primary keys, so other unique constraints are not
+// enforced and OnConflict targets are ignored. Columns are set to their zero
+// value or null unless they have a default of null, CURRENT_TIMESTAMP or a
+// literal, except for version columns which default to 1 and are incremented
+// by Update, the same as dbw.  Raw sql via Exec, Query and ScanRows is not
+// supported and neither are Begin, Rollback and Commit (use DoTx).
+//
+// Faulty wraps any dbw.Reader and dbw.Writer, including a dbw.RW, and injects
+// errors and latency into specific operations to test retry paths.
+package dbwfake
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-dbw"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	// ErrNotSupported is returned for operations and options which the fake
+	// doesn't support.
+	ErrNotSupported = errors.New("not supported by dbwfake")
+
+	// ErrDuplicateKey is returned when a resource is created with the primary
+	// key of an existing resource.
+	ErrDuplicateKey = errors.New("duplicate key")
+)
+
+// Op is an operation of the fake.
+type Op string
+
+const (
+	OpLookupBy         Op = "LookupBy"
+	OpLookupByPublicId Op = "LookupByPublicId"
+	OpLookupWhere      Op = "LookupWhere"
+	OpSearchWhere      Op = "SearchWhere"
+	OpCreate           Op = "Create"
+	OpCreateItems      Op = "CreateItems"
+	OpUpdate           Op = "Update"
+	OpDelete           Op = "Delete"
+	OpDeleteItems      Op = "DeleteItems"
+	OpUpdateWhere      Op = "UpdateWhere"
+	OpDeleteWhere      Op = "DeleteWhere"
+	OpDoTx             Op = "DoTx"
+	OpCommit           Op = "Commit"
+)
+
+// ErrorInjector is called before every operation with the operation and the
+// table of its resource (the table is empty for DoTx and Commit).  If it
+// returns an error, then the operation fails with that error.
+type ErrorInjector func(op Op, table string) error
+
+// row is a resource stored by column name
+type row map[string]interface{}
+
+// keyedRow is a row and its primary key
+type keyedRow struct {
+	key string
+	row row
+}
+
+// tables are the fake's rows by table name and primary key
+type tables map[string]map[string]row
+
+func (t tables) clone() tables {
+	c := make(tables, len(t))
+	for name, rows := range t {
+		c[name] = make(map[string]row, len(rows))
+		for k, r := range rows {
+			c[name][k] = r
+		}
+	}
+	return c
+}
+
+// shared is the state shared by a fake and its transactions
+type shared struct {
+	schemas sync.Map
+	dialect dbw.DbType
+
+	mu       sync.Mutex
+	injector ErrorInjector
+	failNext map[Op][]error
+}
+
+// RW is an in-memory fake of dbw.Reader and dbw.Writer.  It's safe for
+// concurrent use.
+type RW struct {
+	shared *shared
+
+	mu     sync.Mutex
+	data   tables
+	parent *RW                        // set for transactions
+	dirty  map[string]map[string]bool // rows written by a transaction
+}
+
+var (
+	_ dbw.Reader      = (*RW)(nil)
+	_ dbw.Writer      = (*RW)(nil)
+	_ dbw.WhereWriter = (*RW)(nil)
+)
+
+// New creates a new empty fake. Supported options: WithDialect and
+// WithErrorInjector.
+func New(opt ...Option) *RW {
+	opts := getOpts(opt...)
+	return &RW{
+		shared: &shared{
+			dialect:  opts.withDialect,
+			injector: opts.withErrorInjector,
+			failNext: map[Op][]error{},
+		},
+		data: tables{},
+	}
+}
+
+// SetErrorInjector sets the ErrorInjector for the fake and its transactions.
+// A nil injector removes it.
+func (rw *RW) SetErrorInjector(fn ErrorInjector) {
+	rw.shared.mu.Lock()
+	defer rw.shared.mu.Unlock()
+	rw.shared.injector = fn
+}
+
+// FailNext will fail the next call of the operation with the error.  Calling
+// it multiple times for an operation will fail that many calls in order.
+func (rw *RW) FailNext(op Op, err error) {
+	rw.shared.mu.Lock()
+	defer rw.shared.mu.Unlock()
+	rw.shared.failNext[op] = append(rw.shared.failNext[op], err)
+}
+
+// inject returns an injected error for the operation, if any.
+func (rw *RW) inject(ctx context.Context, op Op, table string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	rw.shared.mu.Lock()
+	if errs := rw.shared.failNext[op]; len(errs) > 0 {
+		rw.shared.failNext[op] = errs[1:]
+		rw.shared.mu.Unlock()
+		return errs[0]
+	}
+	injector := rw.shared.injector
+	rw.shared.mu.Unlock()
+	if injector != nil {
+		return injector(op, table)
+	}
+	return nil
+}
+
+// Dialect returns the fake's dialect (see WithDialect) and a raw name of
+// "dbwfake"
+func (rw *RW) Dialect() (dbw.DbType, string, error) {
+	return rw.shared.dialect, "dbwfake", nil
+}
+
+// model is a parsed resource
+type model struct {
+	schema *schema.Schema
+	table  string
+}
+
+// parse the resource's schema and table name
+func (rw *RW) parse(i interface{}, opts dbw.Options) (model, error) {
+	const op = "dbwfake.parse"
+	s, err := schema.Parse(i, &rw.shared.schemas, schema.NamingStrategy{})
+	if err != nil {
+		return model{}, fmt.Errorf("%s: %w", op, errors.Join(err, dbw.ErrInvalidParameter))
+	}
+	m := model{schema: s, table: s.Table}
+	if tabler, ok := i.(schema.Tabler); ok {
+		m.table = tabler.TableName()
+	}
+	if opts.WithTable != "" {
+		m.table = opts.WithTable
+	}
+	return m, nil
+}
+
+// normalize converts a field value to the value stored by the fake
+func normalize(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+	}
+	if valuer, ok := v.(driver.Valuer); ok {
+		if dv, err := valuer.Value(); err == nil {
+			return normalize(dv)
+		}
+	}
+	switch t := v.(type) {
+	case []byte:
+		return append([]byte(nil), t...)
+	case time.Time:
+		return t
+	}
+	if rv.Kind() == reflect.Ptr {
+		return normalize(rv.Elem().Interface())
+	}
+	return v
+}
+
+// toRow returns the resource's row
+func (m model) toRow(ctx context.Context, i interface{}) row {
+	r := row{}
+	rv := reflect.ValueOf(i)
+	for _, f := range m.schema.Fields {
+		if f.DBName == "" {
+			continue
+		}
+		v, _ := f.ValueOf(ctx, rv)
+		r[f.DBName] = normalize(v)
+	}
+	return r
+}
+
+// setDefaults sets the row's zero values to their default for a create
+func (m model) setDefaults(ctx context.Context, i interface{}, r row) {
+	rv := reflect.ValueOf(i)
+	for _, f := range m.schema.Fields {
+		if f.DBName == "" {
+			continue
+		}
+		if _, isZero := f.ValueOf(ctx, rv); !isZero {
+			continue
+		}
+		switch {
+		case f.DBName == "version":
+			r[f.DBName] = 1
+		case !f.HasDefaultValue || strings.EqualFold(f.DefaultValue, "null"):
+			if f.HasDefaultValue {
+				r[f.DBName] = nil
+			}
+		case strings.EqualFold(f.DefaultValue, "current_timestamp"):
+			r[f.DBName] = time.Now().UTC()
+		case f.DefaultValueInterface != nil:
+			r[f.DBName] = normalize(f.DefaultValueInterface)
+		}
+	}
+}
+
+// fromRow sets the resource's fields from the row
+func (m model) fromRow(ctx context.Context, i interface{}, r row) error {
+	const op = "dbwfake.fromRow"
+	rv := reflect.ValueOf(i)
+	for _, f := range m.schema.Fields {
+		if f.DBName == "" {
+			continue
+		}
+		v, ok := r[f.DBName]
+		if !ok {
+			continue
+		}
+		if v == nil {
+			fv := f.ReflectValueOf(ctx, rv)
+			fv.Set(reflect.Zero(fv.Type()))
+			continue
+		}
+		if err := f.Set(ctx, rv, v); err != nil {
+			return fmt.Errorf("%s: unable to set %s: %w", op, f.Name, err)
+		}
+	}
+	return nil
+}
+
+// key returns the primary key of the row
+func (m model) key(r row) (string, error) {
+	const op = "dbwfake.key"
+	if len(m.schema.PrimaryFieldDBNames) == 0 {
+		return "", fmt.Errorf("%s: %s has no primary key: %w", op, m.table, dbw.ErrInvalidParameter)
+	}
+	parts := make([]string, 0, len(m.schema.PrimaryFieldDBNames))
+	for _, name := range m.schema.PrimaryFieldDBNames {
+		v := r[name]
+		if v == nil || reflect.ValueOf(v).IsZero() {
+			return "", fmt.Errorf("%s: primary key %s is not set: %w", op, name, dbw.ErrInvalidParameter)
+		}
+		parts = append(parts, fmt.Sprintf("%v", v))
+	}
+	return strings.Join(parts, "\x00"), nil
+}
+
+// primaryKeyConditions returns the conditions for looking up a resource by
+// its primary key, which are the same as dbw.LookupBy(...)
+func (m model) primaryKeyConditions(ctx context.Context, i interface{}) ([]condition, error) {
+	const op = "dbwfake.primaryKeyConditions"
+	switch resource := i.(type) {
+	case dbw.ResourcePublicIder:
+		if resource.GetPublicId() == "" {
+			return nil, fmt.Errorf("%s: missing primary key: %w", op, dbw.ErrInvalidParameter)
+		}
+		return []condition{{column: "public_id", op: "=", arg: resource.GetPublicId()}}, nil
+	case dbw.ResourcePrivateIder:
+		if resource.GetPrivateId() == "" {
+			return nil, fmt.Errorf("%s: missing primary key: %w", op, dbw.ErrInvalidParameter)
+		}
+		return []condition{{column: "private_id", op: "=", arg: resource.GetPrivateId()}}, nil
+	}
+	var conditions []condition
+	for _, f := range m.schema.PrimaryFields {
+		v, isZero := f.ValueOf(ctx, reflect.ValueOf(i))
+		if isZero {
+			return nil, fmt.Errorf("%s: primary field %s is zero: %w", op, f.Name, dbw.ErrInvalidParameter)
+		}
+		conditions = append(conditions, condition{column: f.DBName, op: "=", arg: normalize(v)})
+	}
+	if len(conditions) == 0 {
+		return nil, fmt.Errorf("%s: no primary key(s) for %T: %w", op, i, dbw.ErrInvalidParameter)
+	}
+	return conditions, nil
+}
+
+// find returns the table's rows matching the conditions
+func (rw *RW) find(table string, conditions []condition) ([]keyedRow, error) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	var found []keyedRow
+	for k, r := range rw.data[table] {
+		ok, err := matches(r, conditions)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			found = append(found, keyedRow{key: k, row: r})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].key < found[j].key })
+	return found, nil
+}
+
+// put stores the row, or deletes it when the row is nil.  The caller must
+// hold rw.mu
+func (rw *RW) put(table, key string, r row) {
+	if r == nil {
+		delete(rw.data[table], key)
+	} else {
+		if rw.data[table] == nil {
+			rw.data[table] = map[string]row{}
+		}
+		rw.data[table][key] = r
+	}
+	if rw.parent != nil {
+		if rw.dirty[table] == nil {
+			rw.dirty[table] = map[string]bool{}
+		}
+		rw.dirty[table][key] = true
+	}
+}
+
+// LookupBy will lookup a resource by it's primary keys, the same as
+// dbw.LookupBy(...).  The WithTable option is supported.
+func (rw *RW) LookupBy(ctx context.Context, resource interface{}, opt ...dbw.Option) error {
+	const op = "dbwfake.LookupBy"
+	return rw.lookupBy(ctx, op, OpLookupBy, resource, opt...)
+}
+
+// LookupByPublicId will lookup resource by its public_id, the same as
+// dbw.LookupByPublicId(...), including the validation of registered ID
+// prefixes.  The WithTable option is supported.
+func (rw *RW) LookupByPublicId(ctx context.Context, resource dbw.ResourcePublicIder, opt ...dbw.Option) error {
+	const op = "dbwfake.LookupByPublicId"
+	if !isNil(resource) {
+		if prefix, ok := dbw.RegisteredIdPrefix(resource); ok && !strings.HasPrefix(resource.GetPublicId(), prefix+"_") {
+			return fmt.Errorf("%s: id %q does not have prefix %q for %T: %w", op, resource.GetPublicId(), prefix, resource, dbw.ErrInvalidParameter)
+		}
+	}
+	return rw.lookupBy(ctx, op, OpLookupByPublicId, resource, opt...)
+}
+
+func (rw *RW) lookupBy(ctx context.Context, op string, fakeOp Op, resource interface{}, opt ...dbw.Option) error {
+	if isNil(resource) {
+		return fmt.Errorf("%s: missing interface: %w", op, dbw.ErrInvalidParameter)
+	}
+	m, err := rw.parse(resource, dbw.GetOpts(opt...))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := rw.inject(ctx, fakeOp, m.table); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	conditions, err := m.primaryKeyConditions(ctx, resource)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	found, err := rw.find(m.table, conditions)
+	switch {
+	case err != nil:
+		return fmt.Errorf("%s: %w", op, err)
+	case len(found) == 0:
+		return fmt.Errorf("%s: %w", op, dbw.ErrRecordNotFound)
+	}
+	if err := m.fromRow(ctx, resource, found[0].row); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// LookupWhere will lookup and return the first resource using a where clause
+// with parameters.  See parseWhere for the supported where clauses. The
+// WithTable and WithOrder options are supported.
+func (rw *RW) LookupWhere(ctx context.Context, resource interface{}, where string, args []interface{}, opt ...dbw.Option) error {
+	const op = "dbwfake.LookupWhere"
+	if isNil(resource) {
+		return fmt.Errorf("%s: missing interface: %w", op, dbw.ErrInvalidParameter)
+	}
+	opts := dbw.GetOpts(opt...)
+	m, err := rw.parse(resource, opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := rw.inject(ctx, OpLookupWhere, m.table); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	found, err := rw.search(m.table, where, args, opts.WithOrder)
+	switch {
+	case err != nil:
+		return fmt.Errorf("%s: %w", op, err)
+	case len(found) == 0:
+		return fmt.Errorf("%s: %w", op, dbw.ErrRecordNotFound)
+	}
+	if err := m.fromRow(ctx, resource, found[0].row); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// SearchWhere will search for all the resources it can find using a where
+// clause with parameters.  See parseWhere for the supported where clauses.
+// The WithTable, WithOrder and WithLimit options are supported.
+func (rw *RW) SearchWhere(ctx context.Context, resources interface{}, where string, args []interface{}, opt ...dbw.Option) error {
+	const op = "dbwfake.SearchWhere"
+	if isNil(resources) {
+		return fmt.Errorf("%s: missing interface: %w", op, dbw.ErrInvalidParameter)
+	}
+	sliceVal := reflect.ValueOf(resources)
+	if sliceVal.Kind() != reflect.Ptr || sliceVal.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("%s: resources is not a pointer to a slice: %w", op, dbw.ErrInvalidParameter)
+	}
+	sliceVal = sliceVal.Elem()
+	elemType := sliceVal.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+
+	opts := dbw.GetOpts(opt...)
+	m, err := rw.parse(reflect.New(elemType).Interface(), opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := rw.inject(ctx, OpSearchWhere, m.table); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	found, err := rw.search(m.table, where, args, opts.WithOrder)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	switch {
+	case opts.WithLimit < 0: // any negative number signals unlimited results
+	case opts.WithLimit == 0:
+		if len(found) > dbw.DefaultLimit {
+			found = found[:dbw.DefaultLimit]
+		}
+	default:
+		if len(found) > opts.WithLimit {
+			found = found[:opts.WithLimit]
+		}
+	}
+	results := reflect.MakeSlice(sliceVal.Type(), 0, len(found))
+	for _, f := range found {
+		item := reflect.New(elemType)
+		if err := m.fromRow(ctx, item.Interface(), f.row); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if !isPtr {
+			item = item.Elem()
+		}
+		results = reflect.Append(results, item)
+	}
+	sliceVal.Set(results)
+	return nil
+}
+
+func (rw *RW) search(table, where string, args []interface{}, order string) ([]keyedRow, error) {
+	const op = "dbwfake.search"
+	conditions, err := parseWhere(where, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	orders, err := parseOrder(order)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	found, err := rw.find(table, conditions)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	sortRows(found, orders)
+	return found, nil
+}
+
+// Create a resource.  Supported options: WithBeforeWrite, WithAfterWrite,
+// WithReturnRowsAffected, WithTable, WithGenerateId, WithIdGenerator and
+// OnConflict with a DoNothing or UpdateAll action.  Like dbw, non-creatable
+// fields are cleared and managed timestamps are set.
+func (rw *RW) Create(ctx context.Context, i interface{}, opt ...dbw.Option) error {
+	const op = "dbwfake.Create"
+	if isNil(i) {
+		return fmt.Errorf("%s: missing interface: %w", op, dbw.ErrInvalidParameter)
+	}
+	opts := dbw.GetOpts(opt...)
+	m, err := rw.parse(i, opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := rw.inject(ctx, OpCreate, m.table); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	rowsAffected, err := rw.create(ctx, m, i, opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if opts.WithRowsAffected != nil {
+		*opts.WithRowsAffected = rowsAffected
+	}
+	if rowsAffected > 0 && opts.WithAfterWrite != nil {
+		if err := opts.WithAfterWrite(i, int(rowsAffected)); err != nil {
+			return fmt.Errorf("%s: error after write: %w", op, err)
+		}
+	}
+	return nil
+}
+
+func (rw *RW) create(ctx context.Context, m model, i interface{}, opts dbw.Options) (int64, error) {
+	const op = "dbwfake.create"
+	if opts.WithOnConflict != nil {
+		switch opts.WithOnConflict.Action.(type) {
+		case dbw.DoNothing, dbw.UpdateAll:
+		default:
+			return 0, fmt.Errorf("%s: on conflict action %T: %w", op, opts.WithOnConflict.Action, ErrNotSupported)
+		}
+	}
+	_ = dbw.Clear(i, dbw.NonCreatableFields(), 2)
+	now := time.Now().UTC()
+	for _, f := range managedTimestamps(m.schema) {
+		if err := f.Set(ctx, reflect.ValueOf(i), now); err != nil {
+			return 0, fmt.Errorf("%s: unable to set %s: %w", op, f.Name, err)
+		}
+	}
+	if opts.WithGenerateId != "" {
+		if err := m.generateId(ctx, i, opts); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	if opts.WithBeforeWrite != nil {
+		if err := opts.WithBeforeWrite(i); err != nil {
+			return 0, fmt.Errorf("%s: error before write: %w", op, err)
+		}
+	}
+	r := m.toRow(ctx, i)
+	m.setDefaults(ctx, i, r)
+	key, err := m.key(r)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rw.mu.Lock()
+	if _, exists := rw.data[m.table][key]; exists {
+		switch {
+		case opts.WithOnConflict == nil:
+			rw.mu.Unlock()
+			return 0, fmt.Errorf("%s: %s: %w", op, m.table, ErrDuplicateKey)
+		default:
+			if _, ok := opts.WithOnConflict.Action.(dbw.DoNothing); ok {
+				rw.mu.Unlock()
+				return 0, nil
+			}
+		}
+	}
+	rw.put(m.table, key, r)
+	rw.mu.Unlock()
+
+	if err := m.fromRow(ctx, i, r); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return 1, nil
+}
+
+// managedTimestamps returns the managed create and update timestamp fields of
+// the schema (see dbw.InitCreateTimestampFields)
+func managedTimestamps(s *schema.Schema) []*schema.Field {
+	names := append(dbw.CreateTimestampFields(), dbw.UpdateTimestampFields()...)
+	var fields []*schema.Field
+	for _, f := range s.Fields {
+		if f.DBName == "" {
+			continue
+		}
+		tag := strings.Split(f.Tag.Get(dbw.TimestampTag), ",")
+		if contains(names, f.Name) || contains(tag, dbw.CreateTimestampTagValue) || contains(tag, dbw.UpdateTimestampTagValue) {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// managedUpdateTimestamps returns the managed update timestamp fields of the
+// schema (see dbw.InitUpdateTimestampFields)
+func managedUpdateTimestamps(s *schema.Schema) []*schema.Field {
+	names := dbw.UpdateTimestampFields()
+	var fields []*schema.Field
+	for _, f := range s.Fields {
+		if f.DBName != "" && (contains(names, f.Name) || contains(strings.Split(f.Tag.Get(dbw.TimestampTag), ","), dbw.UpdateTimestampTagValue)) {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// generateId sets an empty primary key to a generated ID
+func (m model) generateId(ctx context.Context, i interface{}, opts dbw.Options) error {
+	const op = "dbwfake.generateId"
+	pks := m.schema.PrimaryFields
+	if len(pks) != 1 || pks[0].FieldType.Kind() != reflect.String {
+		return fmt.Errorf("%s: %s must have a single string primary key to generate an id: %w", op, m.table, dbw.ErrInvalidParameter)
+	}
+	if _, isZero := pks[0].ValueOf(ctx, reflect.ValueOf(i)); !isZero {
+		return nil
+	}
+	id, err := dbw.NewId(opts.WithGenerateId, dbw.WithIdGenerator(opts.WithIdGenerator))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return pks[0].Set(ctx, reflect.ValueOf(i), id)
+}
+
+// CreateItems will create multiple items of the same type. Supported options
+// are the same as Create, except WithBeforeWrite and WithAfterWrite are called
+// with all the items, and WithItemResults is supported.
+func (rw *RW) CreateItems(ctx context.Context, createItems interface{}, opt ...dbw.Option) error {
+	const op = "dbwfake.CreateItems"
+	items, err := sliceItems(createItems)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	opts := dbw.GetOpts(opt...)
+	if opts.WithLookup {
+		return fmt.Errorf("%s: with lookup not a supported option: %w", op, dbw.ErrInvalidParameter)
+	}
+	m, err := rw.parse(items[0], opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := rw.inject(ctx, OpCreateItems, m.table); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if opts.WithBeforeWrite != nil {
+		if err := opts.WithBeforeWrite(createItems); err != nil {
+			return fmt.Errorf("%s: error before write: %w", op, err)
+		}
+	}
+	itemOpts := opts
+	itemOpts.WithBeforeWrite = nil
+	var rowsAffected int64
+	var results []dbw.ItemResult
+	for idx, item := range items {
+		n, err := rw.create(ctx, m, item, itemOpts)
+		switch {
+		case opts.WithItemResults != nil:
+			results = append(results, dbw.ItemResult{Index: idx, Error: err, RowsAffected: n})
+		case err != nil:
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		rowsAffected += n
+	}
+	if opts.WithItemResults != nil {
+		*opts.WithItemResults = results
+	}
+	if opts.WithRowsAffected != nil {
+		*opts.WithRowsAffected = rowsAffected
+	}
+	if rowsAffected > 0 && opts.WithAfterWrite != nil {
+		if err := opts.WithAfterWrite(createItems, int(rowsAffected)); err != nil {
+			return fmt.Errorf("%s: error after write: %w", op, err)
+		}
+	}
+	return nil
+}
+
+// Update a resource using field masks, the same as dbw.Update(...).  Supported
+// options: WithBeforeWrite, WithAfterWrite, WithWhere, WithTable and
+// WithVersion.  ErrRecordNotFound is returned if the resource doesn't exist
+// and ErrStaleVersion is returned if WithVersion doesn't match.
+func (rw *RW) Update(ctx context.Context, i interface{}, fieldMaskPaths []string, setToNullPaths []string, opt ...dbw.Option) (int, error) {
+	const op = "dbwfake.Update"
+	if isNil(i) {
+		return 0, fmt.Errorf("%s: missing interface: %w", op, dbw.ErrInvalidParameter)
+	}
+	if len(fieldMaskPaths) == 0 && len(setToNullPaths) == 0 {
+		return 0, fmt.Errorf("%s: both fieldMaskPaths and setToNullPaths are missing: %w", op, dbw.ErrInvalidParameter)
+	}
+	opts := dbw.GetOpts(opt...)
+	m, err := rw.parse(i, opts)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := rw.inject(ctx, OpUpdate, m.table); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// we need to filter out some non-updatable fields (like: CreateTime, etc)
+	fieldMaskPaths, setToNullPaths = filterPaths(fieldMaskPaths), filterPaths(setToNullPaths)
+	if len(fieldMaskPaths) == 0 && len(setToNullPaths) == 0 {
+		return 0, fmt.Errorf("%s: after filtering non-updated fields, there are no fields left in fieldMaskPaths or setToNullPaths: %w", op, dbw.ErrInvalidParameter)
+	}
+	updateFields, err := dbw.UpdateFields(i, fieldMaskPaths, setToNullPaths)
+	if err != nil {
+		return 0, fmt.Errorf("%s: getting update fields failed: %w", op, err)
+	}
+	if len(updateFields) == 0 {
+		return 0, fmt.Errorf("%s: no fields matched using fieldMaskPaths %s: %w", op, fieldMaskPaths, dbw.ErrInvalidParameter)
+	}
+	for _, pf := range m.schema.PrimaryFields {
+		if contains(fieldMaskPaths, pf.Name) {
+			return 0, fmt.Errorf("%s: not allowed on primary key field %s: %w", op, pf.Name, dbw.ErrInvalidFieldMask)
+		}
+	}
+	where, err := whereConditions(m, opts)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	key, err := m.key(m.toRow(ctx, i))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if opts.WithBeforeWrite != nil {
+		if err := opts.WithBeforeWrite(i); err != nil {
+			return 0, fmt.Errorf("%s: error before write: %w", op, err)
+		}
+	}
+
+	rw.mu.Lock()
+	current, exists := rw.data[m.table][key]
+	if !exists {
+		rw.mu.Unlock()
+		return 0, fmt.Errorf("%s: %w", op, dbw.ErrRecordNotFound)
+	}
+	if opts.WithVersion != nil {
+		if cmp, ok := compare(current["version"], *opts.WithVersion); !ok || cmp != 0 {
+			rw.mu.Unlock()
+			return 0, fmt.Errorf("%s: version %d: %w", op, *opts.WithVersion, dbw.ErrStaleVersion)
+		}
+	}
+	ok, err := matches(current, where)
+	if err != nil {
+		rw.mu.Unlock()
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	rowsUpdated := 0
+	updated := current
+	if ok {
+		rowsUpdated = 1
+		updated = make(row, len(current))
+		for k, v := range current {
+			updated[k] = v
+		}
+		rv := reflect.ValueOf(i)
+		for name, v := range updateFields {
+			f := m.schema.LookUpField(name)
+			if f == nil || f.DBName == "" {
+				continue
+			}
+			if _, isNull := v.(clause.Expr); isNull {
+				updated[f.DBName] = nil
+				continue
+			}
+			fv, _ := f.ValueOf(ctx, rv)
+			updated[f.DBName] = normalize(fv)
+		}
+		if vf := m.schema.LookUpField("version"); vf != nil && !contains(fieldMaskPaths, vf.Name) && !contains(setToNullPaths, vf.Name) {
+			n, _ := toFloat(current[vf.DBName])
+			updated[vf.DBName] = int64(n) + 1
+		}
+		now := time.Now().UTC()
+		for _, f := range managedUpdateTimestamps(m.schema) {
+			if !contains(fieldMaskPaths, f.Name) && !contains(setToNullPaths, f.Name) {
+				updated[f.DBName] = now
+			}
+		}
+		rw.put(m.table, key, updated)
+	}
+	rw.mu.Unlock()
+
+	if err := m.fromRow(ctx, i, updated); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if rowsUpdated > 0 && opts.WithAfterWrite != nil {
+		if err := opts.WithAfterWrite(i, rowsUpdated); err != nil {
+			return rowsUpdated, fmt.Errorf("%s: error after write: %w", op, err)
+		}
+	}
+	return rowsUpdated, nil
+}
+
+// whereConditions returns the conditions for the WithWhere option
+func whereConditions(m model, opts dbw.Options) ([]condition, error) {
+	const op = "dbwfake.whereConditions"
+	if opts.WithVersion != nil {
+		switch {
+		case *opts.WithVersion == 0:
+			return nil, fmt.Errorf("%s: with version option is zero: %w", op, dbw.ErrInvalidParameter)
+		case m.schema.LookUpField("version") == nil:
+			return nil, fmt.Errorf("%s: %s does not have a version field: %w", op, m.table, dbw.ErrInvalidParameter)
+		}
+	}
+	conditions, err := parseWhere(opts.WithWhereClause, opts.WithWhereClauseArgs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return conditions, nil
+}
+
+// filterPaths will filter out non-updatable fields
+func filterPaths(paths []string) []string {
+	nonUpdatable := dbw.NonUpdatableFields()
+	var filtered []string
+	for _, p := range paths {
+		if !contains(nonUpdatable, p) {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+// Delete a resource by its primary key. Supported options: WithBeforeWrite,
+// WithAfterWrite, WithWhere, WithTable and WithVersion.  Delete returns the
+// number of rows deleted.
+func (rw *RW) Delete(ctx context.Context, i interface{}, opt ...dbw.Option) (int, error) {
+	const op = "dbwfake.Delete"
+	if isNil(i) {
+		return 0, fmt.Errorf("%s: missing interface: %w", op, dbw.ErrInvalidParameter)
+	}
+	opts := dbw.GetOpts(opt...)
+	m, err := rw.parse(i, opts)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := rw.inject(ctx, OpDelete, m.table); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if opts.WithBeforeWrite != nil {
+		if err := opts.WithBeforeWrite(i); err != nil {
+			return 0, fmt.Errorf("%s: error before write: %w", op, err)
+		}
+	}
+	rowsDeleted, err := rw.delete(ctx, m, i, opts)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if rowsDeleted > 0 && opts.WithAfterWrite != nil {
+		if err := opts.WithAfterWrite(i, rowsDeleted); err != nil {
+			return rowsDeleted, fmt.Errorf("%s: error after write: %w", op, err)
+		}
+	}
+	return rowsDeleted, nil
+}
+
+func (rw *RW) delete(ctx context.Context, m model, i interface{}, opts dbw.Options) (int, error) {
+	const op = "dbwfake.delete"
+	where, err := whereConditions(m, opts)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if opts.WithVersion != nil {
+		where = append(where, condition{column: "version", op: "=", arg: *opts.WithVersion})
+	}
+	key, err := m.key(m.toRow(ctx, i))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	current, exists := rw.data[m.table][key]
+	if !exists {
+		return 0, nil
+	}
+	ok, err := matches(current, where)
+	switch {
+	case err != nil:
+		return 0, fmt.Errorf("%s: %w", op, err)
+	case !ok:
+		return 0, nil
+	}
+	rw.put(m.table, key, nil)
+	return 1, nil
+}
+
+// DeleteItems will delete multiple items of the same type. Supported options:
+// WithBeforeWrite, WithAfterWrite, WithTable, WithWhere and WithItemResults.
+func (rw *RW) DeleteItems(ctx context.Context, deleteItems interface{}, opt ...dbw.Option) (int, error) {
+	const op = "dbwfake.DeleteItems"
+	items, err := sliceItems(deleteItems)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	opts := dbw.GetOpts(opt...)
+	if opts.WithLookup {
+		return 0, fmt.Errorf("%s: with lookup not a supported option: %w", op, dbw.ErrInvalidParameter)
+	}
+	if opts.WithVersion != nil {
+		return 0, fmt.Errorf("%s: with version is not a supported option: %w", op, dbw.ErrInvalidParameter)
+	}
+	m, err := rw.parse(items[0], opts)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := rw.inject(ctx, OpDeleteItems, m.table); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if opts.WithBeforeWrite != nil {
+		if err := opts.WithBeforeWrite(deleteItems); err != nil {
+			return 0, fmt.Errorf("%s: error before write: %w", op, err)
+		}
+	}
+	rowsDeleted := 0
+	var results []dbw.ItemResult
+	for idx, item := range items {
+		n, err := rw.delete(ctx, m, item, opts)
+		switch {
+		case opts.WithItemResults != nil:
+			results = append(results, dbw.ItemResult{Index: idx, Error: err, RowsAffected: int64(n)})
+		case err != nil:
+			return rowsDeleted, fmt.Errorf("%s: %w", op, err)
+		}
+		rowsDeleted += n
+	}
+	if opts.WithItemResults != nil {
+		*opts.WithItemResults = results
+	}
+	if rowsDeleted > 0 && opts.WithAfterWrite != nil {
+		if err := opts.WithAfterWrite(deleteItems, rowsDeleted); err != nil {
+			return rowsDeleted, fmt.Errorf("%s: error after write: %w", op, err)
+		}
+	}
+	return rowsDeleted, nil
+}
+
+// UpdateWhere updates the columns of every row of the model's table which
+// matches the where clause, the same as dbw.UpdateWhere(...).  Column values
+// can be set to a Column{...} of the table or Expr("NULL"), and other
+// expressions return ErrNotSupported.  Supported options: WithAllRows,
+// WithBeforeWrite, WithAfterWrite and WithTable.
+func (rw *RW) UpdateWhere(ctx context.Context, model interface{}, values []dbw.ColumnValue, where string, args []interface{}, opt ...dbw.Option) (int, error) {
+	const op = "dbwfake.UpdateWhere"
+	switch {
+	case isNil(model):
+		return 0, fmt.Errorf("%s: missing model: %w", op, dbw.ErrInvalidParameter)
+	case len(values) == 0:
+		return 0, fmt.Errorf("%s: missing column values: %w", op, dbw.ErrInvalidParameter)
+	}
+	opts := dbw.GetOpts(opt...)
+	conditions, err := writeWhereConditions(where, args, opts)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	m, err := rw.parse(model, opts)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := rw.inject(ctx, OpUpdateWhere, m.table); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	set := map[string]func(row) interface{}{}
+	for _, cv := range values {
+		f := m.schema.LookUpField(cv.Column)
+		switch {
+		case f == nil || f.DBName == "":
+			return 0, fmt.Errorf("%s: %s is not a column of %s: %w", op, cv.Column, m.table, dbw.ErrInvalidParameter)
+		case f.PrimaryKey:
+			return 0, fmt.Errorf("%s: not allowed on primary key column %s: %w", op, f.DBName, dbw.ErrInvalidParameter)
+		case len(filterPaths([]string{f.Name})) == 0:
+			continue
+		}
+		switch v := cv.Value.(type) {
+		case dbw.ExprValue:
+			if !strings.EqualFold(strings.TrimSpace(v.Sql), "null") {
+				return 0, fmt.Errorf("%s: expression %q: %w", op, v.Sql, ErrNotSupported)
+			}
+			set[f.DBName] = func(row) interface{} { return nil }
+		case dbw.Column:
+			ref := m.schema.LookUpField(v.Name)
+			if ref == nil || ref.DBName == "" || (v.Table != "" && v.Table != m.table) {
+				return 0, fmt.Errorf("%s: column %s.%s: %w", op, v.Table, v.Name, ErrNotSupported)
+			}
+			set[f.DBName] = func(current row) interface{} { return current[ref.DBName] }
+		default:
+			value := normalize(v)
+			set[f.DBName] = func(row) interface{} { return value }
+		}
+	}
+	if len(set) == 0 {
+		return 0, fmt.Errorf("%s: after filtering non-updated fields, there are no column values left: %w", op, dbw.ErrInvalidParameter)
+	}
+	if opts.WithBeforeWrite != nil {
+		if err := opts.WithBeforeWrite(model); err != nil {
+			return 0, fmt.Errorf("%s: error before write: %w", op, err)
+		}
+	}
+
+	found, err := rw.find(m.table, conditions)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	now := time.Now().UTC()
+	rw.mu.Lock()
+	for _, kr := range found {
+		updated := make(row, len(kr.row))
+		for k, v := range kr.row {
+			updated[k] = v
+		}
+		for name, value := range set {
+			updated[name] = value(kr.row)
+		}
+		if vf := m.schema.LookUpField("version"); vf != nil {
+			if _, ok := set[vf.DBName]; !ok {
+				n, _ := toFloat(kr.row[vf.DBName])
+				updated[vf.DBName] = int64(n) + 1
+			}
+		}
+		for _, f := range managedUpdateTimestamps(m.schema) {
+			if _, ok := set[f.DBName]; !ok {
+				updated[f.DBName] = now
+			}
+		}
+		rw.put(m.table, kr.key, updated)
+	}
+	rw.mu.Unlock()
+	rowsUpdated := len(found)
+	if rowsUpdated > 0 && opts.WithAfterWrite != nil {
+		if err := opts.WithAfterWrite(model, rowsUpdated); err != nil {
+			return rowsUpdated, fmt.Errorf("%s: error after write: %w", op, err)
+		}
+	}
+	return rowsUpdated, nil
+}
+
+// DeleteWhere deletes every row of the model's table which matches the where
+// clause, the same as dbw.DeleteWhere(...).  Supported options: WithAllRows,
+// WithBeforeWrite, WithAfterWrite and WithTable.
+func (rw *RW) DeleteWhere(ctx context.Context, model interface{}, where string, args []interface{}, opt ...dbw.Option) (int, error) {
+	const op = "dbwfake.DeleteWhere"
+	if isNil(model) {
+		return 0, fmt.Errorf("%s: missing model: %w", op, dbw.ErrInvalidParameter)
+	}
+	opts := dbw.GetOpts(opt...)
+	conditions, err := writeWhereConditions(where, args, opts)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	m, err := rw.parse(model, opts)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := rw.inject(ctx, OpDeleteWhere, m.table); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if opts.WithBeforeWrite != nil {
+		if err := opts.WithBeforeWrite(model); err != nil {
+			return 0, fmt.Errorf("%s: error before write: %w", op, err)
+		}
+	}
+	found, err := rw.find(m.table, conditions)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	rw.mu.Lock()
+	for _, kr := range found {
+		rw.put(m.table, kr.key, nil)
+	}
+	rw.mu.Unlock()
+	rowsDeleted := len(found)
+	if rowsDeleted > 0 && opts.WithAfterWrite != nil {
+		if err := opts.WithAfterWrite(model, rowsDeleted); err != nil {
+			return rowsDeleted, fmt.Errorf("%s: error after write: %w", op, err)
+		}
+	}
+	return rowsDeleted, nil
+}
+
+// writeWhereConditions returns the conditions of the where clause for
+// UpdateWhere(...) and DeleteWhere(...)
+func writeWhereConditions(where string, args []interface{}, opts dbw.Options) ([]condition, error) {
+	const op = "dbwfake.writeWhereConditions"
+	switch {
+	case strings.TrimSpace(where) == "" && !opts.WithAllRows:
+		return nil, fmt.Errorf("%s: missing where clause (see WithAllRows): %w", op, dbw.ErrInvalidParameter)
+	case opts.WithWhereClause != "":
+		return nil, fmt.Errorf("%s: with where is not a supported option: %w", op, dbw.ErrInvalidParameter)
+	case opts.WithVersion != nil:
+		return nil, fmt.Errorf("%s: with version is not a supported option: %w", op, dbw.ErrInvalidParameter)
+	case opts.WithLookup:
+		return nil, fmt.Errorf("%s: with lookup is not a supported option: %w", op, dbw.ErrInvalidParameter)
+	}
+	conditions, err := parseWhere(where, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return conditions, nil
+}
+
+// sliceItems returns the items of a non-empty slice, which must all be the
+// same type.
+func sliceItems(items interface{}) ([]interface{}, error) {
+	const op = "dbwfake.sliceItems"
+	if isNil(items) {
+		return nil, fmt.Errorf("%s: missing items: %w", op, dbw.ErrInvalidParameter)
+	}
+	v := reflect.ValueOf(items)
+	switch {
+	case v.Kind() != reflect.Slice:
+		return nil, fmt.Errorf("%s: not a slice: %w", op, dbw.ErrInvalidParameter)
+	case v.Len() == 0:
+		return nil, fmt.Errorf("%s: missing items: %w", op, dbw.ErrInvalidParameter)
+	}
+	result := make([]interface{}, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		item := v.Index(i).Interface()
+		if isNil(item) || reflect.TypeOf(item) != reflect.TypeOf(v.Index(0).Interface()) {
+			return nil, fmt.Errorf("%s: item %d is missing or a different type: %w", op, i, dbw.ErrInvalidParameter)
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// DoTx will run the handler in a transaction, the same as dbw.DoTx(...).  The
+// handler's writes are only visible to other transactions and the fake after
+// it's committed, and they're discarded if the handler returns an error.
+// Transactions are not isolated from each other, so a transaction's commit
+// overwrites rows written by others since it began.
+func (rw *RW) DoTx(ctx context.Context, retryErrorsMatchingFn func(error) bool, retries uint, backOff dbw.Backoff, handler dbw.TxHandler) (dbw.RetryInfo, error) {
+	const op = "dbwfake.DoTx"
+	switch {
+	case backOff == nil:
+		return dbw.RetryInfo{}, fmt.Errorf("%s: missing backoff: %w", op, dbw.ErrInvalidParameter)
+	case handler == nil:
+		return dbw.RetryInfo{}, fmt.Errorf("%s: missing handler: %w", op, dbw.ErrInvalidParameter)
+	case retryErrorsMatchingFn == nil:
+		return dbw.RetryInfo{}, fmt.Errorf("%s: missing retry errors matching function: %w", op, dbw.ErrInvalidParameter)
+	}
+	info := dbw.RetryInfo{}
+	for attempts := uint(1); ; attempts++ {
+		if attempts > retries+1 {
+			return info, fmt.Errorf("%s: too many retries: %d of %d: %w", op, attempts-1, retries+1, dbw.ErrMaxRetries)
+		}
+		if err := rw.inject(ctx, OpDoTx, ""); err != nil {
+			return info, fmt.Errorf("%s: %w", op, err)
+		}
+		tx := rw.begin()
+		if err := handler(tx, tx); err != nil {
+			if retryErrorsMatchingFn(err) {
+				d := backOff.Duration(attempts)
+				info.Retries++
+				info.Backoff = info.Backoff + d
+				select {
+				case <-ctx.Done():
+					return info, fmt.Errorf("%s: cancelled: %w", op, err)
+				case <-time.After(d):
+					continue
+				}
+			}
+			return info, fmt.Errorf("%s: %w", op, err)
+		}
+		if err := rw.inject(ctx, OpCommit, ""); err != nil {
+			return info, fmt.Errorf("%s: %w", op, err)
+		}
+		rw.commit(tx)
+		return info, nil
+	}
+}
+
+// begin a transaction with a snapshot of the fake's data
+func (rw *RW) begin() *RW {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	return &RW{
+		shared: rw.shared,
+		data:   rw.data.clone(),
+		parent: rw,
+		dirty:  map[string]map[string]bool{},
+	}
+}
+
+// commit the rows written by the transaction
+func (rw *RW) commit(tx *RW) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	for table, keys := range tx.dirty {
+		for key := range keys {
+			rw.put(table, key, tx.data[table][key])
+		}
+	}
+}
+
+// Exec is not supported by the fake
+func (rw *RW) Exec(_ context.Context, _ string, _ []interface{}, _ ...dbw.Option) (int, error) {
+	return 0, fmt.Errorf("dbwfake.Exec: %w", ErrNotSupported)
+}
+
+// Query is not supported by the fake
+func (rw *RW) Query(_ context.Context, _ string, _ []interface{}, _ ...dbw.Option) (*sql.Rows, error) {
+	return nil, fmt.Errorf("dbwfake.Query: %w", ErrNotSupported)
+}
+
+// ScanRows is not supported by the fake
+func (rw *RW) ScanRows(_ *sql.Rows, _ interface{}) error {
+	return fmt.Errorf("dbwfake.ScanRows: %w", ErrNotSupported)
+}
+
+// Begin is not supported by the fake, use DoTx(...)
+func (rw *RW) Begin(_ context.Context) (*dbw.RW, error) {
+	return nil, fmt.Errorf("dbwfake.Begin: %w", ErrNotSupported)
+}
+
+// Rollback is not supported by the fake, use DoTx(...)
+func (rw *RW) Rollback(_ context.Context) error {
+	return fmt.Errorf("dbwfake.Rollback: %w", ErrNotSupported)
+}
+
+// Commit is not supported by the fake, use DoTx(...)
+func (rw *RW) Commit(_ context.Context) error {
+	return fmt.Errorf("dbwfake.Commit: %w", ErrNotSupported)
+}
+
+func isNil(i interface{}) bool {
+	if i == nil {
+		return true
+	}
+	switch reflect.TypeOf(i).Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Chan, reflect.Slice:
+		return reflect.ValueOf(i).IsNil()
+	}
+	return false
+}
+
+func contains(ss []string, t string) bool {
+	for _, s := range ss {
+		if strings.EqualFold(s, t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,434 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbwfake_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/go-dbw"
+	"github.com/hashicorp/go-dbw/dbwfake"
+	"github.com/hashicorp/go-dbw/internal/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testItem has a primary key which isn't a non-updatable field
+type testItem struct {
+	Id   string `gorm:"primaryKey"`
+	Name string
+}
+
+func testUser(t *testing.T, rw *dbwfake.RW, name, email string) *dbtest.TestUser {
+	t.Helper()
+	u, err := dbtest.NewTestUser()
+	require.NoError(t, err)
+	u.Name = name
+	u.Email = email
+	require.NoError(t, rw.Create(context.Background(), u))
+	return u
+}
+
+func TestRW_Create(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	t.Run("simple", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		rw := dbwfake.New()
+		u := testUser(t, rw, "alice", "alice@example.com")
+		assert.NotNil(u.CreateTime)
+		assert.NotNil(u.UpdateTime)
+		assert.Equal(uint32(1), u.Version)
+
+		found := dbtest.AllocTestUser()
+		found.PublicId = u.PublicId
+		require.NoError(rw.LookupBy(testCtx, &found))
+		assert.Equal("alice", found.Name)
+		assert.Equal("alice@example.com", found.Email)
+		assert.Equal(u.CreateTime.AsTime(), found.CreateTime.AsTime())
+	})
+	t.Run("duplicate", func(t *testing.T) {
+		assert := assert.New(t)
+		rw := dbwfake.New()
+		u := testUser(t, rw, "alice", "")
+		dup := dbtest.AllocTestUser()
+		dup.PublicId = u.PublicId
+		assert.ErrorIs(rw.Create(testCtx, &dup), dbwfake.ErrDuplicateKey)
+
+		var rowsAffected int64
+		assert.NoError(rw.Create(testCtx, &dup, dbw.WithOnConflict(&dbw.OnConflict{Target: dbw.Columns{"public_id"}, Action: dbw.DoNothing(true)}), dbw.WithReturnRowsAffected(&rowsAffected)))
+		assert.Equal(int64(0), rowsAffected)
+
+		dup.Name = "bob"
+		assert.NoError(rw.Create(testCtx, &dup, dbw.WithOnConflict(&dbw.OnConflict{Target: dbw.Columns{"public_id"}, Action: dbw.UpdateAll(true)}), dbw.WithReturnRowsAffected(&rowsAffected)))
+		assert.Equal(int64(1), rowsAffected)
+		found := dbtest.AllocTestUser()
+		found.PublicId = u.PublicId
+		assert.NoError(rw.LookupBy(testCtx, &found))
+		assert.Equal("bob", found.Name)
+	})
+	t.Run("generate-id", func(t *testing.T) {
+		assert := assert.New(t)
+		rw := dbwfake.New()
+		u := dbtest.AllocTestUser()
+		assert.NoError(rw.Create(testCtx, &u, dbw.WithGenerateId("u")))
+		assert.Regexp("^u_", u.PublicId)
+	})
+	t.Run("missing-primary-key", func(t *testing.T) {
+		rw := dbwfake.New()
+		u := dbtest.AllocTestUser()
+		assert.ErrorIs(t, rw.Create(testCtx, &u), dbw.ErrInvalidParameter)
+	})
+	t.Run("create-items", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		rw := dbwfake.New()
+		var items []*dbtest.TestUser
+		for i := 0; i < 3; i++ {
+			u, err := dbtest.NewTestUser()
+			require.NoError(err)
+			items = append(items, u)
+		}
+		var rowsAffected int64
+		require.NoError(rw.CreateItems(testCtx, items, dbw.WithReturnRowsAffected(&rowsAffected)))
+		assert.Equal(int64(3), rowsAffected)
+		var found []*dbtest.TestUser
+		require.NoError(rw.SearchWhere(testCtx, &found, "", nil))
+		assert.Len(found, 3)
+	})
+}
+
+func TestRW_Update(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	t.Run("field-mask", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		rw := dbwfake.New()
+		u := testUser(t, rw, "alice", "alice@example.com")
+
+		upd := u.Clone().(*dbtest.TestUser)
+		upd.Name = "bob"
+		upd.Email = "ignored@example.com"
+		n, err := rw.Update(testCtx, upd, []string{"Name"}, []string{"Email"}, dbw.WithVersion(&u.Version))
+		require.NoError(err)
+		assert.Equal(1, n)
+		assert.Equal("bob", upd.Name)
+		assert.Empty(upd.Email)
+		assert.Equal(uint32(2), upd.Version)
+	})
+	t.Run("stale-version", func(t *testing.T) {
+		rw := dbwfake.New()
+		u := testUser(t, rw, "alice", "")
+		u.Name = "bob"
+		version := uint32(22)
+		_, err := rw.Update(testCtx, u, []string{"Name"}, nil, dbw.WithVersion(&version))
+		assert.ErrorIs(t, err, dbw.ErrStaleVersion)
+	})
+	t.Run("where-miss", func(t *testing.T) {
+		assert := assert.New(t)
+		rw := dbwfake.New()
+		u := testUser(t, rw, "alice", "")
+		u.Name = "bob"
+		n, err := rw.Update(testCtx, u, []string{"Name"}, nil, dbw.WithWhere("name = ?", "carol"))
+		assert.NoError(err)
+		assert.Equal(0, n)
+		assert.Equal("alice", u.Name)
+	})
+	t.Run("not-found", func(t *testing.T) {
+		rw := dbwfake.New()
+		u, err := dbtest.NewTestUser()
+		require.NoError(t, err)
+		_, err = rw.Update(testCtx, u, []string{"Name"}, nil)
+		assert.ErrorIs(t, err, dbw.ErrRecordNotFound)
+	})
+	t.Run("primary-key", func(t *testing.T) {
+		rw := dbwfake.New()
+		item := &testItem{Id: "item-1", Name: "alice"}
+		require.NoError(t, rw.Create(testCtx, item))
+		_, err := rw.Update(testCtx, item, []string{"Id"}, nil)
+		assert.ErrorIs(t, err, dbw.ErrInvalidFieldMask)
+	})
+	t.Run("missing-paths", func(t *testing.T) {
+		rw := dbwfake.New()
+		u := testUser(t, rw, "alice", "")
+		_, err := rw.Update(testCtx, u, nil, nil)
+		assert.ErrorIs(t, err, dbw.ErrInvalidParameter)
+	})
+}
+
+func TestRW_Delete(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	assert, require := assert.New(t), require.New(t)
+	rw := dbwfake.New()
+	u := testUser(t, rw, "alice", "")
+	u2 := testUser(t, rw, "bob", "")
+	u3 := testUser(t, rw, "carol", "")
+
+	version := uint32(22)
+	n, err := rw.Delete(testCtx, u, dbw.WithVersion(&version))
+	require.NoError(err)
+	assert.Equal(0, n)
+
+	n, err = rw.Delete(testCtx, u)
+	require.NoError(err)
+	assert.Equal(1, n)
+	assert.ErrorIs(rw.LookupBy(testCtx, u), dbw.ErrRecordNotFound)
+
+	n, err = rw.DeleteItems(testCtx, []*dbtest.TestUser{u, u2, u3})
+	require.NoError(err)
+	assert.Equal(2, n)
+}
+
+func TestRW_UpdateWhere(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	assert, require := assert.New(t), require.New(t)
+	rw := dbwfake.New()
+	u := testUser(t, rw, "alice", "shared@example.com")
+	u2 := testUser(t, rw, "bob", "shared@example.com")
+	u3 := testUser(t, rw, "carol", "carol@example.com")
+	model := &dbtest.TestUser{StoreTestUser: &dbtest.StoreTestUser{}}
+
+	n, err := rw.UpdateWhere(testCtx, model, dbw.SetColumnValues(map[string]interface{}{
+		"phone_number": "555-1212",
+		"name":         dbw.Expr("NULL"),
+	}), "email = ?", []interface{}{"shared@example.com"})
+	require.NoError(err)
+	assert.Equal(2, n)
+	for _, want := range []*dbtest.TestUser{u, u2} {
+		found := dbtest.AllocTestUser()
+		found.PublicId = want.PublicId
+		require.NoError(rw.LookupBy(testCtx, &found))
+		assert.Equal("555-1212", found.PhoneNumber)
+		assert.Empty(found.Name)
+		assert.Equal(want.Version+1, found.Version)
+	}
+
+	n, err = rw.UpdateWhere(testCtx, model, dbw.SetColumnValues(map[string]interface{}{"email": dbw.Column{Name: "name"}}), "public_id = ?", []interface{}{u3.PublicId})
+	require.NoError(err)
+	assert.Equal(1, n)
+	found := dbtest.AllocTestUser()
+	found.PublicId = u3.PublicId
+	require.NoError(rw.LookupBy(testCtx, &found))
+	assert.Equal("carol", found.Email)
+
+	_, err = rw.UpdateWhere(testCtx, model, dbw.SetColumnValues(map[string]interface{}{"name": "dave"}), "", nil)
+	assert.ErrorIs(err, dbw.ErrInvalidParameter)
+	_, err = rw.UpdateWhere(testCtx, model, dbw.SetColumnValues(map[string]interface{}{"unknown": "dave"}), "name = ?", []interface{}{"carol"})
+	assert.ErrorIs(err, dbw.ErrInvalidParameter)
+	_, err = rw.UpdateWhere(testCtx, model, dbw.SetColumnValues(map[string]interface{}{"name": dbw.Expr("upper(name)")}), "name = ?", []interface{}{"carol"})
+	assert.ErrorIs(err, dbwfake.ErrNotSupported)
+
+	n, err = rw.UpdateWhere(testCtx, model, dbw.SetColumnValues(map[string]interface{}{"name": "dave"}), "", nil, dbw.WithAllRows(true))
+	require.NoError(err)
+	assert.Equal(3, n)
+}
+
+func TestRW_DeleteWhere(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	assert, require := assert.New(t), require.New(t)
+	rw := dbwfake.New()
+	u := testUser(t, rw, "alice", "shared@example.com")
+	testUser(t, rw, "bob", "shared@example.com")
+	testUser(t, rw, "carol", "carol@example.com")
+	model := &dbtest.TestUser{StoreTestUser: &dbtest.StoreTestUser{}}
+
+	n, err := rw.DeleteWhere(testCtx, model, "email = ?", []interface{}{"shared@example.com"})
+	require.NoError(err)
+	assert.Equal(2, n)
+	assert.ErrorIs(rw.LookupBy(testCtx, u), dbw.ErrRecordNotFound)
+
+	_, err = rw.DeleteWhere(testCtx, model, "", nil)
+	assert.ErrorIs(err, dbw.ErrInvalidParameter)
+	n, err = rw.DeleteWhere(testCtx, model, "", nil, dbw.WithAllRows(true))
+	require.NoError(err)
+	assert.Equal(1, n)
+}
+
+func TestRW_SearchWhere(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	rw := dbwfake.New()
+	testUser(t, rw, "alice", "alice@example.com")
+	testUser(t, rw, "bob", "bob@example.com")
+	testUser(t, rw, "carol", "")
+
+	tests := []struct {
+		name      string
+		where     string
+		args      []interface{}
+		opt       []dbw.Option
+		wantNames []string
+		wantErrIs error
+	}{
+		{
+			name:      "all",
+			opt:       []dbw.Option{dbw.WithOrder("name desc")},
+			wantNames: []string{"carol", "bob", "alice"},
+		},
+		{
+			name:      "equal",
+			where:     "name = ?",
+			args:      []interface{}{"bob"},
+			wantNames: []string{"bob"},
+		},
+		{
+			name:      "and",
+			where:     "name <> ? and email like ?",
+			args:      []interface{}{"bob", "%@example.com"},
+			wantNames: []string{"alice"},
+		},
+		{
+			name:      "in",
+			where:     "name in (?)",
+			args:      []interface{}{[]string{"alice", "carol"}},
+			opt:       []dbw.Option{dbw.WithOrder("name")},
+			wantNames: []string{"alice", "carol"},
+		},
+		{
+			name:      "is-null",
+			where:     "email is null",
+			wantNames: []string{"carol"},
+		},
+		{
+			name:      "limit",
+			opt:       []dbw.Option{dbw.WithOrder("name asc"), dbw.WithLimit(2)},
+			wantNames: []string{"alice", "bob"},
+		},
+		{
+			name:      "unsupported",
+			where:     "name = ? or name = ?",
+			args:      []interface{}{"alice", "bob"},
+			wantErrIs: dbwfake.ErrNotSupported,
+		},
+		{
+			name:      "unknown-column",
+			where:     "nope = ?",
+			args:      []interface{}{"alice"},
+			wantErrIs: dbw.ErrInvalidParameter,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert, require := assert.New(t), require.New(t)
+			var found []dbtest.TestUser
+			err := rw.SearchWhere(testCtx, &found, tt.where, tt.args, tt.opt...)
+			if tt.wantErrIs != nil {
+				require.Error(err)
+				assert.ErrorIs(err, tt.wantErrIs)
+				return
+			}
+			require.NoError(err)
+			var names []string
+			for _, u := range found {
+				names = append(names, u.Name)
+			}
+			if len(tt.opt) == 0 {
+				assert.ElementsMatch(tt.wantNames, names)
+				return
+			}
+			assert.Equal(tt.wantNames, names)
+		})
+	}
+	t.Run("lookup-where", func(t *testing.T) {
+		u := dbtest.AllocTestUser()
+		require.NoError(t, rw.LookupWhere(testCtx, &u, "email = ?", []interface{}{"bob@example.com"}))
+		assert.Equal(t, "bob", u.Name)
+		assert.ErrorIs(t, rw.LookupWhere(testCtx, &u, "name = ?", []interface{}{"dave"}), dbw.ErrRecordNotFound)
+	})
+}
+
+func TestRW_DoTx(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	retryOnFn := func(error) bool { return false }
+	t.Run("commit", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		rw := dbwfake.New()
+		u := testUser(t, rw, "alice", "")
+		var created *dbtest.TestUser
+		_, err := rw.DoTx(testCtx, retryOnFn, 0, dbw.ExpBackoff{}, func(r dbw.Reader, w dbw.Writer) error {
+			created = testUser(t, w.(*dbwfake.RW), "bob", "")
+			_, err := w.Delete(testCtx, u)
+			return err
+		})
+		require.NoError(err)
+		assert.ErrorIs(rw.LookupBy(testCtx, u), dbw.ErrRecordNotFound)
+		assert.NoError(rw.LookupBy(testCtx, created))
+	})
+	t.Run("rollback", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		rw := dbwfake.New()
+		u := testUser(t, rw, "alice", "")
+		var created *dbtest.TestUser
+		_, err := rw.DoTx(testCtx, retryOnFn, 0, dbw.ExpBackoff{}, func(r dbw.Reader, w dbw.Writer) error {
+			created = testUser(t, w.(*dbwfake.RW), "bob", "")
+			if _, err := w.Delete(testCtx, u); err != nil {
+				return err
+			}
+			// the transaction sees its own writes
+			if err := r.LookupBy(testCtx, created); err != nil {
+				return err
+			}
+			return errors.New("rollback")
+		})
+		require.Error(err)
+		assert.NoError(rw.LookupBy(testCtx, u))
+		assert.ErrorIs(rw.LookupBy(testCtx, created), dbw.ErrRecordNotFound)
+	})
+	t.Run("retry", func(t *testing.T) {
+		assert := assert.New(t)
+		rw := dbwfake.New()
+		errRetry := errors.New("retry")
+		attempts := 0
+		info, err := rw.DoTx(testCtx, func(err error) bool { return errors.Is(err, errRetry) }, 2, dbw.ConstBackoff{DurationMs: 1}, func(dbw.Reader, dbw.Writer) error {
+			attempts++
+			if attempts < 3 {
+				return errRetry
+			}
+			return nil
+		})
+		assert.NoError(err)
+		assert.Equal(2, info.Retries)
+	})
+	t.Run("commit-error", func(t *testing.T) {
+		assert := assert.New(t)
+		rw := dbwfake.New()
+		errCommit := errors.New("commit failed")
+		rw.FailNext(dbwfake.OpCommit, errCommit)
+		var created *dbtest.TestUser
+		_, err := rw.DoTx(testCtx, retryOnFn, 0, dbw.ExpBackoff{}, func(_ dbw.Reader, w dbw.Writer) error {
+			created = testUser(t, w.(*dbwfake.RW), "bob", "")
+			return nil
+		})
+		assert.ErrorIs(err, errCommit)
+		assert.ErrorIs(rw.LookupBy(testCtx, created), dbw.ErrRecordNotFound)
+	})
+}
+
+func TestRW_ErrorInjection(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	assert := assert.New(t)
+	errInjected := errors.New("injected")
+	rw := dbwfake.New(dbwfake.WithErrorInjector(func(op dbwfake.Op, table string) error {
+		if op == dbwfake.OpUpdate && table == "db_test_user" {
+			return errInjected
+		}
+		return nil
+	}))
+	u := testUser(t, rw, "alice", "")
+	_, err := rw.Update(testCtx, u, []string{"Name"}, nil)
+	assert.ErrorIs(err, errInjected)
+
+	rw.SetErrorInjector(nil)
+	rw.FailNext(dbwfake.OpLookupBy, errInjected)
+	assert.ErrorIs(rw.LookupBy(testCtx, u), errInjected)
+	assert.NoError(rw.LookupBy(testCtx, u))
+
+	_, err = rw.Exec(testCtx, "select 1", nil)
+	assert.ErrorIs(err, dbwfake.ErrNotSupported)
+}
@@ -0,0 +1,439 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbwfake
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-dbw"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm/schema"
+)
+
+// Errors which are commonly injected via Faulty.Inject(...).  The postgres
+// errors are *pgconn.PgError, so they can be matched by their code the same
+// as the errors returned by a real database.
+var (
+	// ErrSerializationFailure is a postgres serialization failure (40001)
+	ErrSerializationFailure error = &pgconn.PgError{Severity: "ERROR", Code: "40001", Message: "could not serialize access due to concurrent update"}
+
+	// ErrUniqueViolation is a postgres unique violation (23505)
+	ErrUniqueViolation error = &pgconn.PgError{Severity: "ERROR", Code: "23505", Message: "duplicate key value violates unique constraint"}
+
+	// ErrStatementTimeout is a postgres statement timeout (57014)
+	ErrStatementTimeout error = &pgconn.PgError{Severity: "ERROR", Code: "57014", Message: "canceling statement due to statement timeout"}
+
+	// ErrCommitFailed is a failed commit
+	ErrCommitFailed = errors.New("commit failed")
+)
+
+// Operations which are only used by Faulty, since the fake doesn't support
+// them.
+const (
+	OpExec     Op = "Exec"
+	OpQuery    Op = "Query"
+	OpBegin    Op = "Begin"
+	OpRollback Op = "Rollback"
+)
+
+// ReadWriter is both a dbw.Reader and dbw.Writer, like a dbw.RW or an RW fake.
+type ReadWriter interface {
+	dbw.Reader
+	dbw.Writer
+}
+
+// Faulty wraps a dbw.Reader and dbw.Writer and injects errors and latency
+// into their operations, so retry paths can be tested deterministically.  A
+// fault applies to the operations and tables which match its options (see
+// WithOps, WithTables, WithMatch, WithNthCall and WithTimes).
+//
+// For DoTx, faults are injected at the start of each attempt, so they are
+// passed to the retryErrorsMatchingFn and can be retried.  OpCommit faults are
+// injected after the handler succeeds, which rolls back the transaction and
+// returns the error without a retry, the same as a failed commit.  The Reader
+// and Writer passed to the handler share the faults of the Faulty.
+type Faulty struct {
+	r      dbw.Reader
+	w      dbw.Writer
+	faults *faults
+}
+
+var (
+	_ dbw.Reader      = (*Faulty)(nil)
+	_ dbw.Writer      = (*Faulty)(nil)
+	_ dbw.WhereWriter = (*Faulty)(nil)
+)
+
+// fault is an injected error and/or latency
+type fault struct {
+	err     error
+	latency time.Duration
+	opts    faultOptions
+	matched int
+	applied int
+}
+
+// faults are shared by a Faulty and its transactions
+type faults struct {
+	schemas sync.Map
+
+	mu       sync.Mutex
+	faults   []*fault
+	calls    map[Op]int
+	injector ErrorInjector
+}
+
+// NewFaulty wraps the ReadWriter.
+func NewFaulty(next ReadWriter) *Faulty {
+	return &Faulty{
+		r: next,
+		w: next,
+		faults: &faults{
+			calls: map[Op]int{},
+		},
+	}
+}
+
+// Inject an error into the operations which match the options.
+func (f *Faulty) Inject(err error, opt ...FaultOption) {
+	f.add(&fault{err: err, opts: getFaultOpts(opt...)})
+}
+
+// AddLatency adds latency to the operations which match the options.
+// Latency is added before an operation and it's cancelled with the
+// operation's context.
+func (f *Faulty) AddLatency(d time.Duration, opt ...FaultOption) {
+	f.add(&fault{latency: d, opts: getFaultOpts(opt...)})
+}
+
+func (f *Faulty) add(flt *fault) {
+	f.faults.mu.Lock()
+	defer f.faults.mu.Unlock()
+	f.faults.faults = append(f.faults.faults, flt)
+}
+
+// SetErrorInjector sets an ErrorInjector which is called before every
+// operation, after the faults.  A nil injector removes it.
+func (f *Faulty) SetErrorInjector(fn ErrorInjector) {
+	f.faults.mu.Lock()
+	defer f.faults.mu.Unlock()
+	f.faults.injector = fn
+}
+
+// Calls returns the number of calls of the operation.
+func (f *Faulty) Calls(op Op) int {
+	f.faults.mu.Lock()
+	defer f.faults.mu.Unlock()
+	return f.faults.calls[op]
+}
+
+// Reset removes all the faults and the ErrorInjector, and resets the call
+// counts.
+func (f *Faulty) Reset() {
+	f.faults.mu.Lock()
+	defer f.faults.mu.Unlock()
+	f.faults.faults = nil
+	f.faults.calls = map[Op]int{}
+	f.faults.injector = nil
+}
+
+// inject the faults which apply to the operation
+func (f *Faulty) inject(ctx context.Context, op Op, table string) error {
+	var err error
+	var latency time.Duration
+	f.faults.mu.Lock()
+	f.faults.calls[op]++
+	for _, flt := range f.faults.faults {
+		if !flt.matches(op, table) {
+			continue
+		}
+		flt.matched++
+		if flt.matched < flt.opts.withNthCall || (flt.opts.withTimes > 0 && flt.applied >= flt.opts.withTimes) {
+			continue
+		}
+		flt.applied++
+		latency += flt.latency
+		if err == nil {
+			err = flt.err
+		}
+	}
+	injector := f.faults.injector
+	f.faults.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(latency):
+		}
+	}
+	if err == nil && injector != nil {
+		err = injector(op, table)
+	}
+	return err
+}
+
+func (flt *fault) matches(op Op, table string) bool {
+	if len(flt.opts.withOps) > 0 {
+		found := false
+		for _, o := range flt.opts.withOps {
+			found = found || o == op
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(flt.opts.withTables) > 0 && !contains(flt.opts.withTables, table) {
+		return false
+	}
+	return flt.opts.withMatch == nil || flt.opts.withMatch(op, table)
+}
+
+// tableOf returns the table name of the resource(s), or an empty string if
+// it's unknown.
+func (f *Faulty) tableOf(i interface{}, opt []dbw.Option) string {
+	if t := dbw.GetOpts(opt...).WithTable; t != "" {
+		return t
+	}
+	if isNil(i) {
+		return ""
+	}
+	v := reflect.ValueOf(i)
+	for v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Slice {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice {
+		if v.Len() == 0 {
+			t := v.Type().Elem()
+			if t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+			v = reflect.New(t)
+		} else {
+			v = v.Index(0)
+		}
+	}
+	if v.Kind() != reflect.Ptr {
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		v = ptr
+	}
+	if tabler, ok := v.Interface().(schema.Tabler); ok {
+		return tabler.TableName()
+	}
+	s, err := schema.Parse(v.Interface(), &f.faults.schemas, schema.NamingStrategy{})
+	if err != nil {
+		return ""
+	}
+	return s.Table
+}
+
+// LookupBy with injected faults
+func (f *Faulty) LookupBy(ctx context.Context, resource interface{}, opt ...dbw.Option) error {
+	const op = "dbwfake.(Faulty).LookupBy"
+	if err := f.inject(ctx, OpLookupBy, f.tableOf(resource, opt)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return f.r.LookupBy(ctx, resource, opt...)
+}
+
+// LookupByPublicId with injected faults
+func (f *Faulty) LookupByPublicId(ctx context.Context, resource dbw.ResourcePublicIder, opt ...dbw.Option) error {
+	const op = "dbwfake.(Faulty).LookupByPublicId"
+	if err := f.inject(ctx, OpLookupByPublicId, f.tableOf(resource, opt)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return f.r.LookupByPublicId(ctx, resource, opt...)
+}
+
+// LookupWhere with injected faults
+func (f *Faulty) LookupWhere(ctx context.Context, resource interface{}, where string, args []interface{}, opt ...dbw.Option) error {
+	const op = "dbwfake.(Faulty).LookupWhere"
+	if err := f.inject(ctx, OpLookupWhere, f.tableOf(resource, opt)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return f.r.LookupWhere(ctx, resource, where, args, opt...)
+}
+
+// SearchWhere with injected faults
+func (f *Faulty) SearchWhere(ctx context.Context, resources interface{}, where string, args []interface{}, opt ...dbw.Option) error {
+	const op = "dbwfake.(Faulty).SearchWhere"
+	if err := f.inject(ctx, OpSearchWhere, f.tableOf(resources, opt)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return f.r.SearchWhere(ctx, resources, where, args, opt...)
+}
+
+// Query with injected faults.  The table is always empty.
+func (f *Faulty) Query(ctx context.Context, sql string, values []interface{}, opt ...dbw.Option) (*sql.Rows, error) {
+	const op = "dbwfake.(Faulty).Query"
+	if err := f.inject(ctx, OpQuery, ""); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return f.r.Query(ctx, sql, values, opt...)
+}
+
+// ScanRows doesn't inject faults
+func (f *Faulty) ScanRows(rows *sql.Rows, result interface{}) error {
+	return f.r.ScanRows(rows, result)
+}
+
+// Dialect doesn't inject faults
+func (f *Faulty) Dialect() (dbw.DbType, string, error) {
+	return f.r.Dialect()
+}
+
+// Create with injected faults
+func (f *Faulty) Create(ctx context.Context, i interface{}, opt ...dbw.Option) error {
+	const op = "dbwfake.(Faulty).Create"
+	if err := f.inject(ctx, OpCreate, f.tableOf(i, opt)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return f.w.Create(ctx, i, opt...)
+}
+
+// CreateItems with injected faults
+func (f *Faulty) CreateItems(ctx context.Context, createItems interface{}, opt ...dbw.Option) error {
+	const op = "dbwfake.(Faulty).CreateItems"
+	if err := f.inject(ctx, OpCreateItems, f.tableOf(createItems, opt)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return f.w.CreateItems(ctx, createItems, opt...)
+}
+
+// Update with injected faults
+func (f *Faulty) Update(ctx context.Context, i interface{}, fieldMaskPaths []string, setToNullPaths []string, opt ...dbw.Option) (int, error) {
+	const op = "dbwfake.(Faulty).Update"
+	if err := f.inject(ctx, OpUpdate, f.tableOf(i, opt)); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return f.w.Update(ctx, i, fieldMaskPaths, setToNullPaths, opt...)
+}
+
+// Delete with injected faults
+func (f *Faulty) Delete(ctx context.Context, i interface{}, opt ...dbw.Option) (int, error) {
+	const op = "dbwfake.(Faulty).Delete"
+	if err := f.inject(ctx, OpDelete, f.tableOf(i, opt)); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return f.w.Delete(ctx, i, opt...)
+}
+
+// DeleteItems with injected faults
+func (f *Faulty) DeleteItems(ctx context.Context, deleteItems interface{}, opt ...dbw.Option) (int, error) {
+	const op = "dbwfake.(Faulty).DeleteItems"
+	if err := f.inject(ctx, OpDeleteItems, f.tableOf(deleteItems, opt)); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return f.w.DeleteItems(ctx, deleteItems, opt...)
+}
+
+// UpdateWhere with injected faults
+func (f *Faulty) UpdateWhere(ctx context.Context, model interface{}, values []dbw.ColumnValue, where string, args []interface{}, opt ...dbw.Option) (int, error) {
+	const op = "dbwfake.(Faulty).UpdateWhere"
+	ww, ok := f.w.(dbw.WhereWriter)
+	if !ok {
+		return 0, fmt.Errorf("%s: %T is not a dbw.WhereWriter: %w", op, f.w, ErrNotSupported)
+	}
+	if err := f.inject(ctx, OpUpdateWhere, f.tableOf(model, opt)); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return ww.UpdateWhere(ctx, model, values, where, args, opt...)
+}
+
+// DeleteWhere with injected faults
+func (f *Faulty) DeleteWhere(ctx context.Context, model interface{}, where string, args []interface{}, opt ...dbw.Option) (int, error) {
+	const op = "dbwfake.(Faulty).DeleteWhere"
+	ww, ok := f.w.(dbw.WhereWriter)
+	if !ok {
+		return 0, fmt.Errorf("%s: %T is not a dbw.WhereWriter: %w", op, f.w, ErrNotSupported)
+	}
+	if err := f.inject(ctx, OpDeleteWhere, f.tableOf(model, opt)); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return ww.DeleteWhere(ctx, model, where, args, opt...)
+}
+
+// Exec with injected faults.  The table is always empty.
+func (f *Faulty) Exec(ctx context.Context, sql string, values []interface{}, opt ...dbw.Option) (int, error) {
+	const op = "dbwfake.(Faulty).Exec"
+	if err := f.inject(ctx, OpExec, ""); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return f.w.Exec(ctx, sql, values, opt...)
+}
+
+// Begin with injected faults.  The transaction returned doesn't inject
+// faults.
+func (f *Faulty) Begin(ctx context.Context) (*dbw.RW, error) {
+	const op = "dbwfake.(Faulty).Begin"
+	if err := f.inject(ctx, OpBegin, ""); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return f.w.Begin(ctx)
+}
+
+// Rollback with injected faults
+func (f *Faulty) Rollback(ctx context.Context) error {
+	const op = "dbwfake.(Faulty).Rollback"
+	if err := f.inject(ctx, OpRollback, ""); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return f.w.Rollback(ctx)
+}
+
+// Commit with injected faults
+func (f *Faulty) Commit(ctx context.Context) error {
+	const op = "dbwfake.(Faulty).Commit"
+	if err := f.inject(ctx, OpCommit, ""); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return f.w.Commit(ctx)
+}
+
+// commitFault is an injected commit error, which is never retried
+type commitFault struct {
+	err error
+}
+
+func (e *commitFault) Error() string { return e.err.Error() }
+func (e *commitFault) Unwrap() error { return e.err }
+
+// DoTx with injected faults.  See Faulty for how faults are injected into
+// transactions.
+func (f *Faulty) DoTx(ctx context.Context, retryErrorsMatchingFn func(error) bool, retries uint, backOff dbw.Backoff, handler dbw.TxHandler) (dbw.RetryInfo, error) {
+	const op = "dbwfake.(Faulty).DoTx"
+	var wrappedHandler dbw.TxHandler
+	if handler != nil {
+		wrappedHandler = func(r dbw.Reader, w dbw.Writer) error {
+			if err := f.inject(ctx, OpDoTx, ""); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			if err := handler(&Faulty{r: r, w: w, faults: f.faults}, &Faulty{r: r, w: w, faults: f.faults}); err != nil {
+				return err
+			}
+			if err := f.inject(ctx, OpCommit, ""); err != nil {
+				return &commitFault{err: fmt.Errorf("%s: %w", op, err)}
+			}
+			return nil
+		}
+	}
+	var wrappedRetryFn func(error) bool
+	if retryErrorsMatchingFn != nil {
+		wrappedRetryFn = func(err error) bool {
+			var cf *commitFault
+			if errors.As(err, &cf) {
+				return false
+			}
+			return retryErrorsMatchingFn(err)
+		}
+	}
+	return f.w.DoTx(ctx, wrappedRetryFn, retries, backOff, wrappedHandler)
+}
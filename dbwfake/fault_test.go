@@ -0,0 +1,169 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbwfake_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-dbw"
+	"github.com/hashicorp/go-dbw/dbwfake"
+	"github.com/hashicorp/go-dbw/internal/dbtest"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func isSerializationFailure(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "40001"
+}
+
+func TestFaulty_Inject(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	newUser := func(t *testing.T) *dbtest.TestUser {
+		u, err := dbtest.NewTestUser()
+		require.NoError(t, err)
+		return u
+	}
+	tests := []struct {
+		name      string
+		opt       []dbwfake.FaultOption
+		wantFails []bool
+	}{
+		{
+			name:      "every-call",
+			wantFails: []bool{true, true, true},
+		},
+		{
+			name:      "nth-call-once",
+			opt:       []dbwfake.FaultOption{dbwfake.WithNthCall(2), dbwfake.WithTimes(1)},
+			wantFails: []bool{false, true, false},
+		},
+		{
+			name:      "other-op",
+			opt:       []dbwfake.FaultOption{dbwfake.WithOps(dbwfake.OpUpdate)},
+			wantFails: []bool{false, false},
+		},
+		{
+			name:      "table",
+			opt:       []dbwfake.FaultOption{dbwfake.WithOps(dbwfake.OpCreate), dbwfake.WithTables("db_test_user")},
+			wantFails: []bool{true, true},
+		},
+		{
+			name:      "other-table",
+			opt:       []dbwfake.FaultOption{dbwfake.WithTables("db_test_car")},
+			wantFails: []bool{false, false},
+		},
+		{
+			name: "predicate",
+			opt: []dbwfake.FaultOption{dbwfake.WithMatch(func(op dbwfake.Op, table string) bool {
+				return op == dbwfake.OpCreate && table == "db_test_user"
+			})},
+			wantFails: []bool{true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			f := dbwfake.NewFaulty(dbwfake.New())
+			f.Inject(dbwfake.ErrUniqueViolation, tt.opt...)
+			for i, wantFail := range tt.wantFails {
+				err := f.Create(testCtx, newUser(t))
+				if wantFail {
+					assert.ErrorIs(err, dbwfake.ErrUniqueViolation, "call %d", i+1)
+					continue
+				}
+				assert.NoError(err, "call %d", i+1)
+			}
+			assert.Equal(len(tt.wantFails), f.Calls(dbwfake.OpCreate))
+		})
+	}
+}
+
+func TestFaulty_DoTx(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	conn, _ := dbw.TestSetup(t)
+	f := dbwfake.NewFaulty(dbw.New(conn))
+
+	t.Run("retry-serialization-failure", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		f.Reset()
+		f.Inject(dbwfake.ErrSerializationFailure, dbwfake.WithOps(dbwfake.OpCreate), dbwfake.WithTimes(2))
+		u, err := dbtest.NewTestUser()
+		require.NoError(err)
+		info, err := f.DoTx(testCtx, isSerializationFailure, 3, dbw.ConstBackoff{DurationMs: 1}, func(_ dbw.Reader, w dbw.Writer) error {
+			return w.Create(testCtx, u.Clone())
+		})
+		require.NoError(err)
+		assert.Equal(2, info.Retries)
+		assert.Equal(3, f.Calls(dbwfake.OpCreate))
+		assert.NoError(f.LookupBy(testCtx, u))
+	})
+	t.Run("max-retries", func(t *testing.T) {
+		f.Reset()
+		f.Inject(dbwfake.ErrSerializationFailure, dbwfake.WithOps(dbwfake.OpDoTx))
+		info, err := f.DoTx(testCtx, isSerializationFailure, 2, dbw.ConstBackoff{DurationMs: 1}, func(dbw.Reader, dbw.Writer) error {
+			return nil
+		})
+		assert.ErrorIs(t, err, dbw.ErrMaxRetries)
+		assert.Equal(t, 3, info.Retries)
+	})
+	t.Run("commit-failure", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		f.Reset()
+		f.Inject(dbwfake.ErrSerializationFailure, dbwfake.WithOps(dbwfake.OpCommit))
+		u, err := dbtest.NewTestUser()
+		require.NoError(err)
+		info, err := f.DoTx(testCtx, isSerializationFailure, 3, dbw.ConstBackoff{DurationMs: 1}, func(_ dbw.Reader, w dbw.Writer) error {
+			return w.Create(testCtx, u.Clone())
+		})
+		assert.ErrorIs(err, dbwfake.ErrSerializationFailure)
+		assert.Equal(0, info.Retries)
+		assert.ErrorIs(f.LookupBy(testCtx, u), dbw.ErrRecordNotFound)
+	})
+}
+
+func TestFaulty_AddLatency(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	f := dbwfake.NewFaulty(dbwfake.New())
+	f.AddLatency(50*time.Millisecond, dbwfake.WithOps(dbwfake.OpSearchWhere))
+
+	var users []*dbtest.TestUser
+	start := time.Now()
+	assert.NoError(f.SearchWhere(context.Background(), &users, "", nil))
+	assert.GreaterOrEqual(time.Since(start), 50*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(f.SearchWhere(ctx, &users, "", nil), context.DeadlineExceeded)
+}
+
+func TestFaulty_WhereWriter(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	t.Run("where-writer", func(t *testing.T) {
+		assert := assert.New(t)
+		f := dbwfake.NewFaulty(dbwfake.New())
+		f.Inject(errors.New("injected"), dbwfake.WithOps(dbwfake.OpDeleteWhere))
+		_, err := f.UpdateWhere(testCtx, &dbtest.TestUser{}, dbw.SetColumnValues(map[string]interface{}{"name": "alice"}), "", nil, dbw.WithAllRows(true))
+		assert.NoError(err)
+		_, err = f.DeleteWhere(testCtx, &dbtest.TestUser{}, "", nil, dbw.WithAllRows(true))
+		assert.EqualError(err, "dbwfake.(Faulty).DeleteWhere: injected")
+	})
+	t.Run("not-a-where-writer", func(t *testing.T) {
+		assert := assert.New(t)
+		// only the methods of dbw.Reader and dbw.Writer are promoted
+		f := dbwfake.NewFaulty(struct{ dbwfake.ReadWriter }{dbwfake.New()})
+		_, err := f.UpdateWhere(testCtx, &dbtest.TestUser{}, dbw.SetColumnValues(map[string]interface{}{"name": "alice"}), "", nil, dbw.WithAllRows(true))
+		assert.ErrorIs(err, dbwfake.ErrNotSupported)
+		_, err = f.DeleteWhere(testCtx, &dbtest.TestUser{}, "", nil, dbw.WithAllRows(true))
+		assert.ErrorIs(err, dbwfake.ErrNotSupported)
+	})
+}
@@ -0,0 +1,115 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbwfake
+
+import (
+	"github.com/hashicorp/go-dbw"
+)
+
+// getOpts - iterate the inbound Options and return a struct.
+func getOpts(opt ...Option) options {
+	opts := getDefaultOptions()
+	for _, o := range opt {
+		if o != nil {
+			o(&opts)
+		}
+	}
+	return opts
+}
+
+// Option - how Options are passed as arguments.
+type Option func(*options)
+
+// options = how options are represented
+type options struct {
+	withDialect       dbw.DbType
+	withErrorInjector ErrorInjector
+}
+
+func getDefaultOptions() options {
+	return options{
+		withDialect: dbw.UnknownDB,
+	}
+}
+
+// WithDialect specifies an optional dialect returned by the fake's Dialect()
+// The default is dbw.UnknownDB
+func WithDialect(t dbw.DbType) Option {
+	return func(o *options) {
+		o.withDialect = t
+	}
+}
+
+// WithErrorInjector specifies an optional ErrorInjector which is called
+// before every operation.  See RW.SetErrorInjector(...)
+func WithErrorInjector(fn ErrorInjector) Option {
+	return func(o *options) {
+		o.withErrorInjector = fn
+	}
+}
+
+// FaultOption - how fault options are passed as arguments to
+// Faulty.Inject(...) and Faulty.AddLatency(...)
+type FaultOption func(*faultOptions)
+
+// faultOptions = how fault options are represented
+type faultOptions struct {
+	withOps     []Op
+	withTables  []string
+	withMatch   func(op Op, table string) bool
+	withNthCall int
+	withTimes   int
+}
+
+func getFaultOpts(opt ...FaultOption) faultOptions {
+	opts := faultOptions{
+		withNthCall: 1,
+	}
+	for _, o := range opt {
+		if o != nil {
+			o(&opts)
+		}
+	}
+	return opts
+}
+
+// WithOps specifies the operations a fault applies to.  The default is every
+// operation.
+func WithOps(ops ...Op) FaultOption {
+	return func(o *faultOptions) {
+		o.withOps = ops
+	}
+}
+
+// WithTables specifies the tables a fault applies to.  The default is every
+// table.
+func WithTables(tables ...string) FaultOption {
+	return func(o *faultOptions) {
+		o.withTables = tables
+	}
+}
+
+// WithMatch specifies an optional predicate which must return true for a fault
+// to apply to an operation.
+func WithMatch(fn func(op Op, table string) bool) FaultOption {
+	return func(o *faultOptions) {
+		o.withMatch = fn
+	}
+}
+
+// WithNthCall specifies that a fault applies starting with the nth matching
+// call (counting from 1).  The default is 1.
+func WithNthCall(n int) FaultOption {
+	return func(o *faultOptions) {
+		o.withNthCall = n
+	}
+}
+
+// WithTimes specifies how many times a fault applies before it's exhausted.
+// The default of 0 applies the fault to every matching call.
+func WithTimes(n int) FaultOption {
+	return func(o *faultOptions) {
+		o.withTimes = n
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbwfake
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-dbw"
+)
+
+// condition is a single comparison of a where clause
+type condition struct {
+	column string
+	op     string
+	arg    interface{}
+}
+
+var (
+	andRegexp       = regexp.MustCompile(`(?i)\s+and\s+`)
+	conditionRegexp = regexp.MustCompile(`(?i)^\s*([a-z_][a-z0-9_]*\.)?([a-z_][a-z0-9_]*)\s*(=|<>|!=|<=|>=|<|>|not\s+like|like|not\s+in|in|is\s+not\s+null|is\s+null)\s*(\?|\(\s*\?\s*\))?\s*$`)
+	orderRegexp     = regexp.MustCompile(`(?i)^\s*([a-z_][a-z0-9_]*\.)?([a-z_][a-z0-9_]*)(\s+(asc|desc))?\s*$`)
+	spaceRegexp     = regexp.MustCompile(`\s+`)
+)
+
+// parseWhere parses the subset of where clauses supported by the fake, which
+// is a conjunction ("and") of column comparisons with parameters:
+//
+//	column = ?, column <> ?, column != ?, column < ?, column <= ?, column > ?,
+//	column >= ?, column like ?, column not like ?, column in (?),
+//	column not in (?), column is null, column is not null
+func parseWhere(where string, args []interface{}) ([]condition, error) {
+	const op = "dbwfake.parseWhere"
+	if strings.TrimSpace(where) == "" {
+		if len(args) > 0 {
+			return nil, fmt.Errorf("%s: args without a where clause: %w", op, dbw.ErrInvalidParameter)
+		}
+		return nil, nil
+	}
+	var conditions []condition
+	for _, term := range andRegexp.Split(strings.TrimSpace(where), -1) {
+		m := conditionRegexp.FindStringSubmatch(term)
+		if m == nil {
+			return nil, fmt.Errorf("%s: unsupported where clause %q: %w", op, term, ErrNotSupported)
+		}
+		c := condition{
+			column: strings.ToLower(m[2]),
+			op:     strings.ToLower(spaceRegexp.ReplaceAllString(m[3], " ")),
+		}
+		switch c.op {
+		case "is null", "is not null":
+			if m[4] != "" {
+				return nil, fmt.Errorf("%s: unsupported where clause %q: %w", op, term, ErrNotSupported)
+			}
+		default:
+			if m[4] == "" {
+				return nil, fmt.Errorf("%s: unsupported where clause %q: %w", op, term, ErrNotSupported)
+			}
+			if len(args) == 0 {
+				return nil, fmt.Errorf("%s: missing arg for %q: %w", op, term, dbw.ErrInvalidParameter)
+			}
+			c.arg, args = normalize(args[0]), args[1:]
+		}
+		conditions = append(conditions, c)
+	}
+	if len(args) > 0 {
+		return nil, fmt.Errorf("%s: too many args: %w", op, dbw.ErrInvalidParameter)
+	}
+	return conditions, nil
+}
+
+// matches returns true if the row matches all the conditions
+func matches(r row, conditions []condition) (bool, error) {
+	const op = "dbwfake.matches"
+	for _, c := range conditions {
+		v, ok := r[c.column]
+		if !ok {
+			return false, fmt.Errorf("%s: unknown column %q: %w", op, c.column, dbw.ErrInvalidParameter)
+		}
+		var match bool
+		switch c.op {
+		case "is null":
+			match = v == nil
+		case "is not null":
+			match = v != nil
+		case "in", "not in":
+			argVal := reflect.ValueOf(c.arg)
+			if argVal.Kind() != reflect.Slice {
+				return false, fmt.Errorf("%s: %s arg for %q is not a slice: %w", op, c.op, c.column, dbw.ErrInvalidParameter)
+			}
+			for i := 0; i < argVal.Len(); i++ {
+				if cmp, ok := compare(v, normalize(argVal.Index(i).Interface())); ok && cmp == 0 {
+					match = true
+					break
+				}
+			}
+			if c.op == "not in" {
+				match = v != nil && !match
+			}
+		case "like", "not like":
+			s, ok1 := v.(string)
+			pattern, ok2 := c.arg.(string)
+			if ok1 && ok2 {
+				match = likeRegexp(pattern).MatchString(s)
+				if c.op == "not like" {
+					match = !match
+				}
+			}
+		default:
+			cmp, ok := compare(v, c.arg)
+			if !ok {
+				break
+			}
+			switch c.op {
+			case "=":
+				match = cmp == 0
+			case "<>", "!=":
+				match = cmp != 0
+			case "<":
+				match = cmp < 0
+			case "<=":
+				match = cmp <= 0
+			case ">":
+				match = cmp > 0
+			case ">=":
+				match = cmp >= 0
+			}
+		}
+		if !match {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// likeRegexp converts a sql like pattern to a regexp
+func likeRegexp(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("(?s)^")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// compare two normalized values.  Like sql, null values are not comparable
+// and false is returned when the values are not comparable.
+func compare(a, b interface{}) (int, bool) {
+	if a == nil || b == nil {
+		return 0, false
+	}
+	if af, ok := toFloat(a); ok {
+		bf, ok := toFloat(b)
+		switch {
+		case !ok:
+			return 0, false
+		case af < bf:
+			return -1, true
+		case af > bf:
+			return 1, true
+		default:
+			return 0, true
+		}
+	}
+	switch av := a.(type) {
+	case string:
+		bv, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(av, bv), true
+	case []byte:
+		bv, ok := b.([]byte)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(string(av), string(bv)), true
+	case time.Time:
+		bv, ok := b.(time.Time)
+		if !ok {
+			return 0, false
+		}
+		return av.Compare(bv), true
+	case bool:
+		bv, ok := b.(bool)
+		if !ok || av != bv {
+			return 1, ok
+		}
+		return 0, true
+	default:
+		if reflect.DeepEqual(a, b) {
+			return 0, true
+		}
+		return 0, false
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+// orderBy is a single column of an order by clause
+type orderBy struct {
+	column string
+	desc   bool
+}
+
+// parseOrder parses an order by clause of comma separated columns with an
+// optional asc or desc.
+func parseOrder(order string) ([]orderBy, error) {
+	const op = "dbwfake.parseOrder"
+	if strings.TrimSpace(order) == "" {
+		return nil, nil
+	}
+	var orders []orderBy
+	for _, term := range strings.Split(order, ",") {
+		m := orderRegexp.FindStringSubmatch(term)
+		if m == nil {
+			return nil, fmt.Errorf("%s: unsupported order %q: %w", op, term, ErrNotSupported)
+		}
+		orders = append(orders, orderBy{column: strings.ToLower(m[2]), desc: strings.EqualFold(m[4], "desc")})
+	}
+	return orders, nil
+}
+
+// sortRows sorts the rows by the order, and then by their keys so the
+// results are deterministic.  Null values sort first.
+func sortRows(rows []keyedRow, orders []orderBy) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, o := range orders {
+			a, b := rows[i].row[o.column], rows[j].row[o.column]
+			var cmp int
+			switch {
+			case a == nil && b == nil:
+				cmp = 0
+			case a == nil:
+				cmp = -1
+			case b == nil:
+				cmp = 1
+			default:
+				cmp, _ = compare(a, b)
+			}
+			if o.desc {
+				cmp = -cmp
+			}
+			if cmp != 0 {
+				return cmp < 0
+			}
+		}
+		return rows[i].key < rows[j].key
+	})
+}
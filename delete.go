@@ -7,13 +7,15 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+
+	"gorm.io/gorm"
 )
 
 // Delete a resource in the db with options: WithWhere, WithDebug, WithTable,
-// and WithVersion. WithWhere and WithVersion allows specifying a additional
+// WithVersion and WithTimeout. WithWhere and WithVersion allows specifying a additional
 // constraints on the operation in addition to the PKs. Delete returns the
 // number of rows deleted and any errors.
-func (rw *RW) Delete(ctx context.Context, i interface{}, opt ...Option) (int, error) {
+func (rw *RW) Delete(ctx context.Context, i interface{}, opt ...Option) (_ int, retErr error) {
 	const op = "dbw.Delete"
 	if rw.underlying == nil {
 		return noRowsAffected, fmt.Errorf("%s: missing underlying db: %w", op, ErrInvalidParameter)
@@ -42,6 +44,11 @@ func (rw *RW) Delete(ctx context.Context, i interface{}, opt ...Option) (int, er
 			return noRowsAffected, fmt.Errorf("%s: error before write: %w", op, err)
 		}
 	}
+	rw, ctx, done, err := rw.startTimeout(ctx, opts)
+	if err != nil {
+		return noRowsAffected, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { retErr = done(retErr) }()
 	db := rw.underlying.wrapped.WithContext(ctx)
 	if opts.WithVersion != nil || opts.WithWhereClause != "" {
 		where, args, err := rw.whereClausesFromOpts(ctx, i, opts)
@@ -61,6 +68,9 @@ func (rw *RW) Delete(ctx context.Context, i interface{}, opt ...Option) (int, er
 		return noRowsAffected, fmt.Errorf("%s: %w", op, db.Error)
 	}
 	rowsDeleted := int(db.RowsAffected)
+	if rowsDeleted > 0 {
+		rw.invalidateLookupCache(ctx, opts.WithTable, i)
+	}
 	if rowsDeleted > 0 && opts.WithAfterWrite != nil {
 		if err := opts.WithAfterWrite(i, rowsDeleted); err != nil {
 			return rowsDeleted, fmt.Errorf("%s: error after write: %w", op, err)
@@ -70,8 +80,12 @@ func (rw *RW) Delete(ctx context.Context, i interface{}, opt ...Option) (int, er
 }
 
 // DeleteItems will delete multiple items of the same type. Options supported:
-// WithWhereClause, WithDebug, WithTable
-func (rw *RW) DeleteItems(ctx context.Context, deleteItems interface{}, opt ...Option) (int, error) {
+// WithWhereClause, WithDebug, WithTable, WithItemResults and WithTimeout.
+//
+// WithItemResults deletes the items on a best effort basis in batches of
+// WithBatchSize, so items which fail don't fail the others, and returns the
+// result of each item, the same as CreateItems(...)
+func (rw *RW) DeleteItems(ctx context.Context, deleteItems interface{}, opt ...Option) (_ int, retErr error) {
 	const op = "dbw.DeleteItems"
 	switch {
 	case rw.underlying == nil:
@@ -98,6 +112,13 @@ func (rw *RW) DeleteItems(ctx context.Context, deleteItems interface{}, opt ...O
 	case opts.WithVersion != nil:
 		return noRowsAffected, fmt.Errorf("%s: with version is not a supported option: %w", op, ErrInvalidParameter)
 	}
+	if opts.WithItemResults != nil {
+		rowsDeleted, err := rw.writeItemsWithResults(ctx, valDeleteItems, opt, writeDeleteItems)
+		if err != nil {
+			return int(rowsDeleted), fmt.Errorf("%s: %w", op, err)
+		}
+		return int(rowsDeleted), nil
+	}
 
 	// we need to dig out the stmt so in just a sec we can make sure the PKs are
 	// set for all the items, so we'll just use the first item to do so.
@@ -142,6 +163,11 @@ func (rw *RW) DeleteItems(ctx context.Context, deleteItems interface{}, opt ...O
 		}
 	}
 
+	rw, ctx, done, err := rw.startTimeout(ctx, opts)
+	if err != nil {
+		return noRowsAffected, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { retErr = done(retErr) }()
 	db := rw.underlying.wrapped.WithContext(ctx)
 	if opts.WithDebug {
 		db = db.Debug()
@@ -170,6 +196,9 @@ func (rw *RW) DeleteItems(ctx context.Context, deleteItems interface{}, opt ...O
 		return noRowsAffected, fmt.Errorf("%s: %w", op, db.Error)
 	}
 	rowsDeleted := int(db.RowsAffected)
+	if rowsDeleted > 0 {
+		rw.invalidateLookupCache(ctx, opts.WithTable, sliceItems(valDeleteItems)...)
+	}
 	if rowsDeleted > 0 && opts.WithAfterWrite != nil {
 		if err := opts.WithAfterWrite(deleteItems, int(rowsDeleted)); err != nil {
 			return rowsDeleted, fmt.Errorf("%s: error after write: %w", op, err)
@@ -178,6 +207,91 @@ func (rw *RW) DeleteItems(ctx context.Context, deleteItems interface{}, opt ...O
 	return rowsDeleted, nil
 }
 
+// writeDeleteItems is the writeItemFn of DeleteItems(...)
+func writeDeleteItems(ctx context.Context, rw *RW, items interface{}, opt ...Option) (int64, error) {
+	rowsDeleted, err := rw.DeleteItems(ctx, items, opt...)
+	return int64(rowsDeleted), err
+}
+
 type tableNamer interface {
 	TableName() string
 }
+
+// DeleteWhere deletes every row of the model's table which matches the where
+// clause, and returns the number of rows deleted.  The model is only used for
+// its schema and table, so its field values are ignored.
+//
+// An empty where clause is refused, unless the WithAllRows option is used.
+// When the model is cached by a LookupCache, the primary keys of the matching
+// rows are read before the delete, so their cached resources can be
+// invalidated.  Rows which start matching the where clause between that read
+// and the delete, because of a concurrent write, aren't invalidated, so their
+// cached resources are stale until their TTL expires.  Supported options: WithAllRows, WithBeforeWrite,
+// WithAfterWrite, WithDebug, WithTable and WithTimeout.  WithBeforeWrite and
+// WithAfterWrite are called with the model.
+func (rw *RW) DeleteWhere(ctx context.Context, model interface{}, where string, args []interface{}, opt ...Option) (_ int, retErr error) {
+	const op = "dbw.DeleteWhere"
+	switch {
+	case rw.underlying == nil:
+		return noRowsAffected, fmt.Errorf("%s: missing underlying db: %w", op, ErrInvalidParameter)
+	case isNil(model):
+		return noRowsAffected, fmt.Errorf("%s: missing model: %w", op, ErrInvalidParameter)
+	}
+	if err := raiseErrorOnHooks(model); err != nil {
+		return noRowsAffected, fmt.Errorf("%s: %w", op, err)
+	}
+	opts := GetOpts(opt...)
+	if err := validateWriteWhere(where, opts); err != nil {
+		return noRowsAffected, fmt.Errorf("%s: %w", op, err)
+	}
+	mDb := rw.underlying.wrapped.Model(model)
+	err := mDb.Statement.Parse(model)
+	if err != nil || mDb.Statement.Schema == nil {
+		return noRowsAffected, fmt.Errorf("%s: internal error: unable to parse stmt: %w", op, err)
+	}
+
+	if opts.WithBeforeWrite != nil {
+		if err := opts.WithBeforeWrite(model); err != nil {
+			return noRowsAffected, fmt.Errorf("%s: error before write: %w", op, err)
+		}
+	}
+	rw, ctx, done, err := rw.startTimeout(ctx, opts)
+	if err != nil {
+		return noRowsAffected, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { retErr = done(retErr) }()
+	table := tableOf(model, opts)
+	cached, err := rw.cachedResourcesWhere(ctx, model, table, where, args)
+	if err != nil {
+		return noRowsAffected, fmt.Errorf("%s: %w", op, err)
+	}
+	db := rw.underlying.wrapped.WithContext(ctx)
+	if opts.WithAllRows {
+		db = db.Session(&gorm.Session{AllowGlobalUpdate: true})
+	}
+	if opts.WithDebug {
+		db = db.Debug()
+	}
+	if table != "" {
+		db = db.Table(table)
+	}
+	if where != "" {
+		db = db.Where(where, args...)
+	}
+	// a new model is deleted, so the model's primary key isn't added to the
+	// where clause
+	db = db.Delete(reflect.New(reflect.Indirect(reflect.ValueOf(model)).Type()).Interface())
+	if db.Error != nil {
+		return noRowsAffected, fmt.Errorf("%s: %w", op, db.Error)
+	}
+	rowsDeleted := int(db.RowsAffected)
+	if rowsDeleted > 0 {
+		rw.invalidateLookupCache(ctx, opts.WithTable, cached...)
+	}
+	if rowsDeleted > 0 && opts.WithAfterWrite != nil {
+		if err := opts.WithAfterWrite(model, rowsDeleted); err != nil {
+			return rowsDeleted, fmt.Errorf("%s: error after write: %w", op, err)
+		}
+	}
+	return rowsDeleted, nil
+}
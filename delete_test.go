@@ -589,3 +589,66 @@ func TestDb_DeleteItems(t *testing.T) {
 		}
 	})
 }
+
+func TestDb_DeleteWhere(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	conn, _ := dbw.TestSetup(t)
+	rw := dbw.New(conn)
+
+	t.Run("where", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		testUser(t, rw, "delete-where-1", "delete-where@example.com", "")
+		testUser(t, rw, "delete-where-2", "delete-where@example.com", "")
+		other := testUser(t, rw, "delete-where-3", "other@example.com", "")
+
+		var afterRows int
+		rowsDeleted, err := rw.DeleteWhere(testCtx, &dbtest.TestUser{StoreTestUser: &dbtest.StoreTestUser{}},
+			"email = ?", []interface{}{"delete-where@example.com"},
+			dbw.WithAfterWrite(func(_ interface{}, rowsAffected int) error {
+				afterRows = rowsAffected
+				return nil
+			}))
+		require.NoError(err)
+		assert.Equal(2, rowsDeleted)
+		assert.Equal(2, afterRows)
+
+		var found []*dbtest.TestUser
+		require.NoError(rw.SearchWhere(testCtx, &found, "email = ?", []interface{}{"delete-where@example.com"}))
+		assert.Empty(found)
+		u := dbtest.AllocTestUser()
+		u.PublicId = other.PublicId
+		require.NoError(rw.LookupByPublicId(testCtx, &u))
+	})
+	t.Run("ignores-model-primary-key", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		u := testUser(t, rw, "delete-where-pk", "", "")
+		model := testUser(t, nil, "", "", "")
+		rowsDeleted, err := rw.DeleteWhere(testCtx, model, "name = ?", []interface{}{u.Name})
+		require.NoError(err)
+		assert.Equal(1, rowsDeleted)
+	})
+	t.Run("all-rows", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		conn, _ := dbw.TestSetup(t)
+		rw := dbw.New(conn)
+		testUser(t, rw, "all-rows-1", "", "")
+		testUser(t, rw, "all-rows-2", "", "")
+
+		_, err := rw.DeleteWhere(testCtx, &dbtest.TestUser{StoreTestUser: &dbtest.StoreTestUser{}}, "", nil)
+		assert.ErrorIs(err, dbw.ErrInvalidParameter)
+
+		rowsDeleted, err := rw.DeleteWhere(testCtx, &dbtest.TestUser{StoreTestUser: &dbtest.StoreTestUser{}}, "", nil, dbw.WithAllRows(true))
+		require.NoError(err)
+		assert.Equal(2, rowsDeleted)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		assert := assert.New(t)
+		_, err := (&dbw.RW{}).DeleteWhere(testCtx, &dbtest.TestUser{StoreTestUser: &dbtest.StoreTestUser{}}, "1=1", nil)
+		assert.ErrorIs(err, dbw.ErrInvalidParameter)
+		_, err = rw.DeleteWhere(testCtx, nil, "1=1", nil)
+		assert.ErrorIs(err, dbw.ErrInvalidParameter)
+		_, err = rw.DeleteWhere(testCtx, &dbtest.TestUser{StoreTestUser: &dbtest.StoreTestUser{}}, "1=1", nil, dbw.WithVersion(new(uint32)))
+		assert.ErrorIs(err, dbw.ErrInvalidParameter)
+	})
+}
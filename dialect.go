@@ -0,0 +1,257 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Dialect defines the behavior which differs between databases.  dbw uses
+// the dialect of a DB for quoting, upserts, locking clauses, error
+// classification, RETURNING support and the test schema, so the rest of dbw
+// doesn't need to know which database it's using.
+//
+// Dialects are registered by name with RegisterDialect(...), and a DB uses
+// the dialect registered with the name of its gorm.Dialector.  The Postgres,
+// Sqlite, MySQL and SqlServer dialects are registered by default.
+type Dialect interface {
+	// Name returns the dialect's name, which must match the Name() of the
+	// gorm.Dialector it's used with.
+	Name() string
+
+	// Quote returns the identifier quoted for the dialect.  Identifiers
+	// qualified with a table name (table.column) have each part quoted.
+	Quote(identifier string) string
+
+	// Excluded returns the value which references a column of the row
+	// proposed for insertion by an upsert.  See SetColumns(...)
+	Excluded(column string) interface{}
+
+	// OnConflict validates an upsert's on conflict clause, returning
+	// ErrInvalidParameter when the dialect doesn't support it.  The
+	// primaryKeys are the column names of the resource's primary key.
+	OnConflict(c *clause.OnConflict, primaryKeys []string) error
+
+	// Locking returns the locking clause for a valid row lock.  A nil clause
+	// is returned when the dialect doesn't need row locks, and
+	// ErrInvalidParameter is returned when it doesn't support the lock.
+	Locking(lock RowLock) (clause.Expression, error)
+
+	// SupportsReturning returns true when the dialect supports "returning"
+	// clauses for inserts, updates and deletes.
+	SupportsReturning() bool
+
+	// IsAuthError returns true when the database rejected a connection's
+	// credentials.
+	IsAuthError(err error) bool
+
+	// IsStaleStmtError returns true when a prepared statement can no longer
+	// be used because the schema changed or its connection is gone.
+	IsStaleStmtError(err error) bool
+
+	// IsStatementTimeout returns true when the database canceled a statement
+	// because it exceeded a statement timeout.
+	IsStatementTimeout(err error) bool
+
+	// IsBusyError returns true when a transaction failed because the
+	// database was busy, so it can be retried.
+	IsBusyError(err error) bool
+
+	// TestSchema returns the statements which create and drop the dbw test
+	// tables.  See TestCreateTables(...)
+	TestSchema() (create, drop []string)
+}
+
+var (
+	dialectsMu sync.RWMutex
+	dialects   = map[string]Dialect{}
+)
+
+func init() {
+	for _, d := range []Dialect{postgresDialect{}, sqliteDialect{}, mysqlDialect{}, sqlServerDialect{}} {
+		dialects[d.Name()] = d
+	}
+}
+
+// RegisterDialect registers a dialect, which is used by every DB with a
+// gorm.Dialector of the same name.  Registering a dialect with the name of
+// an existing dialect replaces it.
+func RegisterDialect(d Dialect) error {
+	const op = "dbw.RegisterDialect"
+	switch {
+	case isNil(d):
+		return fmt.Errorf("%s: missing dialect: %w", op, ErrInvalidParameter)
+	case d.Name() == "":
+		return fmt.Errorf("%s: missing dialect name: %w", op, ErrInvalidParameter)
+	}
+	dialectsMu.Lock()
+	defer dialectsMu.Unlock()
+	dialects[d.Name()] = d
+	return nil
+}
+
+// LookupDialect returns the dialect registered with the name
+func LookupDialect(name string) (Dialect, bool) {
+	dialectsMu.RLock()
+	defer dialectsMu.RUnlock()
+	d, ok := dialects[name]
+	return d, ok
+}
+
+// dialectFor returns the dialect registered for the gorm.Dialector, or a
+// default dialect when one isn't registered.
+func dialectFor(dialector gorm.Dialector) Dialect {
+	if d, ok := LookupDialect(dialector.Name()); ok {
+		return d
+	}
+	return defaultDialect{dialector: dialector}
+}
+
+// dialect returns the DB's dialect
+func (db *DB) dialect() Dialect {
+	return dialectFor(db.wrapped.Dialector)
+}
+
+// defaultDialect is used by a DB with an unregistered gorm.Dialector.  It
+// quotes identifiers using the gorm.Dialector, and otherwise leaves the SQL
+// to gorm.
+type defaultDialect struct {
+	dialector gorm.Dialector
+}
+
+var _ Dialect = defaultDialect{}
+
+// Name returns the gorm.Dialector's name
+func (d defaultDialect) Name() string { return d.dialector.Name() }
+
+// Quote quotes the identifier using the gorm.Dialector
+func (d defaultDialect) Quote(identifier string) string {
+	var b strings.Builder
+	d.dialector.QuoteTo(&b, identifier)
+	return b.String()
+}
+
+// Excluded returns excluded.column
+func (defaultDialect) Excluded(column string) interface{} {
+	return clause.Column{Table: excludedTable, Name: column}
+}
+
+// OnConflict supports every on conflict clause
+func (defaultDialect) OnConflict(*clause.OnConflict, []string) error { return nil }
+
+// Locking returns a "for" locking clause
+func (defaultDialect) Locking(lock RowLock) (clause.Expression, error) {
+	return forLocking(lock), nil
+}
+
+// SupportsReturning returns false
+func (defaultDialect) SupportsReturning() bool { return false }
+
+// IsAuthError returns false
+func (defaultDialect) IsAuthError(error) bool { return false }
+
+// IsStaleStmtError returns false
+func (defaultDialect) IsStaleStmtError(error) bool { return false }
+
+// IsStatementTimeout returns false
+func (defaultDialect) IsStatementTimeout(error) bool { return false }
+
+// IsBusyError returns false
+func (defaultDialect) IsBusyError(error) bool { return false }
+
+// TestSchema returns no statements
+func (defaultDialect) TestSchema() (create, drop []string) { return nil, nil }
+
+// SavepointReleaser is an optional interface for dialects which can release a
+// savepoint.  Savepoints are released when they succeed, so a transaction
+// doesn't accumulate them.
+type SavepointReleaser interface {
+	// ReleaseSavepoint returns the statement which releases the named
+	// savepoint.
+	ReleaseSavepoint(name string) string
+}
+
+// OpenerDialect is an optional interface for dialects which configure a DB
+// when it's opened.
+type OpenerDialect interface {
+	// Open configures the opened gorm.DB with the options.  The readers are
+	// a separate pool for the DB's reads, which is nil unless it's opened
+	// for the dialect (see WithSqliteSingleWriter), and the dialect is
+	// responsible for them once Open succeeds.
+	Open(db *gorm.DB, readers *sql.DB, opts Options) error
+}
+
+// StatementTimeoutDialect is an optional interface for dialects which limit
+// the duration of a session's statements with a statement timeout, which is
+// set to an operation's timeout.  See WithTimeout(...)
+type StatementTimeoutDialect interface {
+	// SetLocalStatementTimeout returns a query which sets the transaction's
+	// statement timeout and returns the previous and current values.
+	SetLocalStatementTimeout(d time.Duration) string
+
+	// RestoreLocalStatementTimeout returns a query which restores the
+	// transaction's statement timeout to the previous value in its arg.
+	RestoreLocalStatementTimeout() string
+
+	// SetStatementTimeout returns a statement which sets the session's
+	// statement timeout.
+	SetStatementTimeout(d time.Duration) string
+
+	// ResetStatementTimeout returns a statement which resets the session's
+	// statement timeout to its default.
+	ResetStatementTimeout() string
+}
+
+// LockDialect is an optional interface for dialects which support
+// distributed locks.  See DB.Lock(...) and RW.LockTx(...)
+type LockDialect interface {
+	// NewSessionLock returns a lock for the key, which isn't acquired until
+	// its TryLock or Lock is called.  The lock is held by a session of the
+	// db, and it may expire unless it's checked within the expiration.
+	NewSessionLock(ctx context.Context, db *DB, key string, expiration time.Duration) (SessionLock, error)
+
+	// LockTx acquires a lock for the key, which is released when the rw's
+	// transaction is committed or rolled back.
+	LockTx(ctx context.Context, rw *RW, key string) error
+}
+
+// CredentialsDialect is an optional interface for dialects which support
+// rotating credentials.  See OpenWithCredentials(...)
+type CredentialsDialect interface {
+	// CredentialsDialector returns a gorm.Dialector which opens connections
+	// with the provider's credentials.  The readers are a separate pool for
+	// reads, which is nil unless the dialect uses one (see OpenerDialect).
+	CredentialsDialector(connectionUrl string, provider CredentialsProvider, opts Options) (_ gorm.Dialector, readers *sql.DB, _ error)
+}
+
+// releaseSavepoint returns "release savepoint name"
+func releaseSavepoint(name string) string {
+	return "release savepoint " + name
+}
+
+// quoteIdentifier quotes each part of a (possibly qualified) identifier with
+// the quote char, doubling any quote chars within the identifier.
+func quoteIdentifier(identifier string, quote string) string {
+	parts := strings.Split(identifier, ".")
+	for i, p := range parts {
+		parts[i] = quote + strings.ReplaceAll(p, quote, quote+quote) + quote
+	}
+	return strings.Join(parts, ".")
+}
+
+// forLocking returns a "for" locking clause for the row lock
+func forLocking(lock RowLock) clause.Expression {
+	return clause.Locking{
+		Strength: string(lock.Strength),
+		Options:  string(lock.Wait),
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// testDialector is a sqlite gorm.Dialector with a different name
+type testDialector struct {
+	gorm.Dialector
+	name string
+}
+
+func (d testDialector) Name() string { return d.name }
+
+// testQuoteDialect is a sqlite dialect which quotes with double quotes and
+// doesn't support upserts
+type testQuoteDialect struct {
+	sqliteDialect
+	name string
+}
+
+func (d testQuoteDialect) Name() string { return d.name }
+
+func (testQuoteDialect) Quote(identifier string) string {
+	return quoteIdentifier(identifier, `"`)
+}
+
+func (testQuoteDialect) OnConflict(*clause.OnConflict, []string) error {
+	return ErrInvalidParameter
+}
+
+func TestRegisterDialect(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	t.Run("registered", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		require.NoError(RegisterDialect(testQuoteDialect{name: "test-registered"}))
+		d, ok := LookupDialect("test-registered")
+		require.True(ok)
+		assert.Equal("test-registered", d.Name())
+
+		db, err := OpenWith(testDialector{Dialector: sqlite.Open("file::memory:"), name: "test-registered"})
+		require.NoError(err)
+		defer db.Close(testCtx)
+		assert.Equal(`"public_id"`, db.dialect().Quote("public_id"))
+
+		rw := New(db)
+		_, err = rw.Exec(testCtx, testQueryCreateTablesSqlite, nil)
+		require.NoError(err)
+		err = rw.Create(testCtx, &testUser{PublicId: "u_1234567890"}, WithOnConflict(&OnConflict{Target: Columns{"public_id"}, Action: DoNothing(true)}))
+		assert.ErrorIs(err, ErrInvalidParameter)
+	})
+	t.Run("unregistered", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		_, ok := LookupDialect("test-unregistered")
+		assert.False(ok)
+		db, err := OpenWith(testDialector{Dialector: sqlite.Open("file::memory:"), name: "test-unregistered"})
+		require.NoError(err)
+		defer db.Close(testCtx)
+		d := db.dialect()
+		assert.IsType(defaultDialect{}, d)
+		// the gorm.Dialector quotes identifiers
+		assert.Equal("`db_test_user`.`public_id`", d.Quote("db_test_user.public_id"))
+		assert.False(d.SupportsReturning())
+		create, drop := d.TestSchema()
+		assert.Empty(create)
+		assert.Empty(drop)
+	})
+	t.Run("builtin", func(t *testing.T) {
+		assert := assert.New(t)
+		for _, typ := range []DbType{Postgres, Sqlite, MySQL, SqlServer} {
+			d, ok := LookupDialect(typ.String())
+			if assert.True(ok, typ.String()) {
+				assert.Equal(typ.String(), d.Name())
+				create, drop := d.TestSchema()
+				assert.NotEmpty(create)
+				assert.NotEmpty(drop)
+			}
+		}
+	})
+	t.Run("lock-dialect", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		// the registered dialect embeds sqliteDialect, so it supports locks
+		require.NoError(RegisterDialect(testQuoteDialect{name: "test-locks"}))
+		db, err := OpenWith(testDialector{Dialector: sqlite.Open(filepath.Join(t.TempDir(), "locks.db")), name: "test-locks"})
+		require.NoError(err)
+		defer db.Close(testCtx)
+		l, acquired, err := db.TryLock(testCtx, "test-key")
+		require.NoError(err)
+		assert.True(acquired)
+		require.NoError(l.Close(testCtx))
+
+		// the default dialect doesn't support locks
+		unsupported, err := OpenWith(testDialector{Dialector: sqlite.Open("file::memory:"), name: "test-no-locks"})
+		require.NoError(err)
+		defer unsupported.Close(testCtx)
+		_, _, err = unsupported.TryLock(testCtx, "test-key")
+		assert.ErrorIs(err, ErrInvalidParameter)
+		assert.ErrorContains(err, "locks are not supported for test-no-locks")
+	})
+	t.Run("invalid", func(t *testing.T) {
+		assert := assert.New(t)
+		assert.ErrorIs(RegisterDialect(nil), ErrInvalidParameter)
+		assert.ErrorIs(RegisterDialect(testQuoteDialect{}), ErrInvalidParameter)
+	})
+}
+
+func Test_quoteIdentifier(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	assert.Equal(`"name"`, quoteIdentifier("name", `"`))
+	assert.Equal(`"db_test_user"."name"`, quoteIdentifier("db_test_user.name", `"`))
+	assert.Equal("`na``me`", quoteIdentifier("na`me", "`"))
+}
+
+func TestDialect_Excluded(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	assert.Equal(clause.Column{Table: excludedTable, Name: "name"}, postgresDialect{}.Excluded("name"))
+	assert.Equal(clause.Column{Table: excludedTable, Name: "name"}, sqlServerDialect{}.Excluded("name"))
+	assert.Equal(clause.Expr{SQL: "VALUES(?)", Vars: []interface{}{clause.Column{Name: "name"}}}, mysqlDialect{}.Excluded("name"))
+
+	c := Column{Table: excludedTable, Name: "name"}
+	assert.Equal(clause.Assignment{Column: clause.Column{Name: "email"}, Value: mysqlDialect{}.Excluded("name")}, c.toAssignment(mysqlDialect{}, "email"))
+	c = Column{Table: "db_test_user", Name: "name"}
+	assert.Equal(clause.Assignment{Column: clause.Column{Name: "email"}, Value: clause.Column{Table: "db_test_user", Name: "name"}}, c.toAssignment(mysqlDialect{}, "email"))
+}
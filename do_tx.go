@@ -6,13 +6,26 @@ package dbw
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
+
+	"gorm.io/gorm"
 )
 
+// txSavepoints is used to name the savepoints of nested transactions
+var txSavepoints atomic.Uint64
+
 // DoTx will wrap the Handler func passed within a transaction with retries
 // you should ensure that any objects written to the db in your TxHandler are retryable, which
 // means that the object may be sent to the db several times (retried), so
-// things like the primary key may need to be reset before retry.
+// things like the primary key may need to be reset before retry.  Sqlite's
+// SQLITE_BUSY errors are retryable, regardless of the retryErrorsMatchingFn.
+//
+// A rw which is already in a transaction returns gorm.ErrInvalidTransaction,
+// except for the RW of a TestSnapshot(...), which wraps the handler in a
+// savepoint that's released when the handler succeeds and rolled back when it
+// returns an error.  A savepoint isn't retried, since its error may have
+// aborted the outer transaction, which owns the retries.
 func (rw *RW) DoTx(ctx context.Context, retryErrorsMatchingFn func(error) bool, retries uint, backOff Backoff, handler TxHandler) (RetryInfo, error) {
 	const op = "dbw.DoTx"
 	if rw.underlying == nil {
@@ -27,7 +40,17 @@ func (rw *RW) DoTx(ctx context.Context, retryErrorsMatchingFn func(error) bool,
 	if retryErrorsMatchingFn == nil {
 		return RetryInfo{}, fmt.Errorf("%s: missing retry errors matching function: %w", op, ErrInvalidParameter)
 	}
+	if rw.IsTx() {
+		if !rw.underlying.txSavepoints {
+			return RetryInfo{}, fmt.Errorf("%s: already in a transaction: %w", op, gorm.ErrInvalidTransaction)
+		}
+		if err := rw.savepoint(ctx, func(w *RW) error { return handler(w, w) }); err != nil {
+			return RetryInfo{}, fmt.Errorf("%s: %w", op, err)
+		}
+		return RetryInfo{}, nil
+	}
 	info := RetryInfo{}
+	busy := rw.underlying.dialect().IsBusyError
 	for attempts := uint(1); ; attempts++ {
 		if attempts > retries+1 {
 			return info, fmt.Errorf("%s: too many retries: %d of %d: %w", op, attempts-1, retries+1, ErrMaxRetries)
@@ -37,12 +60,28 @@ func (rw *RW) DoTx(ctx context.Context, retryErrorsMatchingFn func(error) bool,
 		newTx := rw.underlying.wrapped.WithContext(ctx)
 		newTx = newTx.Begin()
 
-		newRW := &RW{underlying: &DB{newTx}}
-		if err := handler(newRW, newRW); err != nil {
+		newRW := &RW{underlying: rw.underlying.withTx(newTx)}
+		err := handler(newRW, newRW)
+		switch {
+		case err != nil:
 			if err := newTx.Rollback().Error; err != nil {
 				return info, fmt.Errorf("%s: %w", op, err)
 			}
-			if retry := retryErrorsMatchingFn(err); retry {
+		default:
+			// a commit which fails because the database is busy has been
+			// rolled back by the driver (like sqlite's SQLITE_BUSY), so it's
+			// retried below.
+			if err = newTx.Commit().Error; err != nil && !busy(err) {
+				if err := newTx.Rollback().Error; err != nil {
+					return info, fmt.Errorf("%s: %w", op, err)
+				}
+				return info, fmt.Errorf("%s: %w", op, err)
+			}
+		}
+		if err != nil {
+			// busy errors are always retryable, since the tx conflicted
+			// with another connection's lock.
+			if retry := retryErrorsMatchingFn(err) || busy(err); retry {
 				d := backOff.Duration(attempts)
 				info.Retries++
 				info.Backoff = info.Backoff + d
@@ -55,13 +94,32 @@ func (rw *RW) DoTx(ctx context.Context, retryErrorsMatchingFn func(error) bool,
 			}
 			return info, fmt.Errorf("%s: %w", op, err)
 		}
+		newRW.commitLookupCache(ctx)
+		return info, nil // it all worked!!!
+	}
+}
 
-		if err := newTx.Commit().Error; err != nil {
-			if err := newTx.Rollback().Error; err != nil {
-				return info, fmt.Errorf("%s: %w", op, err)
-			}
-			return info, fmt.Errorf("%s: %w", op, err)
+// savepoint runs fn with a rw bound to a savepoint of the rw's transaction.
+// The savepoint is rolled back when fn returns an error, which is returned,
+// and it's released otherwise (when the dialect supports releasing
+// savepoints, see SavepointReleaser).
+func (rw *RW) savepoint(ctx context.Context, fn func(*RW) error) error {
+	const op = "dbw.savepoint"
+	name := fmt.Sprintf("dbw_tx_%d", txSavepoints.Add(1))
+	tx := rw.underlying.wrapped.WithContext(ctx)
+	if err := tx.SavePoint(name).Error; err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := fn(&RW{underlying: rw.underlying.withWrapped(tx)}); err != nil {
+		if rollbackErr := tx.RollbackTo(name).Error; rollbackErr != nil {
+			return fmt.Errorf("%s: %w", op, rollbackErr)
+		}
+		return err
+	}
+	if r, ok := rw.underlying.dialect().(SavepointReleaser); ok {
+		if err := tx.Exec(r.ReleaseSavepoint(name)).Error; err != nil {
+			return fmt.Errorf("%s: %w", op, err)
 		}
-		return info, nil // it all worked!!!
 	}
+	return nil
 }
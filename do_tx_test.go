@@ -15,6 +15,7 @@ import (
 	"github.com/hashicorp/go-dbw/internal/dbtest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
 )
 
 func TestDb_DoTx(t *testing.T) {
@@ -166,6 +167,17 @@ func TestDb_DoTx(t *testing.T) {
 		assert.Equal(dbw.RetryInfo{}, got)
 		assert.False(errors.Is(err, retryErr))
 	})
+	t.Run("already-in-tx", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		attempts := 0
+		_, err := dbw.New(conn).DoTx(testCtx, retryOnFn, 0, dbw.ConstBackoff{}, func(_ dbw.Reader, w dbw.Writer) error {
+			_, err := w.DoTx(testCtx, retryOnFn, 0, dbw.ConstBackoff{}, func(dbw.Reader, dbw.Writer) error { attempts += 1; return nil })
+			return err
+		})
+		require.Error(err)
+		assert.ErrorIs(err, gorm.ErrInvalidTransaction)
+		assert.Equal(0, attempts)
+	})
 	t.Run("too-many-retries", func(t *testing.T) {
 		assert, require := assert.New(t), require.New(t)
 		w := dbw.New(conn)
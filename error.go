@@ -23,4 +23,13 @@ var (
 
 	// ErrInvalidFieldMask is an invalid field mask error
 	ErrInvalidFieldMask = errors.New("invalid field mask")
+
+	// ErrStaleVersion is an optimistic locking error, which is returned when
+	// a write's WithVersion option doesn't match the resource's current
+	// version.
+	ErrStaleVersion = errors.New("stale version")
+
+	// ErrTimeout is a timeout error, which is returned when an operation
+	// exceeds its WithTimeout option or the DB's default timeout.
+	ErrTimeout = errors.New("timeout")
 )
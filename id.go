@@ -13,7 +13,9 @@ import (
 )
 
 // NewId creates a new random base62 ID with the provided prefix with an
-// underscore delimiter
+// underscore delimiter.  Supported options: WithPrngValues and
+// WithIdGenerator. If WithIdGenerator is used, then the ID is generated by the
+// IdGenerator and WithPrngValues is ignored.
 func NewId(prefix string, opt ...Option) (string, error) {
 	const op = "dbw.NewId"
 	if prefix == "" {
@@ -22,6 +24,13 @@ func NewId(prefix string, opt ...Option) (string, error) {
 	var publicId string
 	var err error
 	opts := GetOpts(opt...)
+	if opts.WithIdGenerator != nil {
+		id, err := opts.WithIdGenerator.NewId(prefix)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+		return id, nil
+	}
 	if len(opts.WithPrngValues) > 0 {
 		sum := blake2b.Sum256([]byte(strings.Join(opts.WithPrngValues, "|")))
 		reader := bytes.NewReader(sum[0:])
@@ -0,0 +1,360 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IdGenerator defines an interface for generating and parsing prefixed IDs.
+// IDs are formatted as prefix_suffix where the suffix is defined by the
+// generator.
+type IdGenerator interface {
+	// NewId generates a new ID with the prefix
+	NewId(prefix string) (string, error)
+
+	// ParseId parses and validates an ID generated by the generator
+	ParseId(id string) (ParsedId, error)
+}
+
+// ParsedId is an ID which has been parsed into its parts by ParseId(...)
+type ParsedId struct {
+	// Prefix of the ID
+	Prefix string
+
+	// Timestamp of when the ID was generated.  It's the zero time for IDs
+	// which are not time-sortable.
+	Timestamp time.Time
+
+	// Random part of the ID.  It's empty for IDs which don't have a random
+	// part (Snowflake IDs).
+	Random string
+
+	// Node which generated the ID.  It's only set for Snowflake IDs.
+	Node int64
+
+	// Sequence of the ID within its timestamp and node.  It's only set for
+	// Snowflake IDs.
+	Sequence int64
+}
+
+// ParseId parses and validates an ID.  Supported options: WithIdGenerator
+// which specifies the generator of the ID.  The default generator is
+// RandomIdGenerator.
+func ParseId(id string, opt ...Option) (ParsedId, error) {
+	const op = "dbw.ParseId"
+	if id == "" {
+		return ParsedId{}, fmt.Errorf("%s: missing id: %w", op, ErrInvalidParameter)
+	}
+	opts := GetOpts(opt...)
+	g := opts.WithIdGenerator
+	if g == nil {
+		g = RandomIdGenerator{}
+	}
+	parsed, err := g.ParseId(id)
+	if err != nil {
+		return ParsedId{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return parsed, nil
+}
+
+// splitId splits an ID into its prefix and suffix
+func splitId(id string) (string, string, error) {
+	const op = "dbw.splitId"
+	idx := strings.LastIndex(id, "_")
+	switch {
+	case idx <= 0:
+		return "", "", fmt.Errorf("%s: missing prefix for %q: %w", op, id, ErrInvalidParameter)
+	case idx == len(id)-1:
+		return "", "", fmt.Errorf("%s: missing suffix for %q: %w", op, id, ErrInvalidParameter)
+	}
+	return id[:idx], id[idx+1:], nil
+}
+
+const base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// RandomIdGenerator generates IDs with a 10 character random base62 suffix,
+// which is the same as NewId(...)
+type RandomIdGenerator struct{}
+
+// NewId generates a new random ID with the prefix
+func (RandomIdGenerator) NewId(prefix string) (string, error) {
+	return NewId(prefix)
+}
+
+// ParseId parses and validates a random ID
+func (RandomIdGenerator) ParseId(id string) (ParsedId, error) {
+	const op = "dbw.(RandomIdGenerator).ParseId"
+	prefix, suffix, err := splitId(id)
+	if err != nil {
+		return ParsedId{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(suffix) != 10 || strings.Trim(suffix, base62Alphabet) != "" {
+		return ParsedId{}, fmt.Errorf("%s: %q is not a random id: %w", op, id, ErrInvalidParameter)
+	}
+	return ParsedId{Prefix: prefix, Random: suffix}, nil
+}
+
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULIDGenerator generates IDs with a ULID suffix, which is a 48 bit
+// millisecond timestamp and 80 random bits encoded as 26 characters of
+// Crockford's base32.  ULIDs are sortable by their timestamp.
+// See: https://github.com/ulid/spec
+type ULIDGenerator struct{}
+
+// NewId generates a new ULID with the prefix
+func (ULIDGenerator) NewId(prefix string) (string, error) {
+	const op = "dbw.(ULIDGenerator).NewId"
+	if prefix == "" {
+		return "", fmt.Errorf("%s: missing prefix: %w", op, ErrInvalidParameter)
+	}
+	var b [16]byte
+	if _, err := rand.Read(b[6:]); err != nil {
+		return "", fmt.Errorf("%s: unable to generate id: %w", op, ErrInternal)
+	}
+	putMillis(b[:6], time.Now())
+
+	// encode the 128 bits as 26 base32 characters, where the first character
+	// only has 3 bits.
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	var s [26]byte
+	for i := 25; i >= 0; i-- {
+		s[i] = crockfordAlphabet[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return fmt.Sprintf("%s_%s", prefix, s[:]), nil
+}
+
+// ParseId parses and validates a ULID
+func (ULIDGenerator) ParseId(id string) (ParsedId, error) {
+	const op = "dbw.(ULIDGenerator).ParseId"
+	prefix, suffix, err := splitId(id)
+	if err != nil {
+		return ParsedId{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(suffix) != 26 || suffix[0] > '7' {
+		return ParsedId{}, fmt.Errorf("%s: %q is not a ulid: %w", op, id, ErrInvalidParameter)
+	}
+	var ms uint64
+	for _, c := range suffix[:10] {
+		v := strings.IndexRune(crockfordAlphabet, c)
+		if v < 0 {
+			return ParsedId{}, fmt.Errorf("%s: %q is not a ulid: %w", op, id, ErrInvalidParameter)
+		}
+		ms = ms<<5 | uint64(v)
+	}
+	if strings.Trim(suffix[10:], crockfordAlphabet) != "" {
+		return ParsedId{}, fmt.Errorf("%s: %q is not a ulid: %w", op, id, ErrInvalidParameter)
+	}
+	return ParsedId{
+		Prefix:    prefix,
+		Timestamp: time.UnixMilli(int64(ms)).UTC(),
+		Random:    suffix[10:],
+	}, nil
+}
+
+// UUIDv7Generator generates IDs with a UUIDv7 suffix, which is a 48 bit
+// millisecond timestamp and 74 random bits.  UUIDv7s are sortable by their
+// timestamp. See: https://www.rfc-editor.org/rfc/rfc9562#name-uuid-version-7
+type UUIDv7Generator struct{}
+
+// NewId generates a new UUIDv7 with the prefix
+func (UUIDv7Generator) NewId(prefix string) (string, error) {
+	const op = "dbw.(UUIDv7Generator).NewId"
+	if prefix == "" {
+		return "", fmt.Errorf("%s: missing prefix: %w", op, ErrInvalidParameter)
+	}
+	var b [16]byte
+	if _, err := rand.Read(b[6:]); err != nil {
+		return "", fmt.Errorf("%s: unable to generate id: %w", op, ErrInternal)
+	}
+	putMillis(b[:6], time.Now())
+	b[6] = 0x70 | b[6]&0x0f // version 7
+	b[8] = 0x80 | b[8]&0x3f // variant 10
+	h := hex.EncodeToString(b[:])
+	return fmt.Sprintf("%s_%s-%s-%s-%s-%s", prefix, h[0:8], h[8:12], h[12:16], h[16:20], h[20:]), nil
+}
+
+// ParseId parses and validates a UUIDv7
+func (UUIDv7Generator) ParseId(id string) (ParsedId, error) {
+	const op = "dbw.(UUIDv7Generator).ParseId"
+	prefix, suffix, err := splitId(id)
+	if err != nil {
+		return ParsedId{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(suffix) != 36 || suffix[8] != '-' || suffix[13] != '-' || suffix[18] != '-' || suffix[23] != '-' {
+		return ParsedId{}, fmt.Errorf("%s: %q is not a uuid: %w", op, id, ErrInvalidParameter)
+	}
+	h := strings.ReplaceAll(suffix, "-", "")
+	b, err := hex.DecodeString(h)
+	if err != nil || len(b) != 16 {
+		return ParsedId{}, fmt.Errorf("%s: %q is not a uuid: %w", op, id, ErrInvalidParameter)
+	}
+	if b[6]>>4 != 7 || b[8]>>6 != 2 {
+		return ParsedId{}, fmt.Errorf("%s: %q is not a uuid v7: %w", op, id, ErrInvalidParameter)
+	}
+	var ms [8]byte
+	copy(ms[2:], b[:6])
+	return ParsedId{
+		Prefix:    prefix,
+		Timestamp: time.UnixMilli(int64(binary.BigEndian.Uint64(ms[:]))).UTC(),
+		Random:    h[12:],
+	}, nil
+}
+
+// putMillis puts the 48 bit unix millisecond timestamp of t into b
+func putMillis(b []byte, t time.Time) {
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(t.UnixMilli()))
+	copy(b, ms[2:])
+}
+
+const (
+	// MaxSnowflakeNode is the max node ID for a SnowflakeGenerator
+	MaxSnowflakeNode = 1<<snowflakeNodeBits - 1
+
+	snowflakeNodeBits     = 10
+	snowflakeSequenceBits = 12
+	snowflakeSuffixLen    = 11
+)
+
+// snowflakeEpoch is the epoch of Snowflake ID timestamps
+var snowflakeEpoch = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// SnowflakeGenerator generates Snowflake-style IDs, which are a 41 bit
+// millisecond timestamp since 2020-01-01 UTC, a 10 bit node ID and a 12
+// bit sequence encoded as 11 characters of base62.  Snowflake IDs are
+// sortable by their timestamp and are unique as long as each generator has a
+// unique node ID.  A SnowflakeGenerator is safe for concurrent use.
+type SnowflakeGenerator struct {
+	node int64
+
+	mu       sync.Mutex
+	lastMs   int64
+	sequence int64
+}
+
+// NewSnowflakeGenerator creates a new SnowflakeGenerator for the node, which
+// must be between 0 and MaxSnowflakeNode.
+func NewSnowflakeGenerator(node int64) (*SnowflakeGenerator, error) {
+	const op = "dbw.NewSnowflakeGenerator"
+	if node < 0 || node > MaxSnowflakeNode {
+		return nil, fmt.Errorf("%s: node %d is not between 0 and %d: %w", op, node, MaxSnowflakeNode, ErrInvalidParameter)
+	}
+	return &SnowflakeGenerator{node: node}, nil
+}
+
+// NewId generates a new Snowflake ID with the prefix
+func (g *SnowflakeGenerator) NewId(prefix string) (string, error) {
+	const op = "dbw.(SnowflakeGenerator).NewId"
+	if prefix == "" {
+		return "", fmt.Errorf("%s: missing prefix: %w", op, ErrInvalidParameter)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	ms := time.Since(snowflakeEpoch).Milliseconds()
+	if ms < g.lastMs {
+		// the clock went backwards, so keep using the last timestamp
+		ms = g.lastMs
+	}
+	if ms == g.lastMs {
+		g.sequence = (g.sequence + 1) & (1<<snowflakeSequenceBits - 1)
+		if g.sequence == 0 {
+			// the sequence is exhausted for this millisecond
+			for ms <= g.lastMs {
+				time.Sleep(100 * time.Microsecond)
+				ms = time.Since(snowflakeEpoch).Milliseconds()
+			}
+		}
+	} else {
+		g.sequence = 0
+	}
+	g.lastMs = ms
+	v := uint64(ms)<<(snowflakeNodeBits+snowflakeSequenceBits) | uint64(g.node)<<snowflakeSequenceBits | uint64(g.sequence)
+
+	var s [snowflakeSuffixLen]byte
+	for i := snowflakeSuffixLen - 1; i >= 0; i-- {
+		s[i] = base62Alphabet[v%62]
+		v /= 62
+	}
+	return fmt.Sprintf("%s_%s", prefix, s[:]), nil
+}
+
+// ParseId parses and validates a Snowflake ID
+func (g *SnowflakeGenerator) ParseId(id string) (ParsedId, error) {
+	const op = "dbw.(SnowflakeGenerator).ParseId"
+	prefix, suffix, err := splitId(id)
+	if err != nil {
+		return ParsedId{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(suffix) != snowflakeSuffixLen {
+		return ParsedId{}, fmt.Errorf("%s: %q is not a snowflake id: %w", op, id, ErrInvalidParameter)
+	}
+	var v uint64
+	for _, c := range suffix {
+		d := strings.IndexRune(base62Alphabet, c)
+		if d < 0 {
+			return ParsedId{}, fmt.Errorf("%s: %q is not a snowflake id: %w", op, id, ErrInvalidParameter)
+		}
+		// snowflake ids are positive int64s, and checking before the
+		// multiply prevents larger suffixes wrapping around
+		if v > (math.MaxInt64-uint64(d))/62 {
+			return ParsedId{}, fmt.Errorf("%s: %q is not a snowflake id: %w", op, id, ErrInvalidParameter)
+		}
+		v = v*62 + uint64(d)
+	}
+	ms := int64(v >> (snowflakeNodeBits + snowflakeSequenceBits))
+	return ParsedId{
+		Prefix:    prefix,
+		Timestamp: snowflakeEpoch.Add(time.Duration(ms) * time.Millisecond),
+		Node:      int64(v>>snowflakeSequenceBits) & MaxSnowflakeNode,
+		Sequence:  int64(v) & (1<<snowflakeSequenceBits - 1),
+	}, nil
+}
+
+// generateId will set the resource's primary key to a new ID with the prefix
+// if the primary key is empty.  The primary key must be a single string field.
+func (rw *RW) generateId(ctx context.Context, i interface{}, prefix string, g IdGenerator) error {
+	const op = "dbw.generateId"
+	_, isZero, err := rw.primaryFieldsAreZero(ctx, i)
+	switch {
+	case err != nil:
+		return fmt.Errorf("%s: %w", op, err)
+	case !isZero:
+		return nil
+	}
+	mDb := rw.underlying.wrapped.Model(i)
+	if err := mDb.Statement.Parse(i); err != nil || mDb.Statement.Schema == nil {
+		return fmt.Errorf("%s: (internal error) unable to parse stmt: %w", op, ErrUnknown)
+	}
+	pks := mDb.Statement.Schema.PrimaryFields
+	switch {
+	case len(pks) != 1:
+		return fmt.Errorf("%s: %s must have a single primary key to generate an id: %w", op, mDb.Statement.Schema.Table, ErrInvalidParameter)
+	case pks[0].FieldType.Kind() != reflect.String:
+		return fmt.Errorf("%s: %s primary key %s is not a string: %w", op, mDb.Statement.Schema.Table, pks[0].Name, ErrInvalidParameter)
+	}
+	if g == nil {
+		g = rw.underlying.generator()
+	}
+	id, err := g.NewId(prefix)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := pks[0].Set(ctx, reflect.ValueOf(i), id); err != nil {
+		return fmt.Errorf("%s: unable to set %s: %w", op, pks[0].Name, err)
+	}
+	return nil
+}
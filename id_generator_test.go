@@ -0,0 +1,198 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw_test
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-dbw"
+	"github.com/hashicorp/go-dbw/internal/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdGenerators(t *testing.T) {
+	t.Parallel()
+	snowflake, err := dbw.NewSnowflakeGenerator(42)
+	require.NoError(t, err)
+	tests := []struct {
+		name          string
+		gen           dbw.IdGenerator
+		wantSuffixLen int
+		wantTimestamp bool
+		wantRandom    bool
+	}{
+		{
+			name:          "random",
+			gen:           dbw.RandomIdGenerator{},
+			wantSuffixLen: 10,
+			wantRandom:    true,
+		},
+		{
+			name:          "ulid",
+			gen:           dbw.ULIDGenerator{},
+			wantSuffixLen: 26,
+			wantTimestamp: true,
+			wantRandom:    true,
+		},
+		{
+			name:          "uuidv7",
+			gen:           dbw.UUIDv7Generator{},
+			wantSuffixLen: 36,
+			wantTimestamp: true,
+			wantRandom:    true,
+		},
+		{
+			name:          "snowflake",
+			gen:           snowflake,
+			wantSuffixLen: 11,
+			wantTimestamp: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert, require := assert.New(t), require.New(t)
+			before := time.Now().Truncate(time.Millisecond)
+			id, err := dbw.NewId("pre_fix", dbw.WithIdGenerator(tt.gen))
+			require.NoError(err)
+			assert.True(strings.HasPrefix(id, "pre_fix_"))
+			assert.Len(id, len("pre_fix_")+tt.wantSuffixLen)
+
+			parsed, err := dbw.ParseId(id, dbw.WithIdGenerator(tt.gen))
+			require.NoError(err)
+			assert.Equal("pre_fix", parsed.Prefix)
+			if tt.wantTimestamp {
+				assert.False(parsed.Timestamp.Before(before))
+				assert.False(parsed.Timestamp.After(time.Now()))
+			} else {
+				assert.True(parsed.Timestamp.IsZero())
+			}
+			assert.Equal(tt.wantRandom, parsed.Random != "")
+
+			_, err = tt.gen.NewId("")
+			assert.ErrorIs(err, dbw.ErrInvalidParameter)
+			for _, invalid := range []string{"", "missing-prefix", "_" + id[len("pre_fix_"):], "pre_fix_", id + "0", "pre_fix_" + strings.Repeat("!", tt.wantSuffixLen)} {
+				_, err = dbw.ParseId(invalid, dbw.WithIdGenerator(tt.gen))
+				assert.ErrorIsf(err, dbw.ErrInvalidParameter, "invalid id: %q", invalid)
+			}
+		})
+	}
+	t.Run("time-sortable", func(t *testing.T) {
+		for _, g := range []dbw.IdGenerator{dbw.ULIDGenerator{}, dbw.UUIDv7Generator{}, snowflake} {
+			var ids []string
+			for i := 0; i < 5; i++ {
+				id, err := g.NewId("s")
+				require.NoError(t, err)
+				ids = append(ids, id)
+				time.Sleep(2 * time.Millisecond)
+			}
+			assert.Truef(t, sort.StringsAreSorted(ids), "%T ids are not sorted: %v", g, ids)
+		}
+	})
+	t.Run("snowflake", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		seen := map[string]struct{}{}
+		prev := ""
+		for i := 0; i < 10000; i++ {
+			id, err := snowflake.NewId("s")
+			require.NoError(err)
+			_, dup := seen[id]
+			require.False(dup)
+			seen[id] = struct{}{}
+			require.Greater(id, prev)
+			prev = id
+		}
+		parsed, err := snowflake.ParseId(prev)
+		require.NoError(err)
+		assert.Equal(int64(42), parsed.Node)
+
+		// the largest suffix is math.MaxInt64, and larger suffixes (like
+		// 2^64+1) don't wrap around to valid ids
+		parsed, err = snowflake.ParseId("p_AzL8n0Y58m7")
+		require.NoError(err)
+		assert.Equal(int64(dbw.MaxSnowflakeNode), parsed.Node)
+		for _, invalid := range []string{"p_AzL8n0Y58m8", "p_LygHa16AHYH", "p_zzzzzzzzzzz"} {
+			_, err = snowflake.ParseId(invalid)
+			assert.ErrorIsf(err, dbw.ErrInvalidParameter, "invalid id: %q", invalid)
+		}
+
+		_, err = dbw.NewSnowflakeGenerator(dbw.MaxSnowflakeNode + 1)
+		assert.ErrorIs(err, dbw.ErrInvalidParameter)
+		_, err = dbw.NewSnowflakeGenerator(-1)
+		assert.ErrorIs(err, dbw.ErrInvalidParameter)
+	})
+}
+
+func TestDb_CreateWithGenerateId(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	conn, _ := dbw.TestSetup(t)
+	rw := dbw.New(conn)
+
+	t.Run("empty-primary-key", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		u := dbtest.AllocTestUser()
+		u.Name = "empty-primary-key"
+		require.NoError(rw.Create(testCtx, &u, dbw.WithGenerateId("u")))
+		parsed, err := dbw.ParseId(u.PublicId)
+		require.NoError(err)
+		assert.Equal("u", parsed.Prefix)
+
+		found := dbtest.AllocTestUser()
+		found.PublicId = u.PublicId
+		require.NoError(rw.LookupByPublicId(testCtx, &found))
+		assert.Equal("empty-primary-key", found.Name)
+	})
+	t.Run("with-id-generator", func(t *testing.T) {
+		require := require.New(t)
+		u := dbtest.AllocTestUser()
+		require.NoError(rw.Create(testCtx, &u, dbw.WithGenerateId("u"), dbw.WithIdGenerator(dbw.ULIDGenerator{})))
+		_, err := dbw.ParseId(u.PublicId, dbw.WithIdGenerator(dbw.ULIDGenerator{}))
+		require.NoError(err)
+	})
+	t.Run("primary-key-set", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		u, err := dbtest.NewTestUser()
+		require.NoError(err)
+		id := u.PublicId
+		require.NoError(rw.Create(testCtx, u, dbw.WithGenerateId("u")))
+		assert.Equal(id, u.PublicId)
+	})
+	t.Run("create-items", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		items := []*dbtest.TestUser{}
+		for i := 0; i < 3; i++ {
+			u := dbtest.AllocTestUser()
+			items = append(items, &u)
+		}
+		require.NoError(rw.CreateItems(testCtx, items, dbw.WithGenerateId("u")))
+		for _, u := range items {
+			assert.True(strings.HasPrefix(u.PublicId, "u_"))
+		}
+	})
+	t.Run("composite-primary-key", func(t *testing.T) {
+		r, err := dbtest.NewTestRental("", "")
+		require.NoError(t, err)
+		err = rw.Create(testCtx, r, dbw.WithGenerateId("r"))
+		assert.ErrorIs(t, err, dbw.ErrInvalidParameter)
+	})
+	t.Run("db-id-generator", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		_, url := dbw.TestSetup(t, dbw.WithTestDatabaseUrl(filepath.Join(t.TempDir(), "id.db")))
+		typ, _, err := conn.DbType()
+		require.NoError(err)
+		db, err := dbw.Open(typ, url, dbw.WithIdGenerator(dbw.UUIDv7Generator{}))
+		require.NoError(err)
+		t.Cleanup(func() { _ = db.Close(testCtx) })
+		u := dbtest.AllocTestUser()
+		require.NoError(dbw.New(db).Create(testCtx, &u, dbw.WithGenerateId("u")))
+		_, err = dbw.ParseId(u.PublicId, dbw.WithIdGenerator(dbw.UUIDv7Generator{}))
+		assert.NoError(err)
+	})
+}
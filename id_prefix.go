@@ -0,0 +1,153 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// idPrefixes is the registry of ID prefixes and their model types
+var idPrefixes = struct {
+	sync.RWMutex
+	byPrefix map[string]reflect.Type
+	byType   map[reflect.Type]string
+}{
+	byPrefix: map[string]reflect.Type{},
+	byType:   map[reflect.Type]string{},
+}
+
+// RegisterIdPrefix registers the public ID prefix for a model's type.  The
+// model must be a pointer to a struct which implements ResourcePublicIder and
+// has a string PublicId field.  A prefix can only be registered for one type
+// and a type can only have one prefix.
+//
+// Once registered, LookupByPublicId(...) will reject IDs which don't have the
+// model's prefix and ResolveId(...) can load the model from just its ID.
+func RegisterIdPrefix(prefix string, model ResourcePublicIder) error {
+	const op = "dbw.RegisterIdPrefix"
+	switch {
+	case prefix == "":
+		return fmt.Errorf("%s: missing prefix: %w", op, ErrInvalidParameter)
+	case isNil(model):
+		return fmt.Errorf("%s: missing model: %w", op, ErrInvalidParameter)
+	}
+	typ := reflect.TypeOf(model)
+	if typ.Kind() != reflect.Ptr || typ.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%s: model %T is not a pointer to a struct: %w", op, model, ErrInvalidParameter)
+	}
+	if f, ok := typ.Elem().FieldByName("PublicId"); !ok || f.Type.Kind() != reflect.String {
+		return fmt.Errorf("%s: model %T does not have a string PublicId field: %w", op, model, ErrInvalidParameter)
+	}
+
+	idPrefixes.Lock()
+	defer idPrefixes.Unlock()
+	if t, ok := idPrefixes.byPrefix[prefix]; ok && t != typ {
+		return fmt.Errorf("%s: prefix %q is already registered for %s: %w", op, prefix, t, ErrInvalidParameter)
+	}
+	if p, ok := idPrefixes.byType[typ]; ok && p != prefix {
+		return fmt.Errorf("%s: %s is already registered with prefix %q: %w", op, typ, p, ErrInvalidParameter)
+	}
+	idPrefixes.byPrefix[prefix] = typ
+	idPrefixes.byType[typ] = prefix
+	return nil
+}
+
+// RegisteredIdPrefix returns the registered public ID prefix for the model's
+// type.
+func RegisteredIdPrefix(model interface{}) (string, bool) {
+	if isNil(model) {
+		return "", false
+	}
+	idPrefixes.RLock()
+	defer idPrefixes.RUnlock()
+	p, ok := idPrefixes.byType[reflect.TypeOf(model)]
+	return p, ok
+}
+
+// validateIdPrefix will verify that the resource's public ID has its
+// registered prefix.  Resources which are not registered are not validated.
+func validateIdPrefix(resource ResourcePublicIder) error {
+	const op = "dbw.validateIdPrefix"
+	prefix, ok := RegisteredIdPrefix(resource)
+	if !ok {
+		return nil
+	}
+	if id := resource.GetPublicId(); id != "" && !strings.HasPrefix(id, prefix+"_") {
+		return fmt.Errorf("%s: id %q does not have prefix %q for %T: %w", op, id, prefix, resource, ErrInvalidParameter)
+	}
+	return nil
+}
+
+// ResolveId will lookup a resource using only its public ID, based on the
+// model type registered for the ID's prefix (see RegisterIdPrefix).  The
+// resource returned is a pointer to the registered model type.
+// ErrInvalidParameter is returned when the ID's prefix isn't registered and
+// ErrRecordNotFound is returned if the resource doesn't exist.  Options are
+// passed through to LookupByPublicId(...)
+func ResolveId(ctx context.Context, r Reader, id string, opt ...Option) (ResourcePublicIder, error) {
+	const op = "dbw.ResolveId"
+	switch {
+	case isNil(r):
+		return nil, fmt.Errorf("%s: missing reader: %w", op, ErrInvalidParameter)
+	case id == "":
+		return nil, fmt.Errorf("%s: missing id: %w", op, ErrInvalidParameter)
+	}
+
+	// use the longest registered prefix which matches, since prefixes may
+	// contain the delimiter.
+	var typ reflect.Type
+	var prefix string
+	idPrefixes.RLock()
+	for p, t := range idPrefixes.byPrefix {
+		if strings.HasPrefix(id, p+"_") && len(p) > len(prefix) {
+			prefix, typ = p, t
+		}
+	}
+	idPrefixes.RUnlock()
+	if typ == nil {
+		return nil, fmt.Errorf("%s: no model registered for id %q: %w", op, id, ErrInvalidParameter)
+	}
+
+	resource := reflect.New(typ.Elem())
+	if err := setPublicId(resource, id); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	i := resource.Interface().(ResourcePublicIder)
+	if err := r.LookupByPublicId(ctx, i, opt...); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return i, nil
+}
+
+// setPublicId sets the PublicId field of the resource, allocating any nil
+// embedded structs along the way.
+func setPublicId(resource reflect.Value, id string) error {
+	const op = "dbw.setPublicId"
+	v := resource.Elem()
+	f, ok := v.Type().FieldByName("PublicId")
+	if !ok {
+		return fmt.Errorf("%s: %s does not have a PublicId field: %w", op, v.Type(), ErrInvalidParameter)
+	}
+	for n, idx := range f.Index {
+		if n > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return fmt.Errorf("%s: unable to allocate %s: %w", op, v.Type(), ErrInvalidParameter)
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(idx)
+	}
+	if !v.CanSet() {
+		return fmt.Errorf("%s: unable to set PublicId: %w", op, ErrInvalidParameter)
+	}
+	v.SetString(id)
+	return nil
+}
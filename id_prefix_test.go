@@ -0,0 +1,115 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/go-dbw"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testPrefixedUser struct {
+	PublicId string `gorm:"primaryKey"`
+	Name     string `gorm:"default:null"`
+}
+
+func (*testPrefixedUser) TableName() string     { return "db_test_user" }
+func (u *testPrefixedUser) GetPublicId() string { return u.PublicId }
+
+type PrefixedCarStore struct {
+	PublicId string `gorm:"primaryKey"`
+	Name     string `gorm:"default:null"`
+}
+
+type testPrefixedCar struct {
+	*PrefixedCarStore
+}
+
+func (*testPrefixedCar) TableName() string     { return "db_test_car" }
+func (c *testPrefixedCar) GetPublicId() string { return c.PublicId }
+
+type testNotPrefixed struct {
+	Id string
+}
+
+func (n *testNotPrefixed) GetPublicId() string { return n.Id }
+
+func TestRegisterIdPrefix(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require.NoError(t, dbw.RegisterIdPrefix("tpu", &testPrefixedUser{}))
+	// registering the same prefix and type is idempotent
+	assert.NoError(dbw.RegisterIdPrefix("tpu", &testPrefixedUser{}))
+
+	p, ok := dbw.RegisteredIdPrefix(&testPrefixedUser{})
+	assert.True(ok)
+	assert.Equal("tpu", p)
+	_, ok = dbw.RegisteredIdPrefix(&testNotPrefixed{})
+	assert.False(ok)
+
+	assert.ErrorIs(dbw.RegisterIdPrefix("", &testPrefixedUser{}), dbw.ErrInvalidParameter)
+	assert.ErrorIs(dbw.RegisterIdPrefix("tpu", nil), dbw.ErrInvalidParameter)
+	assert.ErrorIs(dbw.RegisterIdPrefix("other", &testPrefixedUser{}), dbw.ErrInvalidParameter)
+	assert.ErrorIs(dbw.RegisterIdPrefix("tpu", &testPrefixedCar{}), dbw.ErrInvalidParameter)
+	assert.ErrorIs(dbw.RegisterIdPrefix("tnp", &testNotPrefixed{}), dbw.ErrInvalidParameter)
+}
+
+func TestResolveId(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	conn, _ := dbw.TestSetup(t)
+	rw := dbw.New(conn)
+	require.NoError(t, dbw.RegisterIdPrefix("tpu", &testPrefixedUser{}))
+	require.NoError(t, dbw.RegisterIdPrefix("tp_car", &testPrefixedCar{}))
+
+	userId, err := dbw.NewId("tpu")
+	require.NoError(t, err)
+	require.NoError(t, rw.Create(testCtx, &testPrefixedUser{PublicId: userId, Name: "resolve-user"}))
+	carId, err := dbw.NewId("tp_car")
+	require.NoError(t, err)
+	require.NoError(t, rw.Create(testCtx, &testPrefixedCar{&PrefixedCarStore{PublicId: carId, Name: "resolve-car"}}))
+
+	t.Run("lookup-wrong-prefix", func(t *testing.T) {
+		err := rw.LookupByPublicId(testCtx, &testPrefixedUser{PublicId: carId})
+		assert.ErrorIs(t, err, dbw.ErrInvalidParameter)
+	})
+	t.Run("lookup-right-prefix", func(t *testing.T) {
+		u := &testPrefixedUser{PublicId: userId}
+		require.NoError(t, rw.LookupByPublicId(testCtx, u))
+		assert.Equal(t, "resolve-user", u.Name)
+	})
+	t.Run("resolve-user", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		got, err := dbw.ResolveId(testCtx, rw, userId)
+		require.NoError(err)
+		u, ok := got.(*testPrefixedUser)
+		require.True(ok)
+		assert.Equal("resolve-user", u.Name)
+	})
+	t.Run("resolve-embedded", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		got, err := dbw.ResolveId(testCtx, rw, carId)
+		require.NoError(err)
+		c, ok := got.(*testPrefixedCar)
+		require.True(ok)
+		assert.Equal("resolve-car", c.Name)
+	})
+	t.Run("not-found", func(t *testing.T) {
+		id, err := dbw.NewId("tpu")
+		require.NoError(t, err)
+		_, err = dbw.ResolveId(testCtx, rw, id)
+		assert.ErrorIs(t, err, dbw.ErrRecordNotFound)
+	})
+	t.Run("unregistered-prefix", func(t *testing.T) {
+		_, err := dbw.ResolveId(testCtx, rw, "unknown_1234567890")
+		assert.ErrorIs(t, err, dbw.ErrInvalidParameter)
+	})
+	t.Run("missing-id", func(t *testing.T) {
+		_, err := dbw.ResolveId(testCtx, rw, "")
+		assert.ErrorIs(t, err, dbw.ErrInvalidParameter)
+	})
+}
@@ -0,0 +1,249 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultLockExpiration is the default expiration for a lock when the
+	// WithLockExpiration(...) option isn't specified.
+	DefaultLockExpiration = 30 * time.Second
+
+	// lockTableName is the table used for locks when the database doesn't
+	// support advisory locks (sqlite)
+	lockTableName = "dbw_lock"
+)
+
+var (
+	// ErrLockNotAcquired is returned when a lock is held by another session
+	// and cannot be acquired.
+	ErrLockNotAcquired = errors.New("lock not acquired")
+
+	// ErrLockLost is returned when the session holding a lock has been lost,
+	// which means the lock may now be held by another session.
+	ErrLockLost = errors.New("lock lost")
+)
+
+// LockKey hashes a string lock key into the int64 key used by postgres
+// advisory locks.
+func LockKey(key string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return int64(h.Sum64())
+}
+
+// Lock is a handle for a distributed lock which is held until it's closed or
+// the session holding it is lost.  See DB.Lock(...) and DB.TryLock(...)
+type Lock struct {
+	key      string
+	impl     SessionLock
+	interval time.Duration
+
+	mu      sync.Mutex
+	closed  bool
+	lostErr error
+	stop    chan struct{}
+	done    chan struct{}
+	lost    chan struct{}
+}
+
+// SessionLock defines a dialect's implementation of a lock which is held by a
+// session.  See LockDialect
+type SessionLock interface {
+	// TryLock will try to acquire the lock and returns true when it's
+	// acquired.
+	TryLock(ctx context.Context) (bool, error)
+	// Lock will block until the lock is acquired or the ctx is done.
+	Lock(ctx context.Context) error
+	// Check will return an error if the lock is no longer held.
+	Check(ctx context.Context) error
+	// Unlock will release the lock and any resources held by it.
+	Unlock(ctx context.Context) error
+}
+
+// Key returns the lock's key
+func (l *Lock) Key() string {
+	return l.key
+}
+
+// Lost returns a channel that's closed when the session holding the lock has
+// been lost.  Once the channel is closed, Err() will return ErrLockLost.
+func (l *Lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Err returns ErrLockLost if the session holding the lock has been lost,
+// otherwise nil is returned.
+func (l *Lock) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lostErr
+}
+
+// Close will release the lock.  ErrLockLost is returned if the lock was lost
+// before it was closed. Close is idempotent.
+func (l *Lock) Close(ctx context.Context) error {
+	const op = "dbw.(Lock).Close"
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	l.mu.Unlock()
+
+	close(l.stop)
+	<-l.done
+
+	if err := l.impl.Unlock(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := l.Err(); err != nil {
+		return fmt.Errorf("%s: %s: %w", op, l.key, err)
+	}
+	return nil
+}
+
+func (l *Lock) monitor() {
+	defer close(l.done)
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), l.interval)
+			err := l.impl.Check(ctx)
+			cancel()
+			if err != nil {
+				l.mu.Lock()
+				l.lostErr = ErrLockLost
+				l.mu.Unlock()
+				close(l.lost)
+				return
+			}
+		}
+	}
+}
+
+// Lock will acquire a distributed lock for the key, blocking until the lock is
+// acquired or the ctx is done.  The returned Lock must be closed to release
+// the lock.  The WithLockExpiration option is supported.
+//
+// The lock is provided by the DB's dialect (see LockDialect).  Postgres uses a
+// session level advisory lock (pg_advisory_lock) on a connection dedicated to
+// the lock, using LockKey(...) to hash the key. Sqlite uses a lock table
+// (dbw_lock) where each lock expires unless it's refreshed by the session
+// holding it.
+//
+// The lock's session is checked at an interval of a third of the lock's
+// expiration, and Lost() is closed if the session is lost.
+func (db *DB) Lock(ctx context.Context, key string, opt ...Option) (*Lock, error) {
+	const op = "dbw.(DB).Lock"
+	l, err := db.newLock(ctx, key, opt...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := l.impl.Lock(ctx); err != nil {
+		_ = l.impl.Unlock(context.Background())
+		return nil, fmt.Errorf("%s: %s: %w", op, key, err)
+	}
+	go l.monitor()
+	return l, nil
+}
+
+// TryLock will try to acquire a distributed lock for the key without blocking.
+// It returns false (with a nil Lock) when the lock is held by another
+// session. The returned Lock must be closed to release the lock. See
+// DB.Lock(...) for more information.  The WithLockExpiration option is
+// supported.
+func (db *DB) TryLock(ctx context.Context, key string, opt ...Option) (*Lock, bool, error) {
+	const op = "dbw.(DB).TryLock"
+	l, err := db.newLock(ctx, key, opt...)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+	acquired, err := l.impl.TryLock(ctx)
+	if err != nil || !acquired {
+		_ = l.impl.Unlock(context.Background())
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %s: %w", op, key, err)
+		}
+		return nil, false, nil
+	}
+	go l.monitor()
+	return l, true, nil
+}
+
+func (db *DB) newLock(ctx context.Context, key string, opt ...Option) (*Lock, error) {
+	const op = "dbw.(DB).newLock"
+	if db.wrapped == nil {
+		return nil, fmt.Errorf("%s: missing underlying database: %w", op, ErrInternal)
+	}
+	if key == "" {
+		return nil, fmt.Errorf("%s: missing key: %w", op, ErrInvalidParameter)
+	}
+	opts := GetOpts(opt...)
+	if opts.WithLockExpiration <= 0 {
+		return nil, fmt.Errorf("%s: lock expiration must be greater than zero: %w", op, ErrInvalidParameter)
+	}
+	dialect := db.dialect()
+	ld, ok := dialect.(LockDialect)
+	if !ok {
+		return nil, fmt.Errorf("%s: locks are not supported for %s: %w", op, dialect.Name(), ErrInvalidParameter)
+	}
+	impl, err := ld.NewSessionLock(ctx, db, key, opts.WithLockExpiration)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &Lock{
+		key:      key,
+		impl:     impl,
+		interval: opts.WithLockExpiration / 3,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		lost:     make(chan struct{}),
+	}, nil
+}
+
+// LockTx will acquire a transaction scoped lock for the key, which is released
+// when the transaction is committed or rolled back. The rw must be within a
+// transaction (see RW.IsTx()), otherwise ErrInvalidParameter is returned.
+//
+// The lock is provided by the DB's dialect (see LockDialect).  Postgres uses
+// a transaction level advisory lock (pg_advisory_xact_lock) which blocks
+// until the lock is acquired or the ctx is done.  Sqlite serializes all write
+// transactions, so the lock simply acquires the transaction's write lock,
+// without writing a row to the lock table. ErrLockNotAcquired is
+// returned for sqlite if the key is held by a session lock (see
+// DB.Lock(...)), since waiting within the transaction would prevent the
+// session from releasing it.
+func (rw *RW) LockTx(ctx context.Context, key string) error {
+	const op = "dbw.LockTx"
+	switch {
+	case rw.underlying == nil:
+		return fmt.Errorf("%s: missing underlying db: %w", op, ErrInvalidParameter)
+	case key == "":
+		return fmt.Errorf("%s: missing key: %w", op, ErrInvalidParameter)
+	case !rw.IsTx():
+		return fmt.Errorf("%s: not in a transaction: %w", op, ErrInvalidParameter)
+	}
+	dialect := rw.underlying.dialect()
+	ld, ok := dialect.(LockDialect)
+	if !ok {
+		return fmt.Errorf("%s: locks are not supported for %s: %w", op, dialect.Name(), ErrInvalidParameter)
+	}
+	if err := ld.LockTx(ctx, rw, key); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
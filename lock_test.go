@@ -0,0 +1,216 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-dbw"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testLockDB returns a db which can be used concurrently by a lock's
+// monitor.  For sqlite, that requires a file db since each connection to an
+// in-memory db creates a new db.
+func testLockDB(t *testing.T) *dbw.DB {
+	t.Helper()
+	conn, _ := dbw.TestSetup(t, dbw.WithTestDatabaseUrl(filepath.Join(t.TempDir(), "lock.db")))
+	_, rawName, err := conn.DbType()
+	require.NoError(t, err)
+	if d, ok := dbw.LookupDialect(rawName); !ok {
+		t.Skipf("%s doesn't have a dialect", rawName)
+	} else if _, ok := d.(dbw.LockDialect); !ok {
+		t.Skipf("locks are not supported for %s", rawName)
+	}
+	return conn
+}
+
+func TestDB_TryLock(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	conn := testLockDB(t)
+
+	t.Run("acquire-and-release", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		l, acquired, err := conn.TryLock(testCtx, "acquire-and-release")
+		require.NoError(err)
+		require.True(acquired)
+		assert.Equal("acquire-and-release", l.Key())
+
+		other, acquired, err := conn.TryLock(testCtx, "acquire-and-release")
+		require.NoError(err)
+		assert.False(acquired)
+		assert.Nil(other)
+
+		require.NoError(l.Close(testCtx))
+		require.NoError(l.Close(testCtx)) // idempotent
+
+		l, acquired, err = conn.TryLock(testCtx, "acquire-and-release")
+		require.NoError(err)
+		require.True(acquired)
+		require.NoError(l.Close(testCtx))
+	})
+	t.Run("different-keys", func(t *testing.T) {
+		require := require.New(t)
+		l1, acquired, err := conn.TryLock(testCtx, "key-1")
+		require.NoError(err)
+		require.True(acquired)
+		defer l1.Close(testCtx)
+		l2, acquired, err := conn.TryLock(testCtx, "key-2")
+		require.NoError(err)
+		require.True(acquired)
+		defer l2.Close(testCtx)
+	})
+	t.Run("missing-key", func(t *testing.T) {
+		_, _, err := conn.TryLock(testCtx, "")
+		assert.ErrorIs(t, err, dbw.ErrInvalidParameter)
+	})
+	t.Run("invalid-expiration", func(t *testing.T) {
+		_, _, err := conn.TryLock(testCtx, "invalid-expiration", dbw.WithLockExpiration(0))
+		assert.ErrorIs(t, err, dbw.ErrInvalidParameter)
+	})
+}
+
+func TestDB_Lock(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	conn := testLockDB(t)
+
+	t.Run("blocks-until-released", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		opt := dbw.WithLockExpiration(300 * time.Millisecond)
+		l, err := conn.Lock(testCtx, "blocks", opt)
+		require.NoError(err)
+
+		acquired := make(chan *dbw.Lock)
+		go func() {
+			l2, err := conn.Lock(testCtx, "blocks", opt)
+			assert.NoError(err)
+			acquired <- l2
+		}()
+		select {
+		case <-acquired:
+			assert.Fail("lock acquired while held")
+		case <-time.After(100 * time.Millisecond):
+		}
+		require.NoError(l.Close(testCtx))
+		select {
+		case l2 := <-acquired:
+			require.NotNil(l2)
+			require.NoError(l2.Close(testCtx))
+		case <-time.After(5 * time.Second):
+			assert.Fail("lock not acquired after release")
+		}
+	})
+	t.Run("cancelled", func(t *testing.T) {
+		require := require.New(t)
+		l, err := conn.Lock(testCtx, "cancelled")
+		require.NoError(err)
+		defer l.Close(testCtx)
+
+		ctx, cancel := context.WithTimeout(testCtx, 50*time.Millisecond)
+		defer cancel()
+		_, err = conn.Lock(ctx, "cancelled")
+		require.Error(err)
+	})
+}
+
+func TestLock_Lost(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	conn := testLockDB(t)
+	if typ, _, _ := conn.DbType(); typ != dbw.Sqlite {
+		t.Skip("losing the lock's session is only simulated for sqlite")
+	}
+	assert, require := assert.New(t), require.New(t)
+	l, err := conn.Lock(testCtx, "lost", dbw.WithLockExpiration(150*time.Millisecond))
+	require.NoError(err)
+	require.NoError(l.Err())
+
+	// simulate another session taking over the lock after it expired
+	_, err = dbw.New(conn).Exec(testCtx, "update dbw_lock set owner = ? where name = ?", []interface{}{"another-session", "lost"})
+	require.NoError(err)
+	select {
+	case <-l.Lost():
+	case <-time.After(5 * time.Second):
+		require.Fail("lost lock not detected")
+	}
+	assert.ErrorIs(l.Err(), dbw.ErrLockLost)
+	assert.ErrorIs(l.Close(testCtx), dbw.ErrLockLost)
+}
+
+func TestRW_LockTx(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	conn := testLockDB(t)
+	rw := dbw.New(conn)
+
+	t.Run("not-in-tx", func(t *testing.T) {
+		err := rw.LockTx(testCtx, "not-in-tx")
+		assert.ErrorIs(t, err, dbw.ErrInvalidParameter)
+	})
+	t.Run("missing-key", func(t *testing.T) {
+		_, err := rw.DoTx(testCtx, func(error) bool { return false }, 0, dbw.ConstBackoff{}, func(_ dbw.Reader, w dbw.Writer) error {
+			return w.(*dbw.RW).LockTx(testCtx, "")
+		})
+		assert.ErrorIs(t, err, dbw.ErrInvalidParameter)
+	})
+	t.Run("in-tx", func(t *testing.T) {
+		_, err := rw.DoTx(testCtx, func(error) bool { return false }, 0, dbw.ConstBackoff{}, func(_ dbw.Reader, w dbw.Writer) error {
+			return w.(*dbw.RW).LockTx(testCtx, "in-tx")
+		})
+		require.NoError(t, err)
+
+		// released when the tx is complete
+		l, acquired, err := conn.TryLock(testCtx, "in-tx")
+		require.NoError(t, err)
+		require.True(t, acquired)
+		require.NoError(t, l.Close(testCtx))
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		if typ, _, _ := conn.DbType(); typ != dbw.Sqlite {
+			t.Skip("the lock table is only used by sqlite")
+		}
+		assert, require := assert.New(t), require.New(t)
+		r := dbw.NewRecorder()
+		conn, _ := dbw.TestSetup(t, dbw.WithTestDatabaseUrl(filepath.Join(t.TempDir(), "lock-tx.db")), dbw.WithTestRecorder(r))
+		rw := dbw.New(conn)
+		lockTx := func(key string) error {
+			_, err := rw.DoTx(testCtx, func(error) bool { return false }, 0, dbw.ConstBackoff{}, func(_ dbw.Reader, w dbw.Writer) error {
+				return w.(*dbw.RW).LockTx(testCtx, key)
+			})
+			return err
+		}
+		// the first lock creates the lock table
+		require.NoError(lockTx("sqlite"))
+		r.Reset()
+		require.NoError(lockTx("sqlite"))
+		dbw.AssertNoQueriesMatching(t, r, `(?i)create table`)
+
+		// the tx locks don't leave rows behind
+		rows, err := rw.Query(testCtx, "select name from dbw_lock", nil)
+		require.NoError(err)
+		assert.False(rows.Next())
+		require.NoError(rows.Close())
+
+		// a key held by a session lock isn't acquired
+		l, acquired, err := conn.TryLock(testCtx, "sqlite")
+		require.NoError(err)
+		require.True(acquired)
+		defer l.Close(testCtx)
+		assert.ErrorIs(lockTx("sqlite"), dbw.ErrLockNotAcquired)
+		require.NoError(lockTx("another-key"))
+	})
+}
+
+func TestLockKey(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	assert.Equal(dbw.LockKey("migrations"), dbw.LockKey("migrations"))
+	assert.NotEqual(dbw.LockKey("migrations"), dbw.LockKey("leader"))
+}
@@ -14,9 +14,13 @@ import (
 // unique. If the resource implements either ResourcePublicIder or
 // ResourcePrivateIder interface, then they are used as the resource's
 // primary key for lookup.  Otherwise, the resource tags are used to
-// determine it's primary key(s) for lookup.  The WithDebug and WithTable
-// options are supported.
-func (rw *RW) LookupBy(ctx context.Context, resourceWithIder interface{}, opt ...Option) error {
+// determine it's primary key(s) for lookup.  The WithDebug, WithTable,
+// WithLock and WithTimeout options are supported.
+//
+// If the DB has a LookupCache (see WithLookupCache), then resources of models
+// with a cache TTL are returned from the cache when possible.  Lookups
+// WithLock always read from the db.
+func (rw *RW) LookupBy(ctx context.Context, resourceWithIder interface{}, opt ...Option) (retErr error) {
 	const op = "dbw.LookupById"
 	if rw.underlying == nil {
 		return fmt.Errorf("%s: missing underlying db: %w", op, ErrInvalidParameter)
@@ -32,6 +36,19 @@ func (rw *RW) LookupBy(ctx context.Context, resourceWithIder interface{}, opt ..
 		return fmt.Errorf("%s: %w", op, err)
 	}
 	opts := GetOpts(opt...)
+	lock, err := rw.lockingClause(opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	hit, cacheKey, cacheGen := rw.cachedLookup(ctx, resourceWithIder, opts)
+	if hit {
+		return nil
+	}
+	rw, ctx, done, err := rw.startTimeout(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { retErr = done(retErr) }()
 	db := rw.underlying.wrapped.WithContext(ctx)
 	if opts.WithTable != "" {
 		db = db.Table(opts.WithTable)
@@ -39,6 +56,9 @@ func (rw *RW) LookupBy(ctx context.Context, resourceWithIder interface{}, opt ..
 	if opts.WithDebug {
 		db = db.Debug()
 	}
+	if lock != nil {
+		db = db.Clauses(lock)
+	}
 	rw.clearDefaultNullResourceFields(ctx, resourceWithIder)
 	if err := db.Where(where, keys...).First(resourceWithIder).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -46,12 +66,22 @@ func (rw *RW) LookupBy(ctx context.Context, resourceWithIder interface{}, opt ..
 		}
 		return fmt.Errorf("%s: %w", op, err)
 	}
+	if cacheKey != "" {
+		rw.underlying.lookupCache.set(ctx, cacheKey, cacheGen, resourceWithIder)
+	}
 	return nil
 }
 
 // LookupByPublicId will lookup resource by its public_id, which must be unique.
-// The WithTable option is supported.
+// If the resource's type has a registered ID prefix (see RegisterIdPrefix),
+// then an ID without that prefix is rejected with ErrInvalidParameter before
+// querying the db. The WithTable, WithLock and WithTimeout options are
+// supported.
 func (rw *RW) LookupByPublicId(ctx context.Context, resource ResourcePublicIder, opt ...Option) error {
+	const op = "dbw.LookupByPublicId"
+	if err := validateIdPrefix(resource); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 	return rw.LookupBy(ctx, resource, opt...)
 }
 
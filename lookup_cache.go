@@ -0,0 +1,563 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// DefaultLookupCacheSize is the size of the default LRU CacheBackend
+const DefaultLookupCacheSize = 1000
+
+// CacheBackend stores the resources cached by a LookupCache.  Backends must
+// be safe for concurrent use.  They don't return errors, so a backend which
+// fails to get a value should report a miss, and a backend which fails to
+// delete a value must handle the error itself (the value's TTL still limits
+// how long it's stale).
+type CacheBackend interface {
+	// Get returns the value for the key, if it's cached and hasn't expired
+	Get(ctx context.Context, key string) (interface{}, bool)
+
+	// Set caches the value for the key, which expires after the ttl
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration)
+
+	// Delete removes the values for the keys
+	Delete(ctx context.Context, keys ...string)
+}
+
+// LookupCache caches the resources returned by LookupBy(...) and
+// LookupByPublicId(...) for models with a TTL.  A LookupCache is used with
+// the WithLookupCache(...) option.
+//
+// Cached resources are invalidated by Update(...), UpdateWhere(...),
+// Delete(...), DeleteItems(...), DeleteWhere(...) and Create(...) or
+// CreateItems(...) with an OnConflict update.  Writes in a transaction are
+// invalidated when it commits, so rolled back writes don't invalidate
+// anything.  A lookup which read its resource before it was invalidated
+// doesn't cache it.  Writes executed via Exec(...) or by other processes
+// aren't detected, so TTLs should be short unless the resources are
+// immutable.
+type LookupCache struct {
+	backend    CacheBackend
+	defaultTTL time.Duration
+	modelTTLs  map[reflect.Type]time.Duration
+
+	// generations are bumped when their keys are invalidated, so a lookup
+	// which missed before an invalidation doesn't cache the resource it
+	// read.  Keys share the generation of their stripe, which may drop the
+	// sets of other keys, but it never caches a stale resource.
+	generations [lookupCacheStripes]lookupCacheGeneration
+}
+
+// lookupCacheStripes is the number of generations of a LookupCache
+const lookupCacheStripes = 256
+
+type lookupCacheGeneration struct {
+	mu sync.Mutex
+	n  uint64
+}
+
+// NewLookupCache creates a new LookupCache.  Options supported:
+// WithCacheBackend, WithCacheSize, WithCacheTTL and WithCacheModelTTL
+func NewLookupCache(opt ...CacheOption) (*LookupCache, error) {
+	const op = "dbw.NewLookupCache"
+	opts := getCacheOpts(opt...)
+	if opts.withCacheTTL < 0 {
+		return nil, fmt.Errorf("%s: negative ttl: %w", op, ErrInvalidParameter)
+	}
+	backend := opts.withCacheBackend
+	if backend == nil {
+		if opts.withCacheSize <= 0 {
+			return nil, fmt.Errorf("%s: cache size must be greater than zero: %w", op, ErrInvalidParameter)
+		}
+		backend = NewLRUCacheBackend(opts.withCacheSize)
+	}
+	modelTTLs := make(map[reflect.Type]time.Duration, len(opts.withCacheModelTTLs))
+	for _, m := range opts.withCacheModelTTLs {
+		typ := cacheModelType(m.model)
+		if typ == nil || typ.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("%s: model %T is not a struct: %w", op, m.model, ErrInvalidParameter)
+		}
+		modelTTLs[typ] = m.ttl
+	}
+	return &LookupCache{
+		backend:    backend,
+		defaultTTL: opts.withCacheTTL,
+		modelTTLs:  modelTTLs,
+	}, nil
+}
+
+// ttl returns the ttl of the resource's model, which is zero when the model
+// isn't cached.
+func (c *LookupCache) ttl(resource interface{}) time.Duration {
+	if ttl, ok := c.modelTTLs[cacheModelType(resource)]; ok {
+		return ttl
+	}
+	return c.defaultTTL
+}
+
+// get sets the resource to its cached value and returns true on a cache hit
+func (c *LookupCache) get(ctx context.Context, key string, resource interface{}) bool {
+	cached, ok := c.backend.Get(ctx, key)
+	if !ok {
+		return false
+	}
+	v := reflect.ValueOf(resource)
+	cv := reflect.ValueOf(cached)
+	if v.Kind() != reflect.Ptr || v.IsNil() || cv.Type() != v.Elem().Type() {
+		// the key's table may be shared by several models via WithTable
+		return false
+	}
+	v.Elem().Set(deepCopy(cv, map[deepCopyKey]reflect.Value{}))
+	return true
+}
+
+// set caches a deep copy of the resource, so neither the caller nor later
+// cache hits share the cached resource's embedded structs, slices or maps.
+// The gen is the key's generation before the resource was read, and the
+// resource isn't cached if the key was invalidated since then.
+func (c *LookupCache) set(ctx context.Context, key string, gen uint64, resource interface{}) {
+	ttl := c.ttl(resource)
+	if ttl <= 0 {
+		return
+	}
+	v := reflect.ValueOf(resource)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return
+	}
+	g := c.generation(key)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.n != gen {
+		return
+	}
+	c.backend.Set(ctx, key, deepCopy(v.Elem(), map[deepCopyKey]reflect.Value{}).Interface(), ttl)
+}
+
+// invalidate deletes the keys' cached resources, after bumping their
+// generations so concurrent lookups which read them earlier don't cache them.
+func (c *LookupCache) invalidate(ctx context.Context, keys ...string) {
+	for _, k := range keys {
+		g := c.generation(k)
+		g.mu.Lock()
+		g.n++
+		g.mu.Unlock()
+	}
+	c.backend.Delete(ctx, keys...)
+}
+
+// generation returns the generation of the key's stripe
+func (c *LookupCache) generation(key string) *lookupCacheGeneration {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return &c.generations[h.Sum32()%lookupCacheStripes]
+}
+
+// currentGeneration returns the key's current generation, which is passed to
+// set(...) after the key's resource is read.
+func (c *LookupCache) currentGeneration(key string) uint64 {
+	g := c.generation(key)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.n
+}
+
+// deepCopyKey identifies a pointer which was already copied, by its type and
+// address
+type deepCopyKey struct {
+	typ  reflect.Type
+	addr uintptr
+}
+
+// deepCopy returns a copy of the value which doesn't share its pointers,
+// slices, maps or interfaces.  Unexported struct fields can't be set via
+// reflection, so they're copied shallowly.  The copied pointers are tracked,
+// so cyclic values are copied once.
+func deepCopy(v reflect.Value, copied map[deepCopyKey]reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		k := deepCopyKey{typ: v.Type(), addr: v.Pointer()}
+		if c, ok := copied[k]; ok {
+			return c
+		}
+		c := reflect.New(v.Type().Elem())
+		copied[k] = c
+		c.Elem().Set(deepCopy(v.Elem(), copied))
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < c.NumField(); i++ {
+			if f := c.Field(i); f.CanSet() {
+				f.Set(deepCopy(v.Field(i), copied))
+			}
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i), copied))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i), copied))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), deepCopy(iter.Value(), copied))
+		}
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopy(v.Elem(), copied))
+		return c
+	default:
+		return v
+	}
+}
+
+func cacheModelType(model interface{}) reflect.Type {
+	typ := reflect.TypeOf(model)
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+// lookupCacheTx is the set of keys written by a transaction, which are
+// invalidated when it commits.
+type lookupCacheTx struct {
+	mu   sync.Mutex
+	keys map[string]struct{}
+}
+
+func newLookupCacheTx() *lookupCacheTx {
+	return &lookupCacheTx{keys: map[string]struct{}{}}
+}
+
+func (tx *lookupCacheTx) add(keys ...string) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	for _, k := range keys {
+		tx.keys[k] = struct{}{}
+	}
+}
+
+func (tx *lookupCacheTx) contains(key string) bool {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	_, ok := tx.keys[key]
+	return ok
+}
+
+func (tx *lookupCacheTx) drain() []string {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	keys := make([]string, 0, len(tx.keys))
+	for k := range tx.keys {
+		keys = append(keys, k)
+	}
+	tx.keys = map[string]struct{}{}
+	return keys
+}
+
+// lookupCacheKey returns the cache key of the resource, which is built from
+// its table and primary keys.
+func (rw *RW) lookupCacheKey(ctx context.Context, resource interface{}, table string) (string, error) {
+	const op = "dbw.lookupCacheKey"
+	where, keys, err := rw.primaryKeysWhere(ctx, resource)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	if table == "" {
+		tx := rw.underlying.wrapped.Model(resource)
+		if err := tx.Statement.Parse(resource); err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+		table = tx.Statement.Schema.Table
+	}
+	return fmt.Sprintf("%s:%s:%v", table, where, keys), nil
+}
+
+// cachedLookup returns true when the resource was set from the lookup cache.
+// The returned key and its generation are used to cache the resource after
+// it's read from the db, and the key is empty when the resource must not be
+// cached.
+func (rw *RW) cachedLookup(ctx context.Context, resource interface{}, opts Options) (bool, string, uint64) {
+	c := rw.underlying.lookupCache
+	if c == nil || opts.WithLock != nil || c.ttl(resource) <= 0 {
+		return false, "", 0
+	}
+	key, err := rw.lookupCacheKey(ctx, resource, opts.WithTable)
+	if err != nil {
+		return false, "", 0
+	}
+	if tx := rw.underlying.lookupCacheTx; tx != nil {
+		// transactions don't populate the cache, since their writes may be
+		// rolled back, and they must read their own writes from the db.
+		if tx.contains(key) {
+			return false, "", 0
+		}
+		return c.get(ctx, key, resource), "", 0
+	}
+	// the generation is read before the cache, so an invalidation after the
+	// miss prevents caching the resource read from the db.
+	gen := c.currentGeneration(key)
+	if c.get(ctx, key, resource) {
+		return true, "", 0
+	}
+	return false, key, gen
+}
+
+// invalidateLookupCache invalidates the cached resources, or defers
+// invalidating them until the rw's transaction commits.
+func (rw *RW) invalidateLookupCache(ctx context.Context, table string, resources ...interface{}) {
+	c := rw.underlying.lookupCache
+	if c == nil {
+		return
+	}
+	keys := make([]string, 0, len(resources))
+	for _, r := range resources {
+		if c.ttl(r) <= 0 {
+			continue
+		}
+		// resources without primary keys can't have been cached
+		if key, err := rw.lookupCacheKey(ctx, r, table); err == nil {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+	if tx := rw.underlying.lookupCacheTx; tx != nil {
+		tx.add(keys...)
+		return
+	}
+	c.invalidate(ctx, keys...)
+}
+
+// cachedResourcesWhere returns the resources of the model which match the
+// where clause, with only their primary keys set, so they can be invalidated
+// after they're written by UpdateWhere(...) or DeleteWhere(...).  Nothing is
+// returned when the model isn't cached.
+func (rw *RW) cachedResourcesWhere(ctx context.Context, model interface{}, table, where string, args []interface{}) ([]interface{}, error) {
+	const op = "dbw.cachedResourcesWhere"
+	c := rw.underlying.lookupCache
+	if c == nil || c.ttl(model) <= 0 {
+		return nil, nil
+	}
+	tx := rw.underlying.wrapped.Model(model)
+	if err := tx.Statement.Parse(model); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	resources := reflect.New(reflect.SliceOf(reflect.TypeOf(model)))
+	db := rw.underlying.wrapped.WithContext(ctx).Select(tx.Statement.Schema.PrimaryFieldDBNames)
+	if table != "" {
+		db = db.Table(table)
+	}
+	if where != "" {
+		db = db.Where(where, args...)
+	}
+	if err := db.Find(resources.Interface()).Error; err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return sliceItems(resources.Elem()), nil
+}
+
+// sliceItems returns the items of a slice
+func sliceItems(v reflect.Value) []interface{} {
+	items := make([]interface{}, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		items = append(items, v.Index(i).Interface())
+	}
+	return items
+}
+
+// commitLookupCache invalidates the resources written by the rw's
+// transaction, which has committed.
+func (rw *RW) commitLookupCache(ctx context.Context) {
+	c, tx := rw.underlying.lookupCache, rw.underlying.lookupCacheTx
+	if c == nil || tx == nil {
+		return
+	}
+	if keys := tx.drain(); len(keys) > 0 {
+		c.invalidate(ctx, keys...)
+	}
+}
+
+// LRUCacheBackend is an in-memory CacheBackend with LRU eviction, which is the
+// default backend of a LookupCache.
+type LRUCacheBackend struct {
+	size int
+
+	mu      sync.Mutex
+	lru     *list.List // of *lruCacheEntry, most recently used at the front
+	entries map[string]*list.Element
+	nowFunc func() time.Time
+}
+
+type lruCacheEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+var _ CacheBackend = (*LRUCacheBackend)(nil)
+
+// NewLRUCacheBackend creates a new LRUCacheBackend which holds up to size
+// values.
+func NewLRUCacheBackend(size int) *LRUCacheBackend {
+	return &LRUCacheBackend{
+		size:    size,
+		lru:     list.New(),
+		entries: map[string]*list.Element{},
+		nowFunc: time.Now,
+	}
+}
+
+// Get returns the value for the key, if it's cached and hasn't expired
+func (b *LRUCacheBackend) Get(_ context.Context, key string) (interface{}, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	e, ok := b.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*lruCacheEntry)
+	if !b.nowFunc().Before(entry.expires) {
+		b.lru.Remove(e)
+		delete(b.entries, key)
+		return nil, false
+	}
+	b.lru.MoveToFront(e)
+	return entry.value, true
+}
+
+// Set caches the value for the key, which expires after the ttl.  The least
+// recently used value is evicted when the backend is full.
+func (b *LRUCacheBackend) Set(_ context.Context, key string, value interface{}, ttl time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	expires := b.nowFunc().Add(ttl)
+	if e, ok := b.entries[key]; ok {
+		entry := e.Value.(*lruCacheEntry)
+		entry.value, entry.expires = value, expires
+		b.lru.MoveToFront(e)
+		return
+	}
+	b.entries[key] = b.lru.PushFront(&lruCacheEntry{key: key, value: value, expires: expires})
+	for b.lru.Len() > b.size {
+		oldest := b.lru.Back()
+		b.lru.Remove(oldest)
+		delete(b.entries, oldest.Value.(*lruCacheEntry).key)
+	}
+}
+
+// Delete removes the values for the keys
+func (b *LRUCacheBackend) Delete(_ context.Context, keys ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, k := range keys {
+		if e, ok := b.entries[k]; ok {
+			b.lru.Remove(e)
+			delete(b.entries, k)
+		}
+	}
+}
+
+// Len returns the number of cached values, including expired values which
+// haven't been evicted yet.
+func (b *LRUCacheBackend) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lru.Len()
+}
+
+// CacheOption - how options are passed as arguments to NewLookupCache(...)
+type CacheOption func(*cacheOptions)
+
+type cacheModelTTL struct {
+	model interface{}
+	ttl   time.Duration
+}
+
+type cacheOptions struct {
+	withCacheBackend   CacheBackend
+	withCacheSize      int
+	withCacheTTL       time.Duration
+	withCacheModelTTLs []cacheModelTTL
+}
+
+func getDefaultCacheOptions() cacheOptions {
+	return cacheOptions{
+		withCacheSize: DefaultLookupCacheSize,
+	}
+}
+
+func getCacheOpts(opt ...CacheOption) cacheOptions {
+	opts := getDefaultCacheOptions()
+	for _, o := range opt {
+		if o != nil {
+			o(&opts)
+		}
+	}
+	return opts
+}
+
+// WithCacheBackend provides an option to specify the cache's backend.  The
+// default is an LRUCacheBackend.
+func WithCacheBackend(b CacheBackend) CacheOption {
+	return func(o *cacheOptions) {
+		o.withCacheBackend = b
+	}
+}
+
+// WithCacheSize provides an option to specify the size of the default LRU
+// backend.  The default is DefaultLookupCacheSize.
+func WithCacheSize(size int) CacheOption {
+	return func(o *cacheOptions) {
+		o.withCacheSize = size
+	}
+}
+
+// WithCacheTTL provides an option to specify the TTL of the cached resources
+// for models without a TTL from WithCacheModelTTL(...).  The default is zero,
+// so only models with a TTL from WithCacheModelTTL(...) are cached.
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(o *cacheOptions) {
+		o.withCacheTTL = ttl
+	}
+}
+
+// WithCacheModelTTL provides an option to specify the TTL of the model's
+// cached resources.  A TTL of zero disables caching for the model.  The
+// option can be specified for several models.
+func WithCacheModelTTL(model interface{}, ttl time.Duration) CacheOption {
+	return func(o *cacheOptions) {
+		o.withCacheModelTTLs = append(o.withCacheModelTTLs, cacheModelTTL{model: model, ttl: ttl})
+	}
+}
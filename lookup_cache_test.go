@@ -0,0 +1,408 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCachedDB returns a DB with the lookup cache for a migrated sqlite test
+// database, which records its statements.
+func testCachedDB(t *testing.T, c *LookupCache) (*DB, *Recorder) {
+	t.Helper()
+	url := filepath.Join(t.TempDir(), "cached.db")
+	TestSetup(t, WithTestDatabaseUrl(url))
+	r := NewRecorder()
+	db, err := Open(Sqlite, url, WithLookupCache(c), WithRecorder(r))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close(context.Background()) })
+	return db, r
+}
+
+// TestEmbeddedUserStore is embedded by testEmbeddedUser
+type TestEmbeddedUserStore struct {
+	PublicId string `gorm:"primaryKey;default:null"`
+	Name     string `gorm:"default:null"`
+}
+
+// testEmbeddedUser is a db_test_user model which embeds a pointer to its
+// store, the same as the protobuf models.
+type testEmbeddedUser struct {
+	*TestEmbeddedUserStore
+}
+
+func (*testEmbeddedUser) TableName() string { return "db_test_user" }
+
+func TestLookupCache(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	newCache := func(t *testing.T, opt ...CacheOption) *LookupCache {
+		c, err := NewLookupCache(opt...)
+		require.NoError(t, err)
+		return c
+	}
+	// lookup the user and assert the number of db queries
+	lookup := func(t *testing.T, rw *RW, r *Recorder, publicId string, wantQueries int) *testUser {
+		t.Helper()
+		r.Reset()
+		u := &testUser{PublicId: publicId}
+		require.NoError(t, rw.LookupBy(testCtx, u))
+		AssertQueryCount(t, r, wantQueries)
+		return u
+	}
+
+	t.Run("cached", func(t *testing.T) {
+		assert := assert.New(t)
+		db, r := testCachedDB(t, newCache(t, WithCacheModelTTL(&testUser{}, time.Minute)))
+		rw := New(db)
+		u := testCreateUser(t, rw, "alice")
+
+		assert.Equal("alice", lookup(t, rw, r, u.PublicId, 1).Name)
+		assert.Equal("alice", lookup(t, rw, r, u.PublicId, 0).Name)
+
+		// lookups with a lock always read from the db
+		tx, err := rw.Begin(testCtx)
+		require.NoError(t, err)
+		defer func() { _ = tx.Rollback(testCtx) }()
+		r.Reset()
+		require.NoError(t, tx.LookupBy(testCtx, &testUser{PublicId: u.PublicId}, WithLock(ForUpdate)))
+		AssertQueryCount(t, r, 1)
+	})
+	t.Run("embedded-pointer", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		db, r := testCachedDB(t, newCache(t, WithCacheTTL(time.Minute)))
+		rw := New(db)
+		u := testCreateUser(t, rw, "alice")
+		lookupEmbedded := func(wantQueries int) *testEmbeddedUser {
+			t.Helper()
+			r.Reset()
+			e := &testEmbeddedUser{TestEmbeddedUserStore: &TestEmbeddedUserStore{PublicId: u.PublicId}}
+			require.NoError(rw.LookupBy(testCtx, e))
+			AssertQueryCount(t, r, wantQueries)
+			return e
+		}
+		a := lookupEmbedded(1)
+		// changing a lookup's result doesn't change the cached resource
+		a.Name = "changed"
+		b := lookupEmbedded(0)
+		assert.Equal("alice", b.Name)
+		// or the results of other lookups
+		b.Name = "changed again"
+		assert.Equal("changed", a.Name)
+		assert.Equal("alice", lookupEmbedded(0).Name)
+	})
+	t.Run("invalidated-after-miss", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		db, r := testCachedDB(t, newCache(t, WithCacheTTL(time.Minute)))
+		rw := New(db)
+		u := testCreateUser(t, rw, "alice")
+
+		// a lookup misses and reads the resource before it's updated
+		stale := &testUser{PublicId: u.PublicId}
+		hit, key, gen := rw.cachedLookup(testCtx, stale, Options{})
+		require.False(hit)
+		require.NotEmpty(key)
+		stale.Name = "alice"
+
+		u.Name = "updated"
+		_, err := rw.Update(testCtx, u, []string{"Name"}, nil, WithSkipVetForWrite(true))
+		require.NoError(err)
+
+		// the lookup's stale resource doesn't replace the updated resource
+		// cached by the update's lookup after its write
+		db.lookupCache.set(testCtx, key, gen, stale)
+		assert.Equal("updated", lookup(t, rw, r, u.PublicId, 0).Name)
+
+		// a set without an invalidation since the miss is cached
+		hit, key, gen = rw.cachedLookup(testCtx, &testUser{PublicId: "not-cached"}, Options{})
+		require.False(hit)
+		db.lookupCache.set(testCtx, key, gen, &testUser{PublicId: "not-cached", Name: "bob"})
+		got := &testUser{PublicId: "not-cached"}
+		assert.True(db.lookupCache.get(testCtx, key, got))
+		assert.Equal("bob", got.Name)
+	})
+	t.Run("model-ttl", func(t *testing.T) {
+		db, r := testCachedDB(t, newCache(t, WithCacheTTL(time.Minute), WithCacheModelTTL(&testUser{}, 0)))
+		rw := New(db)
+		u := testCreateUser(t, rw, "alice")
+		lookup(t, rw, r, u.PublicId, 1)
+		lookup(t, rw, r, u.PublicId, 1)
+	})
+	t.Run("update", func(t *testing.T) {
+		assert := assert.New(t)
+		db, r := testCachedDB(t, newCache(t, WithCacheTTL(time.Minute)))
+		rw := New(db)
+		u := testCreateUser(t, rw, "alice")
+		lookup(t, rw, r, u.PublicId, 1)
+
+		u.Name = "updated"
+		_, err := rw.Update(testCtx, u, []string{"Name"}, nil)
+		require.NoError(t, err)
+		// the update's lookup after write caches the updated resource
+		assert.Equal("updated", lookup(t, rw, r, u.PublicId, 0).Name)
+	})
+	t.Run("delete", func(t *testing.T) {
+		db, r := testCachedDB(t, newCache(t, WithCacheTTL(time.Minute)))
+		rw := New(db)
+		u := testCreateUser(t, rw, "alice")
+		lookup(t, rw, r, u.PublicId, 1)
+
+		_, err := rw.Delete(testCtx, &testUser{PublicId: u.PublicId})
+		require.NoError(t, err)
+		err = rw.LookupBy(testCtx, &testUser{PublicId: u.PublicId})
+		assert.ErrorIs(t, err, ErrRecordNotFound)
+	})
+	t.Run("delete-items", func(t *testing.T) {
+		db, r := testCachedDB(t, newCache(t, WithCacheTTL(time.Minute)))
+		rw := New(db)
+		u1, u2 := testCreateUser(t, rw, "alice"), testCreateUser(t, rw, "bob")
+		lookup(t, rw, r, u1.PublicId, 1)
+		lookup(t, rw, r, u2.PublicId, 1)
+
+		_, err := rw.DeleteItems(testCtx, []*testUser{{PublicId: u1.PublicId}, {PublicId: u2.PublicId}})
+		require.NoError(t, err)
+		for _, id := range []string{u1.PublicId, u2.PublicId} {
+			err = rw.LookupBy(testCtx, &testUser{PublicId: id})
+			assert.ErrorIs(t, err, ErrRecordNotFound)
+		}
+	})
+	t.Run("update-where", func(t *testing.T) {
+		assert := assert.New(t)
+		db, r := testCachedDB(t, newCache(t, WithCacheTTL(time.Minute)))
+		rw := New(db)
+		u1, u2 := testCreateUser(t, rw, "alice"), testCreateUser(t, rw, "bob")
+		lookup(t, rw, r, u1.PublicId, 1)
+		lookup(t, rw, r, u2.PublicId, 1)
+
+		_, err := rw.UpdateWhere(testCtx, &testUser{}, SetColumnValues(map[string]interface{}{"email": "alice@example.com"}), "name = ?", []interface{}{"alice"})
+		require.NoError(t, err)
+		assert.Equal("alice@example.com", lookup(t, rw, r, u1.PublicId, 1).Email)
+		lookup(t, rw, r, u2.PublicId, 0)
+	})
+	t.Run("delete-where", func(t *testing.T) {
+		db, r := testCachedDB(t, newCache(t, WithCacheTTL(time.Minute)))
+		rw := New(db)
+		u := testCreateUser(t, rw, "alice")
+		lookup(t, rw, r, u.PublicId, 1)
+
+		_, err := rw.DeleteWhere(testCtx, &testUser{}, "name = ?", []interface{}{"alice"})
+		require.NoError(t, err)
+		err = rw.LookupBy(testCtx, &testUser{PublicId: u.PublicId})
+		assert.ErrorIs(t, err, ErrRecordNotFound)
+	})
+	t.Run("create-items-on-conflict", func(t *testing.T) {
+		assert := assert.New(t)
+		db, r := testCachedDB(t, newCache(t, WithCacheTTL(time.Minute)))
+		rw := New(db)
+		u := testCreateUser(t, rw, "alice")
+		lookup(t, rw, r, u.PublicId, 1)
+
+		// on conflict do nothing doesn't invalidate the cache
+		onConflict := &OnConflict{Target: Columns{"public_id"}, Action: DoNothing(true)}
+		err := rw.CreateItems(testCtx, []*testUser{{PublicId: u.PublicId, Name: "ignored"}}, WithOnConflict(onConflict))
+		require.NoError(t, err)
+		lookup(t, rw, r, u.PublicId, 0)
+
+		onConflict = &OnConflict{Target: Columns{"public_id"}, Action: SetColumns([]string{"name"})}
+		err = rw.CreateItems(testCtx, []*testUser{{PublicId: u.PublicId, Name: "upserted"}}, WithOnConflict(onConflict))
+		require.NoError(t, err)
+		assert.Equal("upserted", lookup(t, rw, r, u.PublicId, 1).Name)
+	})
+	t.Run("do-tx", func(t *testing.T) {
+		assert := assert.New(t)
+		db, r := testCachedDB(t, newCache(t, WithCacheTTL(time.Minute)))
+		rw := New(db)
+		u := testCreateUser(t, rw, "alice")
+		lookup(t, rw, r, u.PublicId, 1)
+
+		update := func(name string, fail bool) error {
+			_, err := rw.DoTx(testCtx, func(error) bool { return false }, 0, ExpBackoff{}, func(_ Reader, w Writer) error {
+				txRW := w.(*RW)
+				// reads before the tx writes the resource use the cache
+				assert.Equal("alice", lookup(t, txRW, r, u.PublicId, 0).Name)
+				updated := &testUser{PublicId: u.PublicId, Name: name}
+				if _, err := w.Update(testCtx, updated, []string{"Name"}, nil); err != nil {
+					return err
+				}
+				// the tx reads its own writes
+				assert.Equal(name, lookup(t, txRW, r, u.PublicId, 1).Name)
+				if fail {
+					return errors.New("rollback")
+				}
+				return nil
+			})
+			return err
+		}
+		// a rolled back write doesn't invalidate the cache
+		require.Error(t, update("rolled-back", true))
+		assert.Equal("alice", lookup(t, rw, r, u.PublicId, 0).Name)
+
+		require.NoError(t, update("committed", false))
+		assert.Equal("committed", lookup(t, rw, r, u.PublicId, 1).Name)
+	})
+	t.Run("begin-commit", func(t *testing.T) {
+		assert := assert.New(t)
+		db, r := testCachedDB(t, newCache(t, WithCacheTTL(time.Minute)))
+		rw := New(db)
+		u := testCreateUser(t, rw, "alice")
+		lookup(t, rw, r, u.PublicId, 1)
+
+		tx, err := rw.Begin(testCtx)
+		require.NoError(t, err)
+		_, err = tx.Update(testCtx, &testUser{PublicId: u.PublicId, Name: "committed"}, []string{"Name"}, nil)
+		require.NoError(t, err)
+		// invalidation is deferred until the tx commits
+		assert.Equal("alice", lookup(t, rw, r, u.PublicId, 0).Name)
+		require.NoError(t, tx.Commit(testCtx))
+		assert.Equal("committed", lookup(t, rw, r, u.PublicId, 1).Name)
+	})
+}
+
+func Test_deepCopy(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	type node struct {
+		Name     string
+		Next     *node
+		Tags     []string
+		Labels   map[string]*string
+		Value    interface{}
+		internal *string
+	}
+	label, internal := "label", "internal"
+	n := &node{
+		Name:     "n",
+		Tags:     []string{"a"},
+		Labels:   map[string]*string{"l": &label},
+		Value:    &TestEmbeddedUserStore{Name: "v"},
+		internal: &internal,
+	}
+	n.Next = n
+
+	c := deepCopy(reflect.ValueOf(n), map[deepCopyKey]reflect.Value{}).Interface().(*node)
+	assert.Equal(n.Name, c.Name)
+	assert.NotSame(n, c)
+	// cycles are preserved
+	assert.Same(c, c.Next)
+	assert.Equal(n.Tags, c.Tags)
+	c.Tags[0] = "b"
+	assert.Equal("a", n.Tags[0])
+	assert.NotSame(n.Labels["l"], c.Labels["l"])
+	assert.Equal(label, *c.Labels["l"])
+	assert.NotSame(n.Value, c.Value)
+	assert.Equal(n.Value, c.Value)
+	// unexported fields are shallow copies
+	assert.Same(n.internal, c.internal)
+
+	var nilNode *node
+	assert.Nil(deepCopy(reflect.ValueOf(nilNode), map[deepCopyKey]reflect.Value{}).Interface())
+}
+
+func TestNewLookupCache(t *testing.T) {
+	t.Parallel()
+	backend := NewLRUCacheBackend(1)
+	tests := []struct {
+		name            string
+		opts            []CacheOption
+		want            *LookupCache
+		wantErrContains string
+	}{
+		{
+			name: "defaults",
+			want: &LookupCache{
+				backend:   NewLRUCacheBackend(DefaultLookupCacheSize),
+				modelTTLs: map[reflect.Type]time.Duration{},
+			},
+		},
+		{
+			name: "with-options",
+			opts: []CacheOption{
+				WithCacheBackend(backend),
+				WithCacheTTL(time.Second),
+				WithCacheModelTTL(&testUser{}, time.Minute),
+			},
+			want: &LookupCache{
+				backend:    backend,
+				defaultTTL: time.Second,
+				modelTTLs:  map[reflect.Type]time.Duration{reflect.TypeOf(testUser{}): time.Minute},
+			},
+		},
+		{
+			name:            "negative-ttl",
+			opts:            []CacheOption{WithCacheTTL(-time.Second)},
+			wantErrContains: "negative ttl",
+		},
+		{
+			name:            "invalid-size",
+			opts:            []CacheOption{WithCacheSize(0)},
+			wantErrContains: "cache size must be greater than zero",
+		},
+		{
+			name:            "invalid-model",
+			opts:            []CacheOption{WithCacheModelTTL("user", time.Minute)},
+			wantErrContains: "model string is not a struct",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert, require := assert.New(t), require.New(t)
+			got, err := NewLookupCache(tt.opts...)
+			if tt.wantErrContains != "" {
+				require.Error(err)
+				assert.ErrorIs(err, ErrInvalidParameter)
+				assert.ErrorContains(err, tt.wantErrContains)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tt.want.defaultTTL, got.defaultTTL)
+			assert.Equal(tt.want.modelTTLs, got.modelTTLs)
+			assert.IsType(tt.want.backend, got.backend)
+		})
+	}
+}
+
+func TestLRUCacheBackend(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	t.Run("eviction", func(t *testing.T) {
+		assert := assert.New(t)
+		b := NewLRUCacheBackend(2)
+		b.Set(testCtx, "a", 1, time.Minute)
+		b.Set(testCtx, "b", 2, time.Minute)
+		// a is the most recently used
+		_, ok := b.Get(testCtx, "a")
+		assert.True(ok)
+		b.Set(testCtx, "c", 3, time.Minute)
+		assert.Equal(2, b.Len())
+		_, ok = b.Get(testCtx, "b")
+		assert.False(ok)
+		v, ok := b.Get(testCtx, "a")
+		assert.True(ok)
+		assert.Equal(1, v)
+
+		b.Delete(testCtx, "a", "c", "missing")
+		assert.Equal(0, b.Len())
+	})
+	t.Run("ttl", func(t *testing.T) {
+		assert := assert.New(t)
+		now := time.Now()
+		b := NewLRUCacheBackend(2)
+		b.nowFunc = func() time.Time { return now }
+		b.Set(testCtx, "a", 1, time.Minute)
+		_, ok := b.Get(testCtx, "a")
+		assert.True(ok)
+		now = now.Add(time.Minute)
+		_, ok = b.Get(testCtx, "a")
+		assert.False(ok)
+		assert.Equal(0, b.Len())
+	})
+}
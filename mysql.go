@@ -0,0 +1,116 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm/clause"
+)
+
+// mysqlDialect is the MySQL dialect, which includes MariaDB
+type mysqlDialect struct{}
+
+var (
+	_ Dialect           = mysqlDialect{}
+	_ SavepointReleaser = mysqlDialect{}
+)
+
+// Name returns "mysql"
+func (mysqlDialect) Name() string { return "mysql" }
+
+// Quote returns `identifier`
+func (mysqlDialect) Quote(identifier string) string {
+	return quoteIdentifier(identifier, "`")
+}
+
+// Excluded returns values(column), which is the column's value proposed for
+// insertion by "on duplicate key update"
+func (mysqlDialect) Excluded(column string) interface{} {
+	return clause.Expr{SQL: "VALUES(?)", Vars: []interface{}{clause.Column{Name: column}}}
+}
+
+// OnConflict validates the clause for "on duplicate key update", which
+// doesn't support a constraint target or a where clause.  The target's
+// columns are ignored, since any unique key conflict results in the update.
+func (d mysqlDialect) OnConflict(c *clause.OnConflict, _ []string) error {
+	const op = "dbw.(mysqlDialect).OnConflict"
+	switch {
+	case c.OnConstraint != "":
+		return fmt.Errorf("%s: %s doesn't support an on conflict constraint target: %w", op, d.Name(), ErrInvalidParameter)
+	case len(c.Where.Exprs) > 0 || len(c.TargetWhere.Exprs) > 0:
+		return fmt.Errorf("%s: %s doesn't support on conflict with a version or where clause: %w", op, d.Name(), ErrInvalidParameter)
+	}
+	return nil
+}
+
+// Locking returns a "for" locking clause, which supports the ForUpdate and
+// ForShare strengths
+func (d mysqlDialect) Locking(lock RowLock) (clause.Expression, error) {
+	const op = "dbw.(mysqlDialect).Locking"
+	switch lock.Strength {
+	case ForNoKeyUpdate, ForKeyShare:
+		return nil, fmt.Errorf("%s: lock strength %q is not supported by %s: %w", op, lock.Strength, d.Name(), ErrInvalidParameter)
+	}
+	return forLocking(lock), nil
+}
+
+// SupportsReturning returns false
+func (mysqlDialect) SupportsReturning() bool { return false }
+
+// ReleaseSavepoint returns "release savepoint name"
+func (mysqlDialect) ReleaseSavepoint(name string) string { return releaseSavepoint(name) }
+
+// IsAuthError returns true for ER_ACCESS_DENIED_ERROR errors
+func (mysqlDialect) IsAuthError(err error) bool {
+	return isMysqlError(err, mysqlAccessDenied)
+}
+
+// IsStaleStmtError returns true for ER_NEED_REPREPARE errors
+func (mysqlDialect) IsStaleStmtError(err error) bool {
+	return isMysqlError(err, mysqlNeedReprepare)
+}
+
+// IsStatementTimeout returns true when a statement exceeded its
+// max_execution_time
+func (mysqlDialect) IsStatementTimeout(err error) bool {
+	return isMysqlError(err, mysqlQueryTimeout)
+}
+
+// IsBusyError returns false
+func (mysqlDialect) IsBusyError(error) bool { return false }
+
+// TestSchema returns the mysql test schema
+func (mysqlDialect) TestSchema() (create, drop []string) {
+	return testQueriesCreateTablesMysql, []string{testQueryDropTablesMysql}
+}
+
+const (
+	// mysqlAccessDenied is ER_ACCESS_DENIED_ERROR
+	mysqlAccessDenied uint16 = 1045
+
+	// mysqlNeedReprepare is ER_NEED_REPREPARE
+	mysqlNeedReprepare uint16 = 1615
+
+	// mysqlQueryTimeout is ER_QUERY_TIMEOUT, which is returned when a
+	// statement exceeds its max_execution_time
+	mysqlQueryTimeout uint16 = 3024
+)
+
+// isMysqlError returns true when the error is a mysql error with one of the
+// error numbers
+func isMysqlError(err error, numbers ...uint16) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	for _, n := range numbers {
+		if mysqlErr.Number == n {
+			return true
+		}
+	}
+	return false
+}
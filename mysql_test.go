@@ -0,0 +1,91 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gormMysql "gorm.io/driver/mysql"
+)
+
+// testSetupMysqlMock returns a mysql DB which uses a sqlmock connection
+func testSetupMysqlMock(t *testing.T) (*DB, sqlmock.Sqlmock) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenWith(gormMysql.New(gormMysql.Config{Conn: mockDB, SkipInitializeWithVersion: true}))
+	require.NoError(t, err)
+	return db, mock
+}
+
+func TestMysql_Dialect(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	t.Run("db-type", func(t *testing.T) {
+		db, _ := testSetupMysqlMock(t)
+		typ, rawName, err := New(db).Dialect()
+		require.NoError(t, err)
+		assert.Equal(t, MySQL, typ)
+		assert.Equal(t, "mysql", rawName)
+		assert.Equal(t, "`public_id`", db.dialect().Quote("public_id"))
+	})
+	t.Run("upsert", func(t *testing.T) {
+		require := require.New(t)
+		db, mock := testSetupMysqlMock(t)
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `db_test_user` .* ON DUPLICATE KEY UPDATE `name`=VALUES\\(`name`\\)").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		u := &testUser{PublicId: "u_1234567890", Name: "alice"}
+		err := New(db).Create(testCtx, u, WithOnConflict(&OnConflict{Target: Columns{"public_id"}, Action: SetColumns([]string{"name"})}))
+		require.NoError(err)
+		require.NoError(mock.ExpectationsWereMet())
+	})
+	t.Run("upsert-unsupported", func(t *testing.T) {
+		db, _ := testSetupMysqlMock(t)
+		rw := New(db)
+		u := &testUser{PublicId: "u_1234567890", Name: "alice"}
+		err := rw.Create(testCtx, u, WithOnConflict(&OnConflict{Target: Constraint("db_test_user_pkey"), Action: DoNothing(true)}))
+		assert.ErrorIs(t, err, ErrInvalidParameter)
+		err = rw.Create(testCtx, u, WithOnConflict(&OnConflict{Target: Columns{"public_id"}, Action: UpdateAll(true)}), WithVersion(func() *uint32 { v := uint32(1); return &v }()))
+		assert.ErrorIs(t, err, ErrInvalidParameter)
+		err = rw.CreateItems(testCtx, []*testUser{u}, WithOnConflict(&OnConflict{Target: Columns{"public_id"}, Action: UpdateAll(true)}), WithWhere("name = ?", "alice"))
+		assert.ErrorIs(t, err, ErrInvalidParameter)
+	})
+	t.Run("lock", func(t *testing.T) {
+		require := require.New(t)
+		db, mock := testSetupMysqlMock(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT \\* FROM `db_test_user` WHERE `public_id` = \\? .* FOR UPDATE SKIP LOCKED").
+			WillReturnRows(sqlmock.NewRows([]string{"public_id"}).AddRow("u_1234567890"))
+		tx, err := New(db).Begin(testCtx)
+		require.NoError(err)
+		require.NoError(tx.LookupBy(testCtx, &testUser{PublicId: "u_1234567890"}, WithLock(ForUpdate, SkipLocked)))
+		require.NoError(mock.ExpectationsWereMet())
+
+		err = tx.LookupBy(testCtx, &testUser{PublicId: "u_1234567890"}, WithLock(ForNoKeyUpdate))
+		assert.ErrorIs(t, err, ErrInvalidParameter)
+	})
+}
+
+func Test_isMysqlError(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	accessDenied := &mysql.MySQLError{Number: mysqlAccessDenied}
+	assert.True(isMysqlError(fmt.Errorf("wrapped: %w", accessDenied), mysqlAccessDenied))
+	assert.False(isMysqlError(accessDenied, mysqlNeedReprepare))
+	assert.False(isMysqlError(fmt.Errorf("other"), mysqlAccessDenied))
+
+	d := mysqlDialect{}
+	assert.True(d.IsAuthError(accessDenied))
+	assert.True(d.IsStaleStmtError(&mysql.MySQLError{Number: mysqlNeedReprepare}))
+	assert.True(d.IsStatementTimeout(&mysql.MySQLError{Number: mysqlQueryTimeout}))
+	assert.False(d.IsStatementTimeout(accessDenied))
+}
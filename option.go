@@ -4,6 +4,8 @@
 package dbw
 
 import (
+	"time"
+
 	"github.com/hashicorp/go-hclog"
 )
 
@@ -90,6 +92,22 @@ type Options struct {
 	// database.  A value of zero equals unlimited connections
 	WithMinOpenConnections int
 
+	// WithMaxIdleConnections specifies an optional max idle connections for
+	// the database.
+	WithMaxIdleConnections *int
+
+	// WithConnMaxLifetime specifies an optional max amount of time a
+	// connection may be reused.
+	WithConnMaxLifetime time.Duration
+
+	// WithConnMaxIdleTime specifies an optional max amount of time a
+	// connection may be idle.
+	WithConnMaxIdleTime time.Duration
+
+	// WithConnKeepAliveInterval specifies an optional interval for keeping
+	// the min open connections alive.
+	WithConnKeepAliveInterval time.Duration
+
 	// WithDebug indicates that the given operation should invoke debug output
 	// mode
 	WithDebug bool
@@ -111,6 +129,74 @@ type Options struct {
 	// operations. If WithBatchSize == 0, then the default batch size is used.
 	WithBatchSize int
 
+	// WithLockExpiration specifies an option for setting the expiration of a
+	// lock acquired via DB.Lock(...) or DB.TryLock(...)
+	WithLockExpiration time.Duration
+
+	// WithLock specifies an option for a pessimistic row lock for read
+	// operations.
+	WithLock *RowLock
+
+	// WithNowFunc specifies an option for the func used to get the current
+	// time for managed timestamp fields.
+	WithNowFunc func() time.Time
+
+	// WithIdGenerator specifies an option for the IdGenerator used to
+	// generate and parse IDs.
+	WithIdGenerator IdGenerator
+
+	// WithGenerateId specifies an option for the prefix of an ID generated
+	// for a resource with an empty primary key on create.
+	WithGenerateId string
+
+	// WithRecorder specifies an option for a Recorder which captures every
+	// statement executed by a DB.
+	WithRecorder *Recorder
+
+	// WithPreparedStatements specifies an option for the size of a DB's
+	// prepared statement cache.
+	WithPreparedStatements int
+
+	// WithLookupCache specifies an option for a LookupCache which caches the
+	// resources returned by lookups.
+	WithLookupCache *LookupCache
+
+	// WithTimeout specifies an option for the timeout of an operation, or
+	// the default timeout of every operation when opening a DB.
+	WithTimeout time.Duration
+
+	// WithSqliteJournalMode specifies an option for the journal mode of a
+	// sqlite database.
+	WithSqliteJournalMode SqliteJournalMode
+
+	// WithSqliteBusyTimeout specifies an option for how long a sqlite
+	// connection waits for a lock before returning SQLITE_BUSY.
+	WithSqliteBusyTimeout time.Duration
+
+	// WithSqliteSynchronous specifies an option for the synchronous level of
+	// a sqlite database.
+	WithSqliteSynchronous SqliteSynchronous
+
+	// WithSqliteSingleWriter specifies an option for a sqlite database to
+	// use a single writer connection and a separate pool of readers.
+	WithSqliteSingleWriter bool
+
+	// WithSqliteBusyRetries specifies an option for the number of times a
+	// sqlite statement is retried when it fails with SQLITE_BUSY.
+	WithSqliteBusyRetries uint
+
+	// WithReturning specifies an option for the columns returned by a write
+	// operation.  An empty (but not nil) slice returns every column.
+	WithReturning []string
+
+	// WithAllRows specifies an option for UpdateWhere(...) and
+	// DeleteWhere(...) to write every row when the where clause is empty.
+	WithAllRows bool
+
+	// WithItemResults specifies an option for a bulk write to continue past
+	// failed items and return the result of each item.
+	WithItemResults *[]ItemResult
+
 	withLogLevel LogLevel
 }
 
@@ -119,6 +205,7 @@ func getDefaultOptions() Options {
 		WithFieldMaskPaths: []string{},
 		WithNullPaths:      []string{},
 		WithBatchSize:      DefaultBatchSize,
+		WithLockExpiration: DefaultLockExpiration,
 		withLogLevel:       Error,
 	}
 }
@@ -234,13 +321,52 @@ func WithMaxOpenConnections(max int) Option {
 }
 
 // WithMinOpenConnections specifies and optional min open connections for the
-// database.  A value of zero means that there is no min.
+// database.  A value of zero means that there is no min.  The min open
+// connections are dialed when the database is opened, and they're kept alive
+// by pinging them every keep-alive interval (see WithConnKeepAliveInterval).
 func WithMinOpenConnections(max int) Option {
 	return func(o *Options) {
 		o.WithMinOpenConnections = max
 	}
 }
 
+// WithMaxIdleConnections specifies an optional max idle connections for the
+// database, which must be at least the min open connections.  A value of zero
+// means that no idle connections are kept.  The default is the greater of the
+// min open connections and the database/sql default of 2.
+func WithMaxIdleConnections(max int) Option {
+	return func(o *Options) {
+		o.WithMaxIdleConnections = &max
+	}
+}
+
+// WithConnMaxLifetime specifies an optional max amount of time a connection
+// may be reused.  A value of zero means connections are reused forever.
+func WithConnMaxLifetime(d time.Duration) Option {
+	return func(o *Options) {
+		o.WithConnMaxLifetime = d
+	}
+}
+
+// WithConnMaxIdleTime specifies an optional max amount of time a connection
+// may be idle before it's closed.  A value of zero means connections aren't
+// closed because they're idle.
+func WithConnMaxIdleTime(d time.Duration) Option {
+	return func(o *Options) {
+		o.WithConnMaxIdleTime = d
+	}
+}
+
+// WithConnKeepAliveInterval specifies an optional interval for keeping the
+// min open connections alive (see WithMinOpenConnections), which should be
+// shorter than the connection max idle time.  The default is
+// DefaultConnKeepAliveInterval.
+func WithConnKeepAliveInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.WithConnKeepAliveInterval = d
+	}
+}
+
 // WithDebug specifies the given operation should invoke debug mode for the
 // database output
 func WithDebug(with bool) Option {
@@ -289,3 +415,202 @@ func WithBatchSize(size int) Option {
 		o.WithBatchSize = size
 	}
 }
+
+// WithLockExpiration specifies an option for setting the expiration of a lock
+// acquired via DB.Lock(...) or DB.TryLock(...). The lock's session is checked
+// at an interval of a third of the expiration. For sqlite, the lock expires
+// if it's not refreshed by the session holding it within the expiration. If
+// WithLockExpiration isn't specified, the default expiration is used (see
+// DefaultLockExpiration const).
+func WithLockExpiration(d time.Duration) Option {
+	return func(o *Options) {
+		o.WithLockExpiration = d
+	}
+}
+
+// WithLock specifies an option for a pessimistic row lock (select ... for
+// update, etc) for the LookupBy, LookupByPublicId, LookupWhere and SearchWhere
+// read operations. An optional LockWait can be specified for NoWait or
+// SkipLocked behavior. The lock is held until the transaction completes, so
+// it's only valid within a transaction (see RW.IsTx()).
+//
+// Sqlite doesn't have row locks, so no locking clause is issued for it.
+// Instead, sqlite serializes write transactions, so the transaction's first
+// write will wait (see busy_timeout) or fail if another transaction is
+// writing. NoWait and SkipLocked have no effect for sqlite.
+func WithLock(strength LockStrength, wait ...LockWait) Option {
+	return func(o *Options) {
+		l := &RowLock{Strength: strength}
+		if len(wait) > 0 {
+			l.Wait = wait[0]
+		}
+		o.WithLock = l
+	}
+}
+
+// WithNowFunc specifies an optional func for getting the current time, which
+// is used to set managed create/update timestamp fields (see
+// InitCreateTimestampFields and InitUpdateTimestampFields). It's useful for
+// injecting a clock in tests and is only valid for Open(...) and
+// OpenWith(...). The default is time.Now
+func WithNowFunc(fn func() time.Time) Option {
+	return func(o *Options) {
+		o.WithNowFunc = fn
+	}
+}
+
+// WithIdGenerator specifies an optional IdGenerator. When used with Open(...)
+// and OpenWith(...), it's the generator used for WithGenerateId(...) and it
+// can also be used to override that generator for a single Create(...) or
+// CreateItems(...). It's also supported by NewId(...) and ParseId(...). The
+// default is RandomIdGenerator.
+func WithIdGenerator(g IdGenerator) Option {
+	return func(o *Options) {
+		o.WithIdGenerator = g
+	}
+}
+
+// WithGenerateId specifies an optional prefix which Create(...) and
+// CreateItems(...) use to generate an ID for resources with an empty primary
+// key. The resource must have a single string primary key and the ID is
+// generated by the db's IdGenerator (see WithIdGenerator).
+func WithGenerateId(prefix string) Option {
+	return func(o *Options) {
+		o.WithGenerateId = prefix
+	}
+}
+
+// WithRecorder provides an option to capture every statement executed by the
+// DB with a Recorder.  This option is only supported by Open(...) and
+// OpenWith(...)
+func WithRecorder(r *Recorder) Option {
+	return func(o *Options) {
+		o.WithRecorder = r
+	}
+}
+
+// WithPreparedStatements provides an option to execute statements using
+// prepared statements from an LRU cache of the given size.  Transactions
+// reuse the cached statements, and a cached statement is evicted when it
+// fails because the schema changed.  A size of 0 disables the cache (the
+// default).  This option is only supported by Open(...) and OpenWith(...)
+func WithPreparedStatements(size int) Option {
+	return func(o *Options) {
+		o.WithPreparedStatements = size
+	}
+}
+
+// WithLookupCache provides an option to cache the resources returned by
+// LookupBy(...) and LookupByPublicId(...) in a LookupCache.  This option is
+// only supported by Open(...) and OpenWith(...)
+func WithLookupCache(c *LookupCache) Option {
+	return func(o *Options) {
+		o.WithLookupCache = c
+	}
+}
+
+// WithTimeout provides an option for the timeout of an RW operation.  The
+// operation uses a child context of the caller's context with the timeout, and
+// for Postgres, the statement_timeout is also set for the operation's
+// statements, so the server cancels the work.  ErrTimeout is returned when the
+// timeout is exceeded, which is distinct from the caller's context being
+// canceled or exceeding its deadline.  When used with Open(...) or
+// OpenWith(...), it's the default timeout of every RW operation which doesn't
+// have its own timeout, except Query(...) which doesn't support timeouts.  A
+// value of zero means there's no timeout (the default).  For Postgres,
+// setting and resetting the statement_timeout costs two round trips per
+// operation, and outside of a transaction the operation uses a dedicated
+// connection, which doesn't use WithPreparedStatements.
+func WithTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.WithTimeout = d
+	}
+}
+
+// WithSqliteJournalMode provides an option for the journal mode of a sqlite
+// database (PRAGMA journal_mode), which is typically SqliteJournalWal in
+// production.  This option is only supported by Open(...) and
+// OpenWithCredentials(...)
+func WithSqliteJournalMode(mode SqliteJournalMode) Option {
+	return func(o *Options) {
+		o.WithSqliteJournalMode = mode
+	}
+}
+
+// WithSqliteBusyTimeout provides an option for how long a sqlite connection
+// waits for a lock held by another connection before returning SQLITE_BUSY
+// (PRAGMA busy_timeout).  This option is only supported by Open(...) and
+// OpenWithCredentials(...)
+func WithSqliteBusyTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.WithSqliteBusyTimeout = d
+	}
+}
+
+// WithSqliteSynchronous provides an option for the synchronous level of a
+// sqlite database (PRAGMA synchronous).  This option is only supported by
+// Open(...) and OpenWithCredentials(...)
+func WithSqliteSynchronous(level SqliteSynchronous) Option {
+	return func(o *Options) {
+		o.WithSqliteSynchronous = level
+	}
+}
+
+// WithSqliteSingleWriter provides an option for a sqlite database to use a
+// single writer connection, which executes writes and transactions, and a
+// separate pool of read-only connections for reads outside of transactions.
+// Sqlite only allows one writer at a time, so this avoids SQLITE_BUSY errors
+// between the pool's connections.  The pool options (like
+// WithMaxOpenConnections) configure the readers' pool.  It's typically used
+// with SqliteJournalWal, so reads aren't blocked by writes.  It requires a
+// database file, since each connection to an in-memory database opens a
+// separate database, so ErrInvalidParameter is returned for in-memory
+// databases.  This option is
+// only supported by Open(...) and OpenWithCredentials(...)
+func WithSqliteSingleWriter(enable bool) Option {
+	return func(o *Options) {
+		o.WithSqliteSingleWriter = enable
+	}
+}
+
+// WithSqliteBusyRetries provides an option for the number of times a sqlite
+// statement outside of a transaction is retried with an exponential backoff,
+// when it fails with SQLITE_BUSY.  This option is only supported by Open(...)
+// and OpenWithCredentials(...)
+func WithSqliteBusyRetries(retries uint) Option {
+	return func(o *Options) {
+		o.WithSqliteBusyRetries = retries
+	}
+}
+
+// WithReturning provides an option to append a "returning" clause to a write
+// operation, which scans the columns' values into the resource(s) as they were
+// written, including those set by defaults and triggers.  When no columns are
+// specified, every column is returned.  This avoids the extra query of
+// WithLookup, and it's supported by Create(...), CreateItems(...) and
+// Update(...) for Postgres and Sqlite (3.35+).  ErrInvalidParameter is returned
+// when the database's dialect doesn't support "returning" clauses.
+func WithReturning(columns ...string) Option {
+	return func(o *Options) {
+		o.WithReturning = append([]string{}, columns...)
+	}
+}
+
+// WithAllRows provides an option for UpdateWhere(...) and DeleteWhere(...) to
+// update or delete every row of the table when the where clause is empty,
+// which is otherwise refused.
+func WithAllRows(enable bool) Option {
+	return func(o *Options) {
+		o.WithAllRows = enable
+	}
+}
+
+// WithItemResults provides an option for CreateItems(...) and DeleteItems(...)
+// to write the items on a best effort basis: items which fail are skipped
+// rather than failing the whole operation, and the result of each item is
+// returned in results.  See ItemResult
+func WithItemResults(results *[]ItemResult) Option {
+	return func(o *Options) {
+		o.WithItemResults = results
+	}
+}
@@ -5,9 +5,11 @@ package dbw
 
 import (
 	"testing"
+	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Test_getOpts provides unit tests for GetOpts and all the options
@@ -266,4 +268,181 @@ func Test_getOpts(t *testing.T) {
 		testOpts.WithBatchSize = 100
 		assert.Equal(opts, testOpts)
 	})
+	t.Run("WithLockExpiration", func(t *testing.T) {
+		assert := assert.New(t)
+		// test default
+		opts := GetOpts()
+		testOpts := getDefaultOptions()
+		testOpts.WithLockExpiration = DefaultLockExpiration
+		assert.Equal(opts, testOpts)
+
+		opts = GetOpts(WithLockExpiration(time.Second))
+		testOpts = getDefaultOptions()
+		testOpts.WithLockExpiration = time.Second
+		assert.Equal(opts, testOpts)
+	})
+	t.Run("WithLock", func(t *testing.T) {
+		assert := assert.New(t)
+		// test default
+		opts := GetOpts()
+		testOpts := getDefaultOptions()
+		testOpts.WithLock = nil
+		assert.Equal(opts, testOpts)
+
+		opts = GetOpts(WithLock(ForUpdate))
+		testOpts = getDefaultOptions()
+		testOpts.WithLock = &RowLock{Strength: ForUpdate}
+		assert.Equal(opts, testOpts)
+
+		opts = GetOpts(WithLock(ForShare, SkipLocked))
+		testOpts = getDefaultOptions()
+		testOpts.WithLock = &RowLock{Strength: ForShare, Wait: SkipLocked}
+		assert.Equal(opts, testOpts)
+	})
+	t.Run("WithNowFunc", func(t *testing.T) {
+		assert := assert.New(t)
+		// test default
+		opts := GetOpts()
+		assert.Nil(opts.WithNowFunc)
+
+		now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+		opts = GetOpts(WithNowFunc(func() time.Time { return now }))
+		if assert.NotNil(opts.WithNowFunc) {
+			assert.Equal(now, opts.WithNowFunc())
+		}
+	})
+	t.Run("WithIdGenerator", func(t *testing.T) {
+		assert := assert.New(t)
+		// test default
+		opts := GetOpts()
+		testOpts := getDefaultOptions()
+		testOpts.WithIdGenerator = nil
+		assert.Equal(opts, testOpts)
+
+		opts = GetOpts(WithIdGenerator(ULIDGenerator{}))
+		testOpts = getDefaultOptions()
+		testOpts.WithIdGenerator = ULIDGenerator{}
+		assert.Equal(opts, testOpts)
+	})
+	t.Run("WithGenerateId", func(t *testing.T) {
+		assert := assert.New(t)
+		// test default
+		opts := GetOpts()
+		testOpts := getDefaultOptions()
+		testOpts.WithGenerateId = ""
+		assert.Equal(opts, testOpts)
+
+		opts = GetOpts(WithGenerateId("u"))
+		testOpts = getDefaultOptions()
+		testOpts.WithGenerateId = "u"
+		assert.Equal(opts, testOpts)
+	})
+	t.Run("WithRecorder", func(t *testing.T) {
+		assert := assert.New(t)
+		// test default
+		opts := GetOpts()
+		testOpts := getDefaultOptions()
+		testOpts.WithRecorder = nil
+		assert.Equal(opts, testOpts)
+
+		r := NewRecorder()
+		opts = GetOpts(WithRecorder(r))
+		testOpts = getDefaultOptions()
+		testOpts.WithRecorder = r
+		assert.Equal(opts, testOpts)
+	})
+	t.Run("WithPreparedStatements", func(t *testing.T) {
+		assert := assert.New(t)
+		// test default
+		opts := GetOpts()
+		testOpts := getDefaultOptions()
+		testOpts.WithPreparedStatements = 0
+		assert.Equal(opts, testOpts)
+
+		opts = GetOpts(WithPreparedStatements(100))
+		testOpts = getDefaultOptions()
+		testOpts.WithPreparedStatements = 100
+		assert.Equal(opts, testOpts)
+	})
+	t.Run("WithMaxIdleConnections", func(t *testing.T) {
+		assert := assert.New(t)
+		// test default
+		opts := GetOpts()
+		testOpts := getDefaultOptions()
+		testOpts.WithMaxIdleConnections = nil
+		assert.Equal(opts, testOpts)
+
+		opts = GetOpts(WithMaxIdleConnections(0))
+		testOpts = getDefaultOptions()
+		max := 0
+		testOpts.WithMaxIdleConnections = &max
+		assert.Equal(opts, testOpts)
+	})
+	t.Run("WithConnMaxLifetime", func(t *testing.T) {
+		assert := assert.New(t)
+		opts := GetOpts(WithConnMaxLifetime(time.Hour))
+		testOpts := getDefaultOptions()
+		testOpts.WithConnMaxLifetime = time.Hour
+		assert.Equal(opts, testOpts)
+	})
+	t.Run("WithConnMaxIdleTime", func(t *testing.T) {
+		assert := assert.New(t)
+		opts := GetOpts(WithConnMaxIdleTime(time.Minute))
+		testOpts := getDefaultOptions()
+		testOpts.WithConnMaxIdleTime = time.Minute
+		assert.Equal(opts, testOpts)
+	})
+	t.Run("WithConnKeepAliveInterval", func(t *testing.T) {
+		assert := assert.New(t)
+		opts := GetOpts(WithConnKeepAliveInterval(time.Second))
+		testOpts := getDefaultOptions()
+		testOpts.WithConnKeepAliveInterval = time.Second
+		assert.Equal(opts, testOpts)
+	})
+	t.Run("WithLookupCache", func(t *testing.T) {
+		assert := assert.New(t)
+		// test default
+		opts := GetOpts()
+		testOpts := getDefaultOptions()
+		testOpts.WithLookupCache = nil
+		assert.Equal(opts, testOpts)
+
+		c, err := NewLookupCache()
+		require.NoError(t, err)
+		opts = GetOpts(WithLookupCache(c))
+		testOpts = getDefaultOptions()
+		testOpts.WithLookupCache = c
+		assert.Equal(opts, testOpts)
+	})
+	t.Run("WithReturning", func(t *testing.T) {
+		assert := assert.New(t)
+		// test default
+		opts := GetOpts()
+		assert.Nil(opts.WithReturning)
+
+		opts = GetOpts(WithReturning())
+		testOpts := getDefaultOptions()
+		testOpts.WithReturning = []string{}
+		assert.Equal(opts, testOpts)
+
+		opts = GetOpts(WithReturning("version", "update_time"))
+		testOpts = getDefaultOptions()
+		testOpts.WithReturning = []string{"version", "update_time"}
+		assert.Equal(opts, testOpts)
+	})
+	t.Run("WithAllRows", func(t *testing.T) {
+		assert := assert.New(t)
+		opts := GetOpts(WithAllRows(true))
+		testOpts := getDefaultOptions()
+		testOpts.WithAllRows = true
+		assert.Equal(opts, testOpts)
+	})
+	t.Run("WithItemResults", func(t *testing.T) {
+		assert := assert.New(t)
+		var results []ItemResult
+		opts := GetOpts(WithItemResults(&results))
+		testOpts := getDefaultOptions()
+		testOpts.WithItemResults = &results
+		assert.Equal(opts, testOpts)
+	})
 }
@@ -0,0 +1,212 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultConnKeepAliveInterval is the default interval of the keep-alive
+// which maintains a DB's min open connections.  See WithMinOpenConnections
+const DefaultConnKeepAliveInterval = 30 * time.Second
+
+// defaultMaxIdleConns is the database/sql default for max idle connections
+const defaultMaxIdleConns = 2
+
+// PoolStats are the statistics of a DB's connection pool
+type PoolStats struct {
+	sql.DBStats
+
+	// MinOpenConnections is the configured min open connections, which is
+	// zero when there's no min.
+	MinOpenConnections int
+}
+
+// BelowMinOpen returns true when the pool has fewer open connections than
+// its min open connections.
+func (s PoolStats) BelowMinOpen() bool {
+	return s.OpenConnections < s.MinOpenConnections
+}
+
+// Saturated returns true when every connection of a pool with max open
+// connections is in use, so new requests must wait for a connection.
+func (s PoolStats) Saturated() bool {
+	return s.MaxOpenConnections > 0 && s.InUse >= s.MaxOpenConnections
+}
+
+// PoolHealth is the health of a DB's connection pool, which is returned by
+// Ping(...)
+type PoolHealth struct {
+	PoolStats
+
+	// Latency of the ping
+	Latency time.Duration
+}
+
+// Stats returns the statistics of the DB's connection pool
+func (db *DB) Stats() (PoolStats, error) {
+	const op = "dbw.(DB).Stats"
+	if db.wrapped == nil {
+		return PoolStats{}, fmt.Errorf("%s: missing underlying database: %w", op, ErrInternal)
+	}
+	underlying, err := db.wrapped.DB()
+	if err != nil {
+		return PoolStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+	stats := PoolStats{DBStats: underlying.Stats()}
+	if db.pool != nil {
+		stats.MinOpenConnections = db.pool.minOpen
+	}
+	return stats, nil
+}
+
+// Ping verifies the DB's connection is alive, establishing a connection if
+// necessary, and returns the health of its connection pool.
+func (db *DB) Ping(ctx context.Context) (PoolHealth, error) {
+	const op = "dbw.(DB).Ping"
+	if db.wrapped == nil {
+		return PoolHealth{}, fmt.Errorf("%s: missing underlying database: %w", op, ErrInternal)
+	}
+	underlying, err := db.wrapped.DB()
+	if err != nil {
+		return PoolHealth{}, fmt.Errorf("%s: %w", op, err)
+	}
+	start := time.Now()
+	if err := underlying.PingContext(ctx); err != nil {
+		return PoolHealth{}, fmt.Errorf("%s: %w", op, err)
+	}
+	health := PoolHealth{Latency: time.Since(start)}
+	if health.PoolStats, err = db.Stats(); err != nil {
+		return PoolHealth{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return health, nil
+}
+
+// poolKeeper maintains a pool's min open connections by pre-dialing them and
+// periodically pinging them, which keeps them alive.
+type poolKeeper struct {
+	db       *sql.DB
+	minOpen  int
+	interval time.Duration
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// configurePool applies the pool options to the sql.DB and returns a
+// poolKeeper with the pool's configuration, which is started when there are
+// min open connections.
+func configurePool(ctx context.Context, db *sql.DB, opts Options) (*poolKeeper, error) {
+	const op = "dbw.configurePool"
+	switch {
+	case opts.WithMinOpenConnections < 0:
+		return nil, fmt.Errorf("%s: min open connections must not be negative: %w", op, ErrInvalidParameter)
+	case opts.WithMaxOpenConnections > 0 && opts.WithMinOpenConnections > opts.WithMaxOpenConnections:
+		return nil, fmt.Errorf("%s: max_open_connections must be unlimited by setting 0 or at least %d: %w", op, opts.WithMinOpenConnections, ErrInvalidParameter)
+	case opts.WithMaxIdleConnections != nil && *opts.WithMaxIdleConnections < opts.WithMinOpenConnections:
+		return nil, fmt.Errorf("%s: max idle connections must be at least %d: %w", op, opts.WithMinOpenConnections, ErrInvalidParameter)
+	case opts.WithConnMaxLifetime < 0 || opts.WithConnMaxIdleTime < 0:
+		return nil, fmt.Errorf("%s: connection lifetimes must not be negative: %w", op, ErrInvalidParameter)
+	case opts.WithConnKeepAliveInterval < 0:
+		return nil, fmt.Errorf("%s: keep-alive interval must not be negative: %w", op, ErrInvalidParameter)
+	}
+	if opts.WithMaxOpenConnections > 0 {
+		db.SetMaxOpenConns(opts.WithMaxOpenConnections)
+	}
+	p := &poolKeeper{
+		db:       db,
+		minOpen:  opts.WithMinOpenConnections,
+		interval: opts.WithConnKeepAliveInterval,
+	}
+	switch {
+	case opts.WithMaxIdleConnections != nil:
+		db.SetMaxIdleConns(*opts.WithMaxIdleConnections)
+	case p.minOpen > defaultMaxIdleConns:
+		// otherwise, database/sql would close the idle min open connections
+		db.SetMaxIdleConns(p.minOpen)
+	}
+	if opts.WithConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(opts.WithConnMaxLifetime)
+	}
+	if opts.WithConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(opts.WithConnMaxIdleTime)
+	}
+	if p.interval == 0 {
+		p.interval = DefaultConnKeepAliveInterval
+	}
+	if p.minOpen == 0 {
+		return p, nil
+	}
+	if err := p.warm(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	p.start()
+	return p, nil
+}
+
+// warm dials the pool's missing min open connections and pings its idle
+// connections.  Connections in use by the application are alive, so they
+// aren't needed.
+func (p *poolKeeper) warm(ctx context.Context) error {
+	const op = "dbw.(poolKeeper).warm"
+	n := p.minOpen - p.db.Stats().InUse
+	if n <= 0 {
+		return nil
+	}
+	conns := make([]*sql.Conn, 0, n)
+	defer func() {
+		for _, c := range conns {
+			_ = c.Close()
+		}
+	}()
+	// every connection must be held until all of them are pinged, otherwise
+	// the pool would return the same idle connection over and over.
+	for i := 0; i < n; i++ {
+		c, err := p.db.Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		conns = append(conns, c)
+		if err := c.PingContext(ctx); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	return nil
+}
+
+func (p *poolKeeper) start() {
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				// don't wait for connections held by the application for
+				// longer than an interval.
+				ctx, cancel := context.WithTimeout(context.Background(), p.interval)
+				_ = p.warm(ctx)
+				cancel()
+			}
+		}
+	}()
+}
+
+// close stops the keep-alive, and waits for it to complete
+func (p *poolKeeper) close() {
+	if p == nil || p.stop == nil {
+		return
+	}
+	p.stopOnce.Do(func() { close(p.stop) })
+	<-p.done
+}
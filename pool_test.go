@@ -0,0 +1,174 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDB_Pool(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	t.Run("min-open", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		url := filepath.Join(t.TempDir(), "pool.db")
+		db, err := Open(Sqlite, url,
+			WithMinOpenConnections(3),
+			WithMaxOpenConnections(5),
+			WithConnKeepAliveInterval(10*time.Millisecond),
+		)
+		require.NoError(err)
+
+		stats, err := db.Stats()
+		require.NoError(err)
+		assert.Equal(3, stats.OpenConnections)
+		assert.Equal(3, stats.Idle)
+		assert.Equal(3, stats.MinOpenConnections)
+		assert.Equal(5, stats.MaxOpenConnections)
+		assert.False(stats.BelowMinOpen())
+
+		// the keep-alive re-dials connections which were closed
+		sqlDB, err := db.SqlDB(testCtx)
+		require.NoError(err)
+		sqlDB.SetMaxIdleConns(0)
+		sqlDB.SetMaxIdleConns(3)
+		assert.Eventually(func() bool {
+			stats, err := db.Stats()
+			return err == nil && stats.OpenConnections == 3
+		}, 5*time.Second, 10*time.Millisecond)
+
+		require.NoError(db.Close(testCtx))
+		// the keep-alive was stopped
+		<-db.pool.done
+	})
+	t.Run("ping", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		url := filepath.Join(t.TempDir(), "pool.db")
+		db, err := Open(Sqlite, url)
+		require.NoError(err)
+		defer db.Close(testCtx)
+
+		health, err := db.Ping(testCtx)
+		require.NoError(err)
+		assert.Greater(health.Latency, time.Duration(0))
+		assert.Equal(0, health.MinOpenConnections)
+		assert.Equal(0, health.InUse)
+
+		require.NoError(db.Close(testCtx))
+		_, err = db.Ping(testCtx)
+		assert.Error(err)
+	})
+	t.Run("missing-db", func(t *testing.T) {
+		_, err := (&DB{}).Stats()
+		assert.ErrorIs(t, err, ErrInternal)
+		_, err = (&DB{}).Ping(testCtx)
+		assert.ErrorIs(t, err, ErrInternal)
+	})
+}
+
+func Test_configurePool(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name            string
+		opts            []Option
+		wantMaxIdle     int
+		wantErrContains string
+	}{
+		{
+			name:        "defaults",
+			wantMaxIdle: defaultMaxIdleConns,
+		},
+		{
+			name:        "min-open-idle",
+			opts:        []Option{WithMinOpenConnections(4)},
+			wantMaxIdle: 4,
+		},
+		{
+			name:        "max-idle",
+			opts:        []Option{WithMinOpenConnections(1), WithMaxIdleConnections(1)},
+			wantMaxIdle: 1,
+		},
+		{
+			name:            "negative-min-open",
+			opts:            []Option{WithMinOpenConnections(-1)},
+			wantErrContains: "min open connections must not be negative",
+		},
+		{
+			name:            "min-open-above-max-open",
+			opts:            []Option{WithMinOpenConnections(3), WithMaxOpenConnections(2)},
+			wantErrContains: "max_open_connections must be unlimited by setting 0 or at least 3",
+		},
+		{
+			name:            "max-idle-below-min-open",
+			opts:            []Option{WithMinOpenConnections(3), WithMaxIdleConnections(2)},
+			wantErrContains: "max idle connections must be at least 3",
+		},
+		{
+			name:            "negative-lifetime",
+			opts:            []Option{WithConnMaxLifetime(-time.Second)},
+			wantErrContains: "connection lifetimes must not be negative",
+		},
+		{
+			name:            "negative-idle-time",
+			opts:            []Option{WithConnMaxIdleTime(-time.Second)},
+			wantErrContains: "connection lifetimes must not be negative",
+		},
+		{
+			name:            "negative-keep-alive",
+			opts:            []Option{WithConnKeepAliveInterval(-time.Second)},
+			wantErrContains: "keep-alive interval must not be negative",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert, require := assert.New(t), require.New(t)
+			sqlDB, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "pool.db"))
+			require.NoError(err)
+			defer sqlDB.Close()
+
+			p, err := configurePool(context.Background(), sqlDB, GetOpts(tt.opts...))
+			if tt.wantErrContains != "" {
+				require.Error(err)
+				assert.ErrorIs(err, ErrInvalidParameter)
+				assert.ErrorContains(err, tt.wantErrContains)
+				return
+			}
+			require.NoError(err)
+			defer p.close()
+			assert.Equal(DefaultConnKeepAliveInterval, p.interval)
+
+			// open more connections than the max idle, then release them
+			conns := make([]*sql.Conn, 0, tt.wantMaxIdle+1)
+			for i := 0; i < tt.wantMaxIdle+1; i++ {
+				c, err := sqlDB.Conn(context.Background())
+				require.NoError(err)
+				conns = append(conns, c)
+			}
+			for _, c := range conns {
+				require.NoError(c.Close())
+			}
+			assert.Equal(tt.wantMaxIdle, sqlDB.Stats().Idle)
+		})
+	}
+}
+
+func TestPoolStats(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	s := PoolStats{MinOpenConnections: 2, DBStats: sql.DBStats{OpenConnections: 1, MaxOpenConnections: 2, InUse: 2}}
+	assert.True(s.BelowMinOpen())
+	assert.True(s.Saturated())
+
+	s = PoolStats{DBStats: sql.DBStats{OpenConnections: 1, InUse: 1}}
+	assert.False(s.BelowMinOpen())
+	// a pool without max open connections is never saturated
+	assert.False(s.Saturated())
+}
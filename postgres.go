@@ -0,0 +1,192 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm/clause"
+)
+
+// postgresDialect is the Postgres dialect
+type postgresDialect struct{}
+
+var (
+	_ Dialect                 = postgresDialect{}
+	_ StatementTimeoutDialect = postgresDialect{}
+	_ SavepointReleaser       = postgresDialect{}
+	_ LockDialect             = postgresDialect{}
+	_ CredentialsDialect      = postgresDialect{}
+)
+
+// Name returns "postgres"
+func (postgresDialect) Name() string { return "postgres" }
+
+// Quote returns "identifier"
+func (postgresDialect) Quote(identifier string) string {
+	return quoteIdentifier(identifier, `"`)
+}
+
+// Excluded returns excluded.column
+func (postgresDialect) Excluded(column string) interface{} {
+	return clause.Column{Table: excludedTable, Name: column}
+}
+
+// OnConflict supports every on conflict clause
+func (postgresDialect) OnConflict(*clause.OnConflict, []string) error { return nil }
+
+// Locking returns a "for" locking clause
+func (postgresDialect) Locking(lock RowLock) (clause.Expression, error) {
+	return forLocking(lock), nil
+}
+
+// SupportsReturning returns true
+func (postgresDialect) SupportsReturning() bool { return true }
+
+// ReleaseSavepoint returns "release savepoint name"
+func (postgresDialect) ReleaseSavepoint(name string) string { return releaseSavepoint(name) }
+
+// IsAuthError returns true for invalid_authorization_specification and
+// invalid_password errors
+func (postgresDialect) IsAuthError(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "28000", // invalid_authorization_specification
+			"28P01": // invalid_password
+			return true
+		}
+	}
+	return false
+}
+
+// IsStaleStmtError returns true when a cached plan's result type changed or
+// the prepared statement doesn't exist
+func (postgresDialect) IsStaleStmtError(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "0A000": // feature_not_supported
+			return strings.Contains(pgErr.Message, "cached plan must not change result type")
+		case "26000": // invalid_sql_statement_name
+			return true
+		}
+	}
+	return false
+}
+
+// IsStatementTimeout returns true when a statement was canceled because of
+// its statement_timeout
+func (postgresDialect) IsStatementTimeout(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// query_canceled is also returned when a statement is canceled by a
+		// user request, which is distinguished by its message.
+		return pgErr.Code == "57014" && pgErr.Message == "canceling statement due to statement timeout"
+	}
+	return false
+}
+
+// IsBusyError returns false, since postgres waits for locks
+func (postgresDialect) IsBusyError(error) bool { return false }
+
+// TestSchema returns the postgres test schema
+func (postgresDialect) TestSchema() (create, drop []string) {
+	return []string{testQueryCreateTablesPostgres}, []string{testQueryDropTablesPostgres}
+}
+
+// SetLocalStatementTimeout returns the statement_timeout's previous value,
+// and sets it for the transaction
+func (postgresDialect) SetLocalStatementTimeout(d time.Duration) string {
+	return fmt.Sprintf("select current_setting('statement_timeout'), set_config('statement_timeout', '%s', true)", statementTimeout(d))
+}
+
+// RestoreLocalStatementTimeout restores the transaction's statement_timeout
+func (postgresDialect) RestoreLocalStatementTimeout() string {
+	return "select set_config('statement_timeout', ?, true)"
+}
+
+// SetStatementTimeout sets the session's statement_timeout
+func (postgresDialect) SetStatementTimeout(d time.Duration) string {
+	return fmt.Sprintf("set statement_timeout = %s", statementTimeout(d))
+}
+
+// ResetStatementTimeout resets the session's statement_timeout
+func (postgresDialect) ResetStatementTimeout() string {
+	return "reset statement_timeout"
+}
+
+// NewSessionLock returns a session level advisory lock (pg_advisory_lock) on a
+// connection dedicated to the lock, using LockKey(...) to hash the key.  The
+// lock is held until it's unlocked or the connection is lost, so it doesn't
+// expire.
+func (postgresDialect) NewSessionLock(ctx context.Context, db *DB, key string, _ time.Duration) (SessionLock, error) {
+	const op = "dbw.(postgresDialect).NewSessionLock"
+	sqlDB, err := db.SqlDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: unable to get a connection for the lock: %w", op, err)
+	}
+	return &pgLock{conn: conn, id: LockKey(key)}, nil
+}
+
+// LockTx acquires a transaction level advisory lock (pg_advisory_xact_lock),
+// which blocks until the lock is acquired or the ctx is done.
+func (postgresDialect) LockTx(ctx context.Context, rw *RW, key string) error {
+	const op = "dbw.(postgresDialect).LockTx"
+	if _, err := rw.Exec(ctx, "select pg_advisory_xact_lock(?)", []interface{}{LockKey(key)}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// pgLock is a session level postgres advisory lock held on a dedicated
+// connection.
+type pgLock struct {
+	conn *sql.Conn
+	id   int64
+}
+
+// TryLock tries to acquire the advisory lock
+func (l *pgLock) TryLock(ctx context.Context) (bool, error) {
+	var acquired bool
+	if err := l.conn.QueryRowContext(ctx, "select pg_try_advisory_lock($1)", l.id).Scan(&acquired); err != nil {
+		return false, err
+	}
+	return acquired, nil
+}
+
+// Lock blocks until the advisory lock is acquired
+func (l *pgLock) Lock(ctx context.Context) error {
+	_, err := l.conn.ExecContext(ctx, "select pg_advisory_lock($1)", l.id)
+	return err
+}
+
+// Check pings the lock's connection
+func (l *pgLock) Check(ctx context.Context) error {
+	return l.conn.PingContext(ctx)
+}
+
+// Unlock releases the advisory lock and closes its connection
+func (l *pgLock) Unlock(ctx context.Context) error {
+	// the lock is released with the session, so closing the connection is
+	// sufficient if the unlock fails.
+	_, unlockErr := l.conn.ExecContext(ctx, "select pg_advisory_unlock($1)", l.id)
+	if err := l.conn.Close(); err != nil {
+		return err
+	}
+	if unlockErr != nil && !errors.Is(unlockErr, sql.ErrConnDone) {
+		return unlockErr
+	}
+	return nil
+}
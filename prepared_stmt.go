@@ -0,0 +1,375 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// stmtCache is a gorm.ConnPool which executes statements using prepared
+// statements from an LRU cache.  Transactions started from the cache reuse
+// its statements (see stmtCacheTx).  Statements without args aren't
+// prepared, since they're typically DDL or scripts of multiple statements
+// which can't be prepared.  See WithPreparedStatements(...)
+type stmtCache struct {
+	db      *sql.DB
+	size    int
+	dialect Dialect
+
+	mu    sync.Mutex
+	lru   *list.List // of *cachedStmt, most recently used at the front
+	stmts map[string]*list.Element
+}
+
+// cachedStmt is a cached statement, which is reference counted so it's not
+// closed while a caller is about to use it.  A statement which is removed
+// from the cache is closed once the last caller releases it.
+type cachedStmt struct {
+	query   string
+	stmt    *sql.Stmt
+	refs    int
+	removed bool
+}
+
+var (
+	_ gorm.ConnPool         = (*stmtCache)(nil)
+	_ gorm.ConnPoolBeginner = (*stmtCache)(nil)
+	_ gorm.GetDBConnector   = (*stmtCache)(nil)
+	_ gorm.TxCommitter      = (*stmtCacheTx)(nil)
+	_ gorm.GetDBConnector   = (*stmtCacheTx)(nil)
+	_ gorm.ConnPool         = (*stmtCacheTx)(nil)
+)
+
+func newStmtCache(db *sql.DB, size int, dialect Dialect) *stmtCache {
+	return &stmtCache{
+		db:      db,
+		size:    size,
+		dialect: dialect,
+		lru:     list.New(),
+		stmts:   map[string]*list.Element{},
+	}
+}
+
+// GetDBConn returns the underlying sql.DB
+func (c *stmtCache) GetDBConn() (*sql.DB, error) {
+	return c.db, nil
+}
+
+// Len returns the number of cached statements
+func (c *stmtCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// prepare returns the cached statement for the query, preparing and caching
+// it when it's not already cached.  The statement must be released once it's
+// used.
+func (c *stmtCache) prepare(ctx context.Context, query string) (*cachedStmt, error) {
+	if cs, ok := c.lookup(query); ok {
+		return cs, nil
+	}
+
+	// prepare without holding the lock, since preparing may wait for a
+	// connection held by a transaction which is using the cache.
+	stmt, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.stmts[query]; ok {
+		// another caller prepared the same query concurrently
+		_ = stmt.Close()
+		c.lru.MoveToFront(e)
+		cs := e.Value.(*cachedStmt)
+		cs.refs++
+		return cs, nil
+	}
+	cs := &cachedStmt{query: query, stmt: stmt, refs: 1}
+	c.stmts[query] = c.lru.PushFront(cs)
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+	return cs, nil
+}
+
+// release the caller's reference to the statement, closing it when it's
+// been removed from the cache and it was the last reference.
+func (c *stmtCache) release(cs *cachedStmt) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cs.refs--
+	if cs.removed && cs.refs == 0 {
+		_ = cs.stmt.Close()
+	}
+}
+
+// evict the query's statement when it's the cached statement.  The statement
+// is closed once it's released by the callers using it, and closing a
+// statement waits for the queries using it to complete.
+func (c *stmtCache) evict(query string, stmt *sql.Stmt) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.stmts[query]; ok && e.Value.(*cachedStmt).stmt == stmt {
+		c.remove(e)
+	}
+}
+
+// remove must be called while holding the lock.  The statement is closed
+// unless a caller is using it, in which case it's closed when it's released.
+func (c *stmtCache) remove(e *list.Element) {
+	cs := c.lru.Remove(e).(*cachedStmt)
+	delete(c.stmts, cs.query)
+	cs.removed = true
+	if cs.refs == 0 {
+		_ = cs.stmt.Close()
+	}
+}
+
+// Reset removes all the cached statements, closing the ones which aren't in
+// use
+func (c *stmtCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.lru.Len() > 0 {
+		c.remove(c.lru.Back())
+	}
+}
+
+// PrepareContext prepares an uncached statement
+func (c *stmtCache) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return c.db.PrepareContext(ctx, query)
+}
+
+// ExecContext executes the query using a cached statement.  When the
+// statement fails because the schema changed, it's evicted and the query is
+// retried once with a newly prepared statement.
+func (c *stmtCache) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if len(args) == 0 {
+		return c.db.ExecContext(ctx, query)
+	}
+	var result sql.Result
+	err := c.withStmt(ctx, query, func(stmt *sql.Stmt) error {
+		var err error
+		result, err = stmt.ExecContext(ctx, args...)
+		return err
+	})
+	return result, err
+}
+
+// QueryContext executes the query using a cached statement.  When the
+// statement fails because the schema changed, it's evicted and the query is
+// retried once with a newly prepared statement.
+func (c *stmtCache) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if len(args) == 0 {
+		return c.db.QueryContext(ctx, query)
+	}
+	var rows *sql.Rows
+	err := c.withStmt(ctx, query, func(stmt *sql.Stmt) error {
+		var err error
+		rows, err = stmt.QueryContext(ctx, args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRowContext executes the query using a cached statement.  The
+// statement is not evicted on errors, since they're deferred until the row
+// is scanned.
+func (c *stmtCache) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if len(args) == 0 {
+		return c.db.QueryRowContext(ctx, query)
+	}
+	cs, err := c.prepare(ctx, query)
+	if err != nil {
+		return c.db.QueryRowContext(ctx, query, args...)
+	}
+	defer c.release(cs)
+	return cs.stmt.QueryRowContext(ctx, args...)
+}
+
+func (c *stmtCache) withStmt(ctx context.Context, query string, fn func(*sql.Stmt) error) error {
+	for attempt := 0; ; attempt++ {
+		cs, err := c.prepare(ctx, query)
+		if err != nil {
+			return err
+		}
+		err = fn(cs.stmt)
+		c.release(cs)
+		if err == nil || !isStaleStmtError(c.dialect, err) {
+			return err
+		}
+		c.evict(query, cs.stmt)
+		if attempt > 0 {
+			return err
+		}
+	}
+}
+
+// BeginTx starts a transaction which reuses the cache's statements
+func (c *stmtCache) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	tx, err := c.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &stmtCacheTx{Tx: tx, cache: c, stmts: map[string]*sql.Stmt{}}, nil
+}
+
+// lookup returns the cached statement for the query without preparing it.
+// The statement must be released once it's used.
+func (c *stmtCache) lookup(query string) (*cachedStmt, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.stmts[query]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	cs := e.Value.(*cachedStmt)
+	cs.refs++
+	return cs, true
+}
+
+// stmtCacheTx is a transaction which executes statements using the
+// transaction specific versions of the cache's prepared statements.
+// Statements which aren't cached are prepared on the transaction's
+// connection, since preparing them on another connection could wait forever
+// when the pool is limited to a single connection.  The transaction's
+// statements are closed when it completes.
+type stmtCacheTx struct {
+	*sql.Tx
+	cache *stmtCache
+
+	mu    sync.Mutex
+	stmts map[string]*sql.Stmt
+}
+
+// GetDBConn returns the underlying sql.DB
+func (tx *stmtCacheTx) GetDBConn() (*sql.DB, error) {
+	return tx.cache.db, nil
+}
+
+// prepare returns the transaction's statement for the query
+func (tx *stmtCacheTx) prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if stmt, ok := tx.stmts[query]; ok {
+		return stmt, nil
+	}
+	var stmt *sql.Stmt
+	if cached, ok := tx.cache.lookup(query); ok {
+		// the transaction's statement depends on the cached statement, so
+		// it's not closed until the transaction's statement is closed.
+		stmt = tx.StmtContext(ctx, cached.stmt)
+		tx.cache.release(cached)
+	} else {
+		var err error
+		if stmt, err = tx.PrepareContext(ctx, query); err != nil {
+			return nil, err
+		}
+	}
+	tx.stmts[query] = stmt
+	return stmt, nil
+}
+
+// evict the query's statements from the transaction and the cache
+func (tx *stmtCacheTx) evict(query string) {
+	tx.mu.Lock()
+	delete(tx.stmts, query)
+	tx.mu.Unlock()
+	if cached, ok := tx.cache.lookup(query); ok {
+		tx.cache.evict(query, cached.stmt)
+		tx.cache.release(cached)
+	}
+}
+
+// ExecContext executes the query using a prepared statement.  A statement
+// which fails because the schema changed is evicted, but the query isn't
+// retried since the failure may have aborted the transaction.
+func (tx *stmtCacheTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if len(args) == 0 {
+		return tx.Tx.ExecContext(ctx, query)
+	}
+	stmt, err := tx.prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	result, err := stmt.ExecContext(ctx, args...)
+	if err != nil && isStaleStmtError(tx.cache.dialect, err) {
+		tx.evict(query)
+	}
+	return result, err
+}
+
+// QueryContext executes the query using a prepared statement.  A statement
+// which fails because the schema changed is evicted, but the query isn't
+// retried since the failure may have aborted the transaction.
+func (tx *stmtCacheTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if len(args) == 0 {
+		return tx.Tx.QueryContext(ctx, query)
+	}
+	stmt, err := tx.prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil && isStaleStmtError(tx.cache.dialect, err) {
+		tx.evict(query)
+	}
+	return rows, err
+}
+
+// QueryRowContext executes the query using a prepared statement
+func (tx *stmtCacheTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if len(args) == 0 {
+		return tx.Tx.QueryRowContext(ctx, query)
+	}
+	stmt, err := tx.prepare(ctx, query)
+	if err != nil {
+		return tx.Tx.QueryRowContext(ctx, query, args...)
+	}
+	return stmt.QueryRowContext(ctx, args...)
+}
+
+// isStaleStmtError returns true when a prepared statement can no longer be
+// used because its connection is gone or the dialect classifies the error as
+// stale (see Dialect.IsStaleStmtError).
+func isStaleStmtError(d Dialect, err error) bool {
+	return errors.Is(err, driver.ErrBadConn) || d.IsStaleStmtError(err)
+}
+
+// usePreparedStatements replaces the db's connection pool with a prepared
+// statement cache of the given size.
+func usePreparedStatements(db *gorm.DB, size int) error {
+	const op = "dbw.usePreparedStatements"
+	if size < 0 {
+		return fmt.Errorf("%s: prepared statement cache size must not be negative: %w", op, ErrInvalidParameter)
+	}
+	dialect := dialectFor(db.Dialector)
+	switch pool := db.ConnPool.(type) {
+	case *sql.DB:
+		cache := newStmtCache(pool, size, dialect)
+		db.ConnPool = cache
+		db.Statement.ConnPool = cache
+	case *sqlitePool:
+		// the writer and readers have their own statements
+		pool.writer = newStmtCache(pool.writerDB, size, dialect)
+		if pool.readersDB != nil {
+			pool.readers = newStmtCache(pool.readersDB, size, dialect)
+		}
+	default:
+		return fmt.Errorf("%s: prepared statements require a sql.DB connection pool: %w", op, ErrInvalidParameter)
+	}
+	return nil
+}
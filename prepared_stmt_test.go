@@ -0,0 +1,337 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/go-secure-stdlib/base62"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPreparedDB returns a DB with a prepared statement cache of the given
+// size for a migrated sqlite test database.
+func testPreparedDB(t *testing.T, size int, opt ...Option) *DB {
+	t.Helper()
+	url := filepath.Join(t.TempDir(), "prepared.db")
+	TestSetup(t, WithTestDatabaseUrl(url))
+	prepared, err := Open(Sqlite, url, append(opt, WithPreparedStatements(size))...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = prepared.Close(context.Background()) })
+	return prepared
+}
+
+func testStmtCache(t testing.TB, db *DB) *stmtCache {
+	t.Helper()
+	cache, ok := db.wrapped.ConnPool.(*stmtCache)
+	require.True(t, ok)
+	return cache
+}
+
+func testCreateUser(t testing.TB, rw *RW, name string) *testUser {
+	t.Helper()
+	publicId, err := base62.Random(20)
+	require.NoError(t, err)
+	u := &testUser{PublicId: publicId, Name: name}
+	require.NoError(t, rw.Create(context.Background(), u))
+	return u
+}
+
+func TestWithPreparedStatements(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	t.Run("cached", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		db := testPreparedDB(t, 10)
+		rw := New(db)
+		cache := testStmtCache(t, db)
+
+		u := testCreateUser(t, rw, "alice")
+		before := cache.Len()
+		for i := 0; i < 3; i++ {
+			found := &testUser{PublicId: u.PublicId}
+			require.NoError(rw.LookupBy(testCtx, found))
+			assert.Equal("alice", found.Name)
+		}
+		// the lookup's statement is prepared once
+		assert.Equal(before+1, cache.Len())
+	})
+	t.Run("lru-eviction", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		db := testPreparedDB(t, 2)
+		rw := New(db)
+		cache := testStmtCache(t, db)
+		queries := []string{
+			"select 1 where 1 = ?",
+			"select 2 where 1 = ?",
+			"select 3 where 1 = ?",
+		}
+		for _, q := range queries {
+			rows, err := rw.Query(testCtx, q, []interface{}{1})
+			require.NoError(err)
+			require.NoError(rows.Close())
+		}
+		assert.Equal(2, cache.Len())
+		_, ok := cache.lookup(queries[0])
+		assert.False(ok)
+		_, ok = cache.lookup(queries[2])
+		assert.True(ok)
+	})
+	t.Run("do-tx", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		// a single connection verifies transactions don't wait for another
+		// connection to prepare statements.
+		db := testPreparedDB(t, 10, WithMaxOpenConnections(1))
+		rw := New(db)
+		cache := testStmtCache(t, db)
+
+		u := testCreateUser(t, rw, "alice")
+		require.NoError(rw.LookupBy(testCtx, &testUser{PublicId: u.PublicId}))
+		cached := cache.Len()
+
+		_, err := rw.DoTx(testCtx, func(error) bool { return false }, 0, ExpBackoff{}, func(r Reader, w Writer) error {
+			for i := 0; i < 2; i++ {
+				found := &testUser{PublicId: u.PublicId}
+				if err := r.LookupBy(testCtx, found); err != nil {
+					return err
+				}
+				found.Name = fmt.Sprintf("alice-%d", i)
+				if _, err := w.Update(testCtx, found, []string{"Name"}, nil); err != nil {
+					return err
+				}
+			}
+			testCreateUser(t, w.(*RW), "bob")
+			return nil
+		})
+		require.NoError(err)
+		// statements prepared in the transaction aren't added to the cache
+		assert.Equal(cached, cache.Len())
+
+		found := &testUser{PublicId: u.PublicId}
+		require.NoError(rw.LookupBy(testCtx, found))
+		assert.Equal("alice-1", found.Name)
+	})
+	t.Run("no-args", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		db := testPreparedDB(t, 10)
+		rw := New(db)
+		cache := testStmtCache(t, db)
+		// statements without args, like scripts of multiple statements,
+		// aren't prepared
+		_, err := rw.Exec(testCtx, "create table t1 (id int); create table t2 (id int);", nil)
+		require.NoError(err)
+		_, err = rw.Exec(testCtx, "insert into t2 (id) values (?)", []interface{}{1})
+		require.NoError(err)
+		assert.Equal(1, cache.Len())
+	})
+	t.Run("invalid-size", func(t *testing.T) {
+		_, err := Open(Sqlite, "file::memory:", WithPreparedStatements(-1))
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrInvalidParameter)
+	})
+	t.Run("close", func(t *testing.T) {
+		db := testPreparedDB(t, 10)
+		cache := testStmtCache(t, db)
+		testCreateUser(t, New(db), "alice")
+		require.NoError(t, db.Close(testCtx))
+		assert.Equal(t, 0, cache.Len())
+	})
+}
+
+func Test_stmtCache_evict(t *testing.T) {
+	t.Parallel()
+	assert, require := assert.New(t), require.New(t)
+	db := testPreparedDB(t, 10)
+	cache := testStmtCache(t, db)
+
+	const query = "select 1 where 1 = ?"
+	cs, err := cache.prepare(context.Background(), query)
+	require.NoError(err)
+	other, err := cache.prepare(context.Background(), query)
+	require.NoError(err)
+	assert.Same(cs, other)
+	cache.release(other)
+
+	// a statement which isn't the cached one isn't evicted
+	uncached, err := cache.PrepareContext(context.Background(), query)
+	require.NoError(err)
+	defer uncached.Close()
+	cache.evict(query, uncached)
+	assert.Equal(1, cache.Len())
+
+	// an evicted statement isn't closed until it's released
+	cache.evict(query, cs.stmt)
+	assert.Equal(0, cache.Len())
+	rows, err := cs.stmt.Query(1)
+	require.NoError(err)
+	require.NoError(rows.Close())
+	cache.release(cs)
+	_, err = cs.stmt.Query(1)
+	assert.ErrorContains(err, "statement is closed")
+}
+
+func Test_stmtCache_concurrent(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	// alternating queries with a cache of one statement evict each other's
+	// statements while they're in use.
+	queries := []string{"select 1 where 1 = ?", "select 2 where 1 = ?"}
+	t.Run("evicted-in-use", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		cache := testStmtCache(t, testPreparedDB(t, 1))
+		first, err := cache.prepare(testCtx, queries[0])
+		require.NoError(err)
+		second, err := cache.prepare(testCtx, queries[1])
+		require.NoError(err)
+		defer cache.release(second)
+		assert.Equal(1, cache.Len())
+
+		// the first statement was evicted before it was used
+		rows, err := first.stmt.QueryContext(testCtx, 1)
+		require.NoError(err)
+		require.NoError(rows.Close())
+		cache.release(first)
+	})
+	t.Run("alternating", func(t *testing.T) {
+		rw := New(testPreparedDB(t, 1))
+		var wg sync.WaitGroup
+		errs := make(chan error, 8)
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 500; j++ {
+					rows, err := rw.Query(testCtx, queries[(i+j)%2], []interface{}{1})
+					if err != nil {
+						errs <- err
+						return
+					}
+					if err := rows.Close(); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			assert.NoError(t, err)
+		}
+	})
+}
+
+func Test_isStaleStmtError(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		dialect Dialect
+		err     error
+		want    bool
+	}{
+		{
+			name:    "bad-conn",
+			dialect: postgresDialect{},
+			err:     fmt.Errorf("wrapped: %w", driver.ErrBadConn),
+			want:    true,
+		},
+		{
+			name:    "pg-cached-plan",
+			dialect: postgresDialect{},
+			err:     &pgconn.PgError{Code: "0A000", Message: "cached plan must not change result type"},
+			want:    true,
+		},
+		{
+			name:    "pg-feature-not-supported",
+			dialect: postgresDialect{},
+			err:     &pgconn.PgError{Code: "0A000", Message: "unsupported"},
+		},
+		{
+			name:    "pg-invalid-statement-name",
+			dialect: postgresDialect{},
+			err:     &pgconn.PgError{Code: "26000"},
+			want:    true,
+		},
+		{
+			name:    "pg-unique",
+			dialect: postgresDialect{},
+			err:     &pgconn.PgError{Code: "23505"},
+		},
+		{
+			name:    "sqlite-schema",
+			dialect: sqliteDialect{},
+			err:     sqlite3.Error{Code: sqlite3.ErrSchema},
+			want:    true,
+		},
+		{
+			name:    "sqlite-constraint",
+			dialect: sqliteDialect{},
+			err:     sqlite3.Error{Code: sqlite3.ErrConstraint},
+		},
+		{
+			name:    "other",
+			dialect: postgresDialect{},
+			err:     ErrInvalidParameter,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isStaleStmtError(tt.dialect, tt.err))
+		})
+	}
+}
+
+func BenchmarkPreparedStatements(b *testing.B) {
+	testCtx := context.Background()
+	for _, size := range []int{0, 100} {
+		name := "unprepared"
+		if size > 0 {
+			name = "prepared"
+		}
+		b.Run(name, func(b *testing.B) {
+			url := filepath.Join(b.TempDir(), "bench.db")
+			db, err := Open(Sqlite, url, WithPreparedStatements(size))
+			require.NoError(b, err)
+			defer db.Close(testCtx)
+			rw := New(db)
+			_, err = rw.Exec(testCtx, testQueryCreateTablesSqlite, nil)
+			require.NoError(b, err)
+			u := testCreateUser(b, rw, "alice")
+
+			b.Run("LookupBy", func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if err := rw.LookupBy(testCtx, &testUser{PublicId: u.PublicId}); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+			b.Run("SearchWhere", func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					var users []*testUser
+					if err := rw.SearchWhere(testCtx, &users, "name = ?", []interface{}{"alice"}); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+			b.Run("DoTx", func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					_, err := rw.DoTx(testCtx, func(error) bool { return false }, 0, ExpBackoff{}, func(r Reader, _ Writer) error {
+						return r.LookupBy(testCtx, &testUser{PublicId: u.PublicId})
+					})
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
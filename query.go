@@ -13,6 +13,10 @@ import (
 // operate within the context of any ongoing transaction for the Reader.  The
 // caller must close the returned *sql.Rows. Query can/should be used in
 // combination with ScanRows. The WithDebug option is supported.
+//
+// WithTimeout isn't supported, since the rows are read after Query returns,
+// so its timeout couldn't be released when the rows are closed.  Use the
+// ctx's deadline instead.  The DB's default timeout doesn't apply to Query.
 func (rw *RW) Query(ctx context.Context, sql string, values []interface{}, opt ...Option) (*sql.Rows, error) {
 	const op = "dbw.Query"
 	if rw.underlying == nil {
@@ -22,6 +26,9 @@ func (rw *RW) Query(ctx context.Context, sql string, values []interface{}, opt .
 		return nil, fmt.Errorf("%s: missing sql: %w", op, ErrInvalidParameter)
 	}
 	opts := GetOpts(opt...)
+	if opts.WithTimeout != 0 {
+		return nil, fmt.Errorf("%s: with timeout is not supported, use the context's deadline: %w", op, ErrInvalidParameter)
+	}
 	db := rw.underlying.wrapped.WithContext(ctx)
 	if opts.WithDebug {
 		db = db.Debug()
@@ -30,7 +37,11 @@ func (rw *RW) Query(ctx context.Context, sql string, values []interface{}, opt .
 	if db.Error != nil {
 		return nil, fmt.Errorf("%s: %w", op, db.Error)
 	}
-	return db.Rows()
+	rows, err := db.Rows()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return rows, nil
 }
 
 // ScanRows will scan the rows into the interface
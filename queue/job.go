@@ -0,0 +1,83 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package queue
+
+import (
+	"time"
+)
+
+// DefaultTableName is the default table name for queue jobs.
+const DefaultTableName = "dbw_queue_job"
+
+// Status defines the state of a job
+type Status string
+
+const (
+	// Pending is a job waiting to be claimed by a worker once its RunAt time
+	// has passed.
+	Pending Status = "pending"
+
+	// Running is a job which has been claimed by a worker and is leased until
+	// its LeaseExpiresAt time.
+	Running Status = "running"
+
+	// Completed is a job which has been successfully processed.
+	Completed Status = "completed"
+
+	// Dead is a job which has exhausted its attempts and has been dead
+	// lettered.  Dead jobs are never claimed, but can be requeued via
+	// Queue.Requeue(...)
+	Dead Status = "dead"
+)
+
+// Job represents a unit of work in a queue.
+type Job struct {
+	// Id is the job's primary key
+	Id string `gorm:"primaryKey"`
+
+	// Queue is the name of the queue the job belongs to
+	Queue string
+
+	// Priority of the job.  Jobs with a higher priority are claimed first.
+	Priority int
+
+	// Payload is the job's opaque payload
+	Payload []byte
+
+	// Status of the job
+	Status Status
+
+	// Attempts is the number of times the job has been claimed
+	Attempts int
+
+	// MaxAttempts is the number of attempts allowed before the job is dead
+	// lettered
+	MaxAttempts int
+
+	// UniqueKey is an optional key which prevents duplicate jobs from being
+	// enqueued to the queue.  It's cleared when the job is completed or dead
+	// lettered, so the key can be enqueued again.
+	UniqueKey *string
+
+	// RunAt is the earliest time the job can be claimed
+	RunAt time.Time
+
+	// LeaseOwner is the id of the worker which currently holds the job's lease
+	LeaseOwner string `gorm:"default:null"`
+
+	// LeaseExpiresAt is the time when the current lease expires.  Jobs with
+	// an expired lease may be claimed by another worker.
+	LeaseExpiresAt *time.Time
+
+	// LastError is the error from the job's last failed attempt
+	LastError string `gorm:"default:null"`
+
+	// CreateTime is set by the database when the job is enqueued
+	CreateTime time.Time `gorm:"default:current_timestamp"`
+}
+
+// TableName returns the table name for the job.
+func (j *Job) TableName() string {
+	return DefaultTableName
+}
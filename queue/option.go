@@ -0,0 +1,137 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package queue
+
+import (
+	"time"
+
+	"github.com/hashicorp/go-dbw"
+)
+
+const (
+	// DefaultMaxAttempts is the default number of attempts for a job before
+	// it's dead lettered.
+	DefaultMaxAttempts = 5
+
+	// DefaultLease is the default duration of a lease when a job is claimed.
+	DefaultLease = 5 * time.Minute
+
+	// DefaultTxRetries is the default number of retries for the queue's
+	// transactions.
+	DefaultTxRetries = 3
+)
+
+// getOpts - iterate the inbound Options and return a struct.
+func getOpts(opt ...Option) options {
+	opts := getDefaultOptions()
+	for _, o := range opt {
+		if o != nil {
+			o(&opts)
+		}
+	}
+	return opts
+}
+
+// Option - how Options are passed as arguments.
+type Option func(*options)
+
+// options = how options are represented
+type options struct {
+	withPriority    int
+	withRunAt       time.Time
+	withUniqueKey   string
+	withMaxAttempts int
+	withLease       time.Duration
+	withBackoff     dbw.Backoff
+	withNow         func() time.Time
+	withTxRetries   uint
+	withLimit       int
+}
+
+func getDefaultOptions() options {
+	return options{
+		withMaxAttempts: DefaultMaxAttempts,
+		withLease:       DefaultLease,
+		withBackoff:     dbw.ExpBackoff{},
+		withNow:         time.Now,
+		withTxRetries:   DefaultTxRetries,
+	}
+}
+
+// WithPriority specifies an optional priority for a job when it's enqueued.
+// Jobs with a higher priority are claimed first.
+func WithPriority(p int) Option {
+	return func(o *options) {
+		o.withPriority = p
+	}
+}
+
+// WithRunAt specifies an optional time for when a job can first be claimed.
+// The default is to run it immediately.
+func WithRunAt(t time.Time) Option {
+	return func(o *options) {
+		o.withRunAt = t
+	}
+}
+
+// WithUniqueKey specifies an optional key which prevents duplicate jobs from
+// being enqueued.  Enqueue will return ErrDuplicateJob if a pending or running
+// job with the same key already exists in the queue.  The key is cleared when
+// its job is completed or dead lettered, so it can be enqueued again, and a
+// requeued dead job has no key.
+func WithUniqueKey(k string) Option {
+	return func(o *options) {
+		o.withUniqueKey = k
+	}
+}
+
+// WithMaxAttempts specifies an optional number of attempts before a job is
+// dead lettered.  It can be used with both New(...) to set the queue's default
+// and Enqueue(...) to set it for a single job.
+func WithMaxAttempts(n int) Option {
+	return func(o *options) {
+		o.withMaxAttempts = n
+	}
+}
+
+// WithLease specifies an optional duration for a job's lease when it's
+// claimed.  It's only valid for New(...)
+func WithLease(d time.Duration) Option {
+	return func(o *options) {
+		o.withLease = d
+	}
+}
+
+// WithBackoff specifies an optional backoff used to determine when a failed
+// job will be retried.  It's only valid for New(...) and the default is
+// dbw.ExpBackoff{}
+func WithBackoff(b dbw.Backoff) Option {
+	return func(o *options) {
+		o.withBackoff = b
+	}
+}
+
+// WithNowFunc specifies an optional func for getting the current time, which
+// is useful for testing. It's only valid for New(...)
+func WithNowFunc(fn func() time.Time) Option {
+	return func(o *options) {
+		o.withNow = fn
+	}
+}
+
+// WithTxRetries specifies an optional number of retries for the queue's
+// transactions.  It's only valid for New(...)
+func WithTxRetries(n uint) Option {
+	return func(o *options) {
+		o.withTxRetries = n
+	}
+}
+
+// WithLimit specifies an optional limit for listing jobs.  See
+// dbw.WithLimit(...) for details.
+func WithLimit(n int) Option {
+	return func(o *options) {
+		o.withLimit = n
+	}
+}
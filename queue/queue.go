@@ -0,0 +1,418 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+// Package queue provides a durable work queue which is stored in the database
+// and built using dbw primitives.  Jobs are enqueued using a dbw.Writer, so
+// they can be written in the same transaction as an application's writes.
+// Workers claim jobs with a lease, which they can extend via a heartbeat.
+// Failed jobs are retried using a dbw.Backoff until they exhaust their
+// attempts and are dead lettered.
+//
+// The queue's table must be created by the caller's migrations. See
+// Schema(...)
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-dbw"
+)
+
+var (
+	// ErrDuplicateJob is returned when a job is enqueued with a unique key
+	// which already exists.
+	ErrDuplicateJob = errors.New("duplicate job")
+
+	// ErrLeaseLost is returned when a worker no longer holds the lease for a
+	// job.
+	ErrLeaseLost = errors.New("lease lost")
+)
+
+// Handler defines a func which processes a job.  The reader and writer are
+// bound to the transaction which will complete the job, so any writes made
+// by the handler are atomic with the job's completion.
+type Handler func(ctx context.Context, r dbw.Reader, w dbw.Writer, job *Job) error
+
+// Queue is a named work queue stored in the database.
+type Queue struct {
+	rw          *dbw.RW
+	name        string
+	maxAttempts int
+	lease       time.Duration
+	backoff     dbw.Backoff
+	now         func() time.Time
+	txRetries   uint
+}
+
+// New creates a new Queue with the name.  Supported options: WithMaxAttempts,
+// WithLease, WithBackoff, WithNowFunc and WithTxRetries.
+func New(rw *dbw.RW, name string, opt ...Option) (*Queue, error) {
+	const op = "queue.New"
+	switch {
+	case rw == nil:
+		return nil, fmt.Errorf("%s: missing rw: %w", op, dbw.ErrInvalidParameter)
+	case name == "":
+		return nil, fmt.Errorf("%s: missing name: %w", op, dbw.ErrInvalidParameter)
+	}
+	opts := getOpts(opt...)
+	switch {
+	case opts.withMaxAttempts < 1:
+		return nil, fmt.Errorf("%s: max attempts must be at least 1: %w", op, dbw.ErrInvalidParameter)
+	case opts.withLease <= 0:
+		return nil, fmt.Errorf("%s: lease must be greater than zero: %w", op, dbw.ErrInvalidParameter)
+	case opts.withBackoff == nil:
+		return nil, fmt.Errorf("%s: missing backoff: %w", op, dbw.ErrInvalidParameter)
+	case opts.withNow == nil:
+		return nil, fmt.Errorf("%s: missing now func: %w", op, dbw.ErrInvalidParameter)
+	}
+	return &Queue{
+		rw:          rw,
+		name:        name,
+		maxAttempts: opts.withMaxAttempts,
+		lease:       opts.withLease,
+		backoff:     opts.withBackoff,
+		now:         opts.withNow,
+		txRetries:   opts.withTxRetries,
+	}, nil
+}
+
+// Name returns the name of the queue
+func (q *Queue) Name() string {
+	return q.name
+}
+
+// Enqueue will add a job with the payload to the queue using the writer. The
+// writer can be bound to an existing transaction (see dbw.RW.DoTx), which
+// makes the job atomic with the transaction's other writes.  Supported
+// options: WithPriority, WithRunAt, WithUniqueKey and WithMaxAttempts.
+func (q *Queue) Enqueue(ctx context.Context, w dbw.Writer, payload []byte, opt ...Option) (*Job, error) {
+	const op = "queue.(Queue).Enqueue"
+	if w == nil {
+		return nil, fmt.Errorf("%s: missing writer: %w", op, dbw.ErrInvalidParameter)
+	}
+	opts := getOpts(append([]Option{WithMaxAttempts(q.maxAttempts)}, opt...)...)
+	if opts.withMaxAttempts < 1 {
+		return nil, fmt.Errorf("%s: max attempts must be at least 1: %w", op, dbw.ErrInvalidParameter)
+	}
+	id, err := dbw.NewId("job")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	runAt := opts.withRunAt
+	if runAt.IsZero() {
+		runAt = q.now()
+	}
+	job := &Job{
+		Id:          id,
+		Queue:       q.name,
+		Priority:    opts.withPriority,
+		Payload:     payload,
+		Status:      Pending,
+		MaxAttempts: opts.withMaxAttempts,
+		RunAt:       runAt.UTC(),
+	}
+	var createOpts []dbw.Option
+	var rowsAffected int64
+	if opts.withUniqueKey != "" {
+		job.UniqueKey = &opts.withUniqueKey
+		createOpts = append(createOpts,
+			dbw.WithOnConflict(&dbw.OnConflict{
+				Target: dbw.Columns{"queue", "unique_key"},
+				Action: dbw.DoNothing(true),
+			}),
+			dbw.WithReturnRowsAffected(&rowsAffected),
+		)
+	}
+	if err := w.Create(ctx, job, createOpts...); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if opts.withUniqueKey != "" && rowsAffected == 0 {
+		return nil, fmt.Errorf("%s: unique key %q: %w", op, opts.withUniqueKey, ErrDuplicateJob)
+	}
+	return job, nil
+}
+
+// Claim will claim up to n jobs for the worker.  A job can be claimed when
+// it's pending and its RunAt time has passed, or when it's running and its
+// lease has expired.  Claimed jobs are leased to the worker for the queue's
+// lease duration and their attempts are incremented.  Running jobs with an
+// expired lease which have exhausted their attempts are dead lettered instead
+// of being claimed.
+func (q *Queue) Claim(ctx context.Context, workerId string, n int) ([]*Job, error) {
+	const op = "queue.(Queue).Claim"
+	switch {
+	case workerId == "":
+		return nil, fmt.Errorf("%s: missing worker id: %w", op, dbw.ErrInvalidParameter)
+	case n < 1:
+		return nil, fmt.Errorf("%s: n must be at least 1: %w", op, dbw.ErrInvalidParameter)
+	}
+	var claimed []*Job
+	_, err := q.rw.DoTx(ctx, retryable, q.txRetries, q.backoff, func(r dbw.Reader, w dbw.Writer) error {
+		claimed = nil
+		now := q.now().UTC()
+		var candidates []*Job
+		// workers don't block each other, they simply skip the jobs which
+		// are already being claimed by another worker.
+		err := r.SearchWhere(ctx, &candidates,
+			"queue = ? and ((status = ? and run_at <= ?) or (status = ? and lease_expires_at < ?))",
+			[]interface{}{q.name, Pending, now, Running, now},
+			dbw.WithLimit(n),
+			dbw.WithOrder("priority desc, run_at asc"),
+			dbw.WithLock(dbw.ForUpdate, dbw.SkipLocked),
+		)
+		if err != nil {
+			return err
+		}
+		for _, j := range candidates {
+			prevStatus, prevAttempts := j.Status, j.Attempts
+			guard := dbw.WithWhere("status = ? and attempts = ?", prevStatus, prevAttempts)
+			if j.Status == Running && j.Attempts >= j.MaxAttempts {
+				j.Status = Dead
+				j.LastError = "lease expired"
+				j.UniqueKey = nil
+				if _, err := w.Update(ctx, j, []string{"Status", "LastError"}, []string{"LeaseOwner", "LeaseExpiresAt", "UniqueKey"}, guard); err != nil {
+					return err
+				}
+				continue
+			}
+			leaseExpiresAt := now.Add(q.lease)
+			j.Status = Running
+			j.Attempts++
+			j.LeaseOwner = workerId
+			j.LeaseExpiresAt = &leaseExpiresAt
+			rowsUpdated, err := w.Update(ctx, j, []string{"Status", "Attempts", "LeaseOwner", "LeaseExpiresAt"}, nil, guard)
+			if err != nil {
+				return err
+			}
+			if rowsUpdated == 0 {
+				// another worker claimed it first
+				continue
+			}
+			claimed = append(claimed, j)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return claimed, nil
+}
+
+// Heartbeat will extend the lease for a job which is held by the job's
+// LeaseOwner.  ErrLeaseLost is returned if the lease is no longer held.
+func (q *Queue) Heartbeat(ctx context.Context, job *Job) error {
+	const op = "queue.(Queue).Heartbeat"
+	if err := validateLeasedJob(job); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	leaseExpiresAt := q.now().UTC().Add(q.lease)
+	j := *job
+	j.LeaseExpiresAt = &leaseExpiresAt
+	rowsUpdated, err := q.rw.Update(ctx, &j, []string{"LeaseExpiresAt"}, nil, leaseGuard(job))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if rowsUpdated == 0 {
+		return fmt.Errorf("%s: job %s: %w", op, job.Id, ErrLeaseLost)
+	}
+	*job = j
+	return nil
+}
+
+// Complete will mark the job as completed using the writer.  The writer can be
+// bound to an existing transaction (see dbw.RW.DoTx), which makes the job's
+// completion atomic with the transaction's other writes. ErrLeaseLost is
+// returned if the job's lease is no longer held by its LeaseOwner.
+func (q *Queue) Complete(ctx context.Context, w dbw.Writer, job *Job) error {
+	const op = "queue.(Queue).Complete"
+	if w == nil {
+		return fmt.Errorf("%s: missing writer: %w", op, dbw.ErrInvalidParameter)
+	}
+	if err := validateLeasedJob(job); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	j := *job
+	j.Status = Completed
+	j.UniqueKey = nil
+	rowsUpdated, err := w.Update(ctx, &j, []string{"Status"}, []string{"LeaseOwner", "LeaseExpiresAt", "UniqueKey"}, leaseGuard(job))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if rowsUpdated == 0 {
+		return fmt.Errorf("%s: job %s: %w", op, job.Id, ErrLeaseLost)
+	}
+	*job = j
+	return nil
+}
+
+// Fail will record the jobErr for the job using the writer.  If the job has
+// exhausted its attempts it's dead lettered, otherwise it's returned to
+// pending and will be retried once the queue's backoff has elapsed.
+// ErrLeaseLost is returned if the job's lease is no longer held by its
+// LeaseOwner.
+func (q *Queue) Fail(ctx context.Context, w dbw.Writer, job *Job, jobErr error) error {
+	const op = "queue.(Queue).Fail"
+	if w == nil {
+		return fmt.Errorf("%s: missing writer: %w", op, dbw.ErrInvalidParameter)
+	}
+	if err := validateLeasedJob(job); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	j := *job
+	fieldMask := []string{"Status"}
+	nullPaths := []string{"LeaseOwner", "LeaseExpiresAt"}
+	switch {
+	case j.Attempts >= j.MaxAttempts:
+		j.Status = Dead
+		j.UniqueKey = nil
+		nullPaths = append(nullPaths, "UniqueKey")
+	default:
+		j.Status = Pending
+		j.RunAt = q.now().UTC().Add(q.backoff.Duration(uint(j.Attempts)))
+		fieldMask = append(fieldMask, "RunAt")
+	}
+	switch {
+	case jobErr != nil:
+		j.LastError = jobErr.Error()
+		fieldMask = append(fieldMask, "LastError")
+	default:
+		nullPaths = append(nullPaths, "LastError")
+	}
+	rowsUpdated, err := w.Update(ctx, &j, fieldMask, nullPaths, leaseGuard(job))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if rowsUpdated == 0 {
+		return fmt.Errorf("%s: job %s: %w", op, job.Id, ErrLeaseLost)
+	}
+	*job = j
+	return nil
+}
+
+// Process will claim a single job for the worker and run the handler within a
+// transaction.  If the handler succeeds, the job is completed in the same
+// transaction as the handler's writes. If the handler returns an error, the
+// transaction is rolled back and the job is failed (see Fail(...)) in a
+// separate transaction.  Process returns false when there are no jobs to
+// claim.  The handler's error is returned after the job has been failed.
+func (q *Queue) Process(ctx context.Context, workerId string, h Handler) (bool, error) {
+	const op = "queue.(Queue).Process"
+	if h == nil {
+		return false, fmt.Errorf("%s: missing handler: %w", op, dbw.ErrInvalidParameter)
+	}
+	jobs, err := q.Claim(ctx, workerId, 1)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(jobs) == 0 {
+		return false, nil
+	}
+	job := jobs[0]
+	var handlerErr error
+	_, err = q.rw.DoTx(ctx, func(error) bool { return false }, 0, q.backoff, func(r dbw.Reader, w dbw.Writer) error {
+		j := *job
+		if err := h(ctx, r, w, &j); err != nil {
+			handlerErr = err
+			return err
+		}
+		if err := q.Complete(ctx, w, &j); err != nil {
+			return err
+		}
+		*job = j
+		return nil
+	})
+	if err == nil {
+		return true, nil
+	}
+	if handlerErr == nil {
+		return true, fmt.Errorf("%s: %w", op, err)
+	}
+	_, err = q.rw.DoTx(ctx, retryable, q.txRetries, q.backoff, func(_ dbw.Reader, w dbw.Writer) error {
+		j := *job
+		if err := q.Fail(ctx, w, &j, handlerErr); err != nil {
+			return err
+		}
+		*job = j
+		return nil
+	})
+	if err != nil {
+		return true, fmt.Errorf("%s: unable to fail job %s: %w", op, job.Id, err)
+	}
+	return true, fmt.Errorf("%s: job %s: %w", op, job.Id, handlerErr)
+}
+
+// DeadLetters returns the queue's dead jobs.  The WithLimit option is
+// supported.
+func (q *Queue) DeadLetters(ctx context.Context, r dbw.Reader, opt ...Option) ([]*Job, error) {
+	const op = "queue.(Queue).DeadLetters"
+	if r == nil {
+		return nil, fmt.Errorf("%s: missing reader: %w", op, dbw.ErrInvalidParameter)
+	}
+	opts := getOpts(opt...)
+	var jobs []*Job
+	if err := r.SearchWhere(ctx, &jobs, "queue = ? and status = ?", []interface{}{q.name, Dead}, dbw.WithLimit(opts.withLimit), dbw.WithOrder("run_at asc")); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return jobs, nil
+}
+
+// Requeue will return a dead job to pending with its attempts reset, so it
+// can be claimed again.
+func (q *Queue) Requeue(ctx context.Context, w dbw.Writer, job *Job) error {
+	const op = "queue.(Queue).Requeue"
+	switch {
+	case w == nil:
+		return fmt.Errorf("%s: missing writer: %w", op, dbw.ErrInvalidParameter)
+	case job == nil:
+		return fmt.Errorf("%s: missing job: %w", op, dbw.ErrInvalidParameter)
+	case job.Id == "":
+		return fmt.Errorf("%s: missing job id: %w", op, dbw.ErrInvalidParameter)
+	}
+	j := *job
+	j.Status = Pending
+	j.Attempts = 0
+	j.RunAt = q.now().UTC()
+	rowsUpdated, err := w.Update(ctx, &j, []string{"Status", "Attempts", "RunAt"}, nil, dbw.WithWhere("status = ?", Dead))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if rowsUpdated == 0 {
+		return fmt.Errorf("%s: job %s is not dead: %w", op, job.Id, dbw.ErrInvalidParameter)
+	}
+	*job = j
+	return nil
+}
+
+func validateLeasedJob(job *Job) error {
+	const op = "queue.validateLeasedJob"
+	switch {
+	case job == nil:
+		return fmt.Errorf("%s: missing job: %w", op, dbw.ErrInvalidParameter)
+	case job.Id == "":
+		return fmt.Errorf("%s: missing job id: %w", op, dbw.ErrInvalidParameter)
+	case job.LeaseOwner == "":
+		return fmt.Errorf("%s: missing lease owner: %w", op, dbw.ErrInvalidParameter)
+	}
+	return nil
+}
+
+func leaseGuard(job *Job) dbw.Option {
+	return dbw.WithWhere("status = ? and lease_owner = ? and attempts = ?", Running, job.LeaseOwner, job.Attempts)
+}
+
+// retryable is the retry func for the queue's transactions.  Invalid
+// parameters, lost leases and cancelled contexts are never retried.
+func retryable(err error) bool {
+	switch {
+	case errors.Is(err, dbw.ErrInvalidParameter),
+		errors.Is(err, ErrLeaseLost),
+		errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded):
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,400 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-dbw"
+	"github.com/hashicorp/go-dbw/queue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testQueue(t *testing.T, opt ...queue.Option) (*dbw.RW, *queue.Queue, *testClock) {
+	t.Helper()
+	require := require.New(t)
+	conn, _ := dbw.TestSetup(t)
+	rw := dbw.New(conn)
+	dbType, _, err := rw.Dialect()
+	require.NoError(err)
+	schema, err := queue.Schema(dbType)
+	if errors.Is(err, dbw.ErrInvalidParameter) {
+		t.Skipf("the queue is not supported for %s", dbType)
+	}
+	require.NoError(err)
+	_, err = rw.Exec(context.Background(), schema, nil)
+	require.NoError(err)
+
+	clock := &testClock{now: time.Now().UTC().Truncate(time.Second)}
+	opt = append([]queue.Option{queue.WithNowFunc(clock.Now), queue.WithBackoff(dbw.ConstBackoff{DurationMs: 1000})}, opt...)
+	q, err := queue.New(rw, "test-queue", opt...)
+	require.NoError(err)
+	return rw, q, clock
+}
+
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+	conn, _ := dbw.TestSetup(t)
+	rw := dbw.New(conn)
+	tests := []struct {
+		name      string
+		rw        *dbw.RW
+		queueName string
+		opt       []queue.Option
+		wantErr   bool
+	}{
+		{name: "valid", rw: rw, queueName: "q"},
+		{name: "missing-rw", queueName: "q", wantErr: true},
+		{name: "missing-name", rw: rw, wantErr: true},
+		{name: "zero-max-attempts", rw: rw, queueName: "q", opt: []queue.Option{queue.WithMaxAttempts(0)}, wantErr: true},
+		{name: "zero-lease", rw: rw, queueName: "q", opt: []queue.Option{queue.WithLease(0)}, wantErr: true},
+		{name: "nil-backoff", rw: rw, queueName: "q", opt: []queue.Option{queue.WithBackoff(nil)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert, require := assert.New(t), require.New(t)
+			got, err := queue.New(tt.rw, tt.queueName, tt.opt...)
+			if tt.wantErr {
+				require.Error(err)
+				assert.ErrorIs(err, dbw.ErrInvalidParameter)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tt.queueName, got.Name())
+		})
+	}
+}
+
+func TestQueue_Enqueue(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	rw, q, clock := testQueue(t)
+
+	t.Run("defaults", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		job, err := q.Enqueue(testCtx, rw, []byte("payload"))
+		require.NoError(err)
+		assert.NotEmpty(job.Id)
+		assert.Equal(queue.Pending, job.Status)
+		assert.Equal(queue.DefaultMaxAttempts, job.MaxAttempts)
+		assert.True(clock.Now().Equal(job.RunAt))
+
+		found := &queue.Job{Id: job.Id}
+		require.NoError(rw.LookupBy(testCtx, found))
+		assert.Equal([]byte("payload"), found.Payload)
+		assert.Equal("test-queue", found.Queue)
+	})
+	t.Run("within-tx", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		rollbackErr := errors.New("rollback")
+		var job *queue.Job
+		_, err := rw.DoTx(testCtx, func(error) bool { return false }, 0, dbw.ConstBackoff{}, func(_ dbw.Reader, w dbw.Writer) error {
+			var err error
+			job, err = q.Enqueue(testCtx, w, nil)
+			require.NoError(err)
+			return rollbackErr
+		})
+		require.ErrorIs(err, rollbackErr)
+		err = rw.LookupBy(testCtx, &queue.Job{Id: job.Id})
+		assert.ErrorIs(err, dbw.ErrRecordNotFound)
+	})
+	t.Run("unique-key", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		_, err := q.Enqueue(testCtx, rw, nil, queue.WithUniqueKey("unique"))
+		require.NoError(err)
+		_, err = q.Enqueue(testCtx, rw, nil, queue.WithUniqueKey("unique"))
+		require.Error(err)
+		assert.ErrorIs(err, queue.ErrDuplicateJob)
+
+		// the key is unique per queue
+		other, err := queue.New(rw, "other-queue")
+		require.NoError(err)
+		_, err = other.Enqueue(testCtx, rw, nil, queue.WithUniqueKey("unique"))
+		require.NoError(err)
+	})
+	t.Run("missing-writer", func(t *testing.T) {
+		_, err := q.Enqueue(testCtx, nil, nil)
+		assert.ErrorIs(t, err, dbw.ErrInvalidParameter)
+	})
+}
+
+func TestQueue_Claim(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+
+	t.Run("priority-and-run-at", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		rw, q, clock := testQueue(t)
+		low, err := q.Enqueue(testCtx, rw, nil, queue.WithPriority(1))
+		require.NoError(err)
+		high, err := q.Enqueue(testCtx, rw, nil, queue.WithPriority(10))
+		require.NoError(err)
+		_, err = q.Enqueue(testCtx, rw, nil, queue.WithPriority(100), queue.WithRunAt(clock.Now().Add(time.Hour)))
+		require.NoError(err)
+
+		jobs, err := q.Claim(testCtx, "worker", 10)
+		require.NoError(err)
+		require.Len(jobs, 2)
+		assert.Equal(high.Id, jobs[0].Id)
+		assert.Equal(low.Id, jobs[1].Id)
+		for _, j := range jobs {
+			assert.Equal(queue.Running, j.Status)
+			assert.Equal(1, j.Attempts)
+			assert.Equal("worker", j.LeaseOwner)
+			require.NotNil(j.LeaseExpiresAt)
+			assert.True(clock.Now().Add(queue.DefaultLease).Equal(*j.LeaseExpiresAt))
+		}
+
+		jobs, err = q.Claim(testCtx, "worker", 10)
+		require.NoError(err)
+		assert.Empty(jobs)
+	})
+	t.Run("expired-lease", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		rw, q, clock := testQueue(t, queue.WithLease(time.Minute), queue.WithMaxAttempts(2))
+		job, err := q.Enqueue(testCtx, rw, nil)
+		require.NoError(err)
+
+		jobs, err := q.Claim(testCtx, "worker-1", 1)
+		require.NoError(err)
+		require.Len(jobs, 1)
+
+		clock.Add(2 * time.Minute)
+		jobs, err = q.Claim(testCtx, "worker-2", 1)
+		require.NoError(err)
+		require.Len(jobs, 1)
+		assert.Equal(job.Id, jobs[0].Id)
+		assert.Equal("worker-2", jobs[0].LeaseOwner)
+		assert.Equal(2, jobs[0].Attempts)
+
+		// the second lease expires and the job is out of attempts, so it's
+		// dead lettered
+		clock.Add(2 * time.Minute)
+		jobs, err = q.Claim(testCtx, "worker-3", 1)
+		require.NoError(err)
+		assert.Empty(jobs)
+
+		dead, err := q.DeadLetters(testCtx, rw)
+		require.NoError(err)
+		require.Len(dead, 1)
+		assert.Equal(job.Id, dead[0].Id)
+		assert.Equal("lease expired", dead[0].LastError)
+	})
+	t.Run("invalid-parameters", func(t *testing.T) {
+		_, q, _ := testQueue(t)
+		_, err := q.Claim(testCtx, "", 1)
+		assert.ErrorIs(t, err, dbw.ErrInvalidParameter)
+		_, err = q.Claim(testCtx, "worker", 0)
+		assert.ErrorIs(t, err, dbw.ErrInvalidParameter)
+	})
+}
+
+func TestQueue_Heartbeat(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	assert, require := assert.New(t), require.New(t)
+	rw, q, clock := testQueue(t, queue.WithLease(time.Minute))
+	_, err := q.Enqueue(testCtx, rw, nil)
+	require.NoError(err)
+	jobs, err := q.Claim(testCtx, "worker", 1)
+	require.NoError(err)
+	require.Len(jobs, 1)
+	job := jobs[0]
+
+	clock.Add(30 * time.Second)
+	require.NoError(q.Heartbeat(testCtx, job))
+	assert.True(clock.Now().Add(time.Minute).Equal(*job.LeaseExpiresAt))
+
+	stolen := *job
+	stolen.LeaseOwner = "another-worker"
+	err = q.Heartbeat(testCtx, &stolen)
+	assert.ErrorIs(err, queue.ErrLeaseLost)
+
+	err = q.Heartbeat(testCtx, &queue.Job{})
+	assert.ErrorIs(err, dbw.ErrInvalidParameter)
+}
+
+func TestQueue_CompleteAndFail(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	t.Run("complete", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		rw, q, _ := testQueue(t)
+		_, err := q.Enqueue(testCtx, rw, nil)
+		require.NoError(err)
+		jobs, err := q.Claim(testCtx, "worker", 1)
+		require.NoError(err)
+		require.Len(jobs, 1)
+		require.NoError(q.Complete(testCtx, rw, jobs[0]))
+		assert.Equal(queue.Completed, jobs[0].Status)
+		assert.Empty(jobs[0].LeaseOwner)
+
+		// completing it again fails since the lease was released
+		jobs[0].LeaseOwner = "worker"
+		err = q.Complete(testCtx, rw, jobs[0])
+		assert.ErrorIs(err, queue.ErrLeaseLost)
+	})
+	t.Run("fail-with-retry-then-dead", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		rw, q, clock := testQueue(t, queue.WithMaxAttempts(2))
+		_, err := q.Enqueue(testCtx, rw, nil)
+		require.NoError(err)
+
+		jobs, err := q.Claim(testCtx, "worker", 1)
+		require.NoError(err)
+		require.Len(jobs, 1)
+		require.NoError(q.Fail(testCtx, rw, jobs[0], errors.New("first failure")))
+		assert.Equal(queue.Pending, jobs[0].Status)
+		assert.Equal("first failure", jobs[0].LastError)
+		assert.True(clock.Now().Add(time.Second).Equal(jobs[0].RunAt))
+
+		// not claimable until the backoff has passed
+		jobs, err = q.Claim(testCtx, "worker", 1)
+		require.NoError(err)
+		assert.Empty(jobs)
+
+		clock.Add(time.Second)
+		jobs, err = q.Claim(testCtx, "worker", 1)
+		require.NoError(err)
+		require.Len(jobs, 1)
+		require.NoError(q.Fail(testCtx, rw, jobs[0], errors.New("second failure")))
+		assert.Equal(queue.Dead, jobs[0].Status)
+
+		dead, err := q.DeadLetters(testCtx, rw)
+		require.NoError(err)
+		require.Len(dead, 1)
+
+		require.NoError(q.Requeue(testCtx, rw, dead[0]))
+		assert.Equal(queue.Pending, dead[0].Status)
+		assert.Equal(0, dead[0].Attempts)
+		err = q.Requeue(testCtx, rw, dead[0])
+		assert.ErrorIs(err, dbw.ErrInvalidParameter)
+	})
+	t.Run("unique-key-released", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		rw, q, _ := testQueue(t, queue.WithMaxAttempts(1))
+		key := queue.WithUniqueKey("unique")
+
+		// completed
+		_, err := q.Enqueue(testCtx, rw, nil, key)
+		require.NoError(err)
+		jobs, err := q.Claim(testCtx, "worker", 1)
+		require.NoError(err)
+		require.Len(jobs, 1)
+		_, err = q.Enqueue(testCtx, rw, nil, key)
+		assert.ErrorIs(err, queue.ErrDuplicateJob)
+		require.NoError(q.Complete(testCtx, rw, jobs[0]))
+		assert.Nil(jobs[0].UniqueKey)
+
+		// dead lettered
+		_, err = q.Enqueue(testCtx, rw, nil, key)
+		require.NoError(err)
+		jobs, err = q.Claim(testCtx, "worker", 1)
+		require.NoError(err)
+		require.Len(jobs, 1)
+		require.NoError(q.Fail(testCtx, rw, jobs[0], errors.New("failure")))
+		assert.Equal(queue.Dead, jobs[0].Status)
+		assert.Nil(jobs[0].UniqueKey)
+
+		_, err = q.Enqueue(testCtx, rw, nil, key)
+		require.NoError(err)
+	})
+}
+
+func TestQueue_Process(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	t.Run("success", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		rw, q, _ := testQueue(t)
+		job, err := q.Enqueue(testCtx, rw, []byte("hello"))
+		require.NoError(err)
+
+		processed, err := q.Process(testCtx, "worker", func(ctx context.Context, _ dbw.Reader, w dbw.Writer, j *queue.Job) error {
+			assert.Equal([]byte("hello"), j.Payload)
+			// writes within the handler are part of the job's transaction
+			_, err := q.Enqueue(ctx, w, []byte("follow-up"))
+			return err
+		})
+		require.NoError(err)
+		assert.True(processed)
+
+		found := &queue.Job{Id: job.Id}
+		require.NoError(rw.LookupBy(testCtx, found))
+		assert.Equal(queue.Completed, found.Status)
+
+		var pending []*queue.Job
+		require.NoError(rw.SearchWhere(testCtx, &pending, "status = ?", []interface{}{queue.Pending}))
+		require.Len(pending, 1)
+		assert.Equal([]byte("follow-up"), pending[0].Payload)
+	})
+	t.Run("handler-error", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		rw, q, _ := testQueue(t)
+		job, err := q.Enqueue(testCtx, rw, nil)
+		require.NoError(err)
+
+		handlerErr := errors.New("handler failed")
+		processed, err := q.Process(testCtx, "worker", func(ctx context.Context, _ dbw.Reader, w dbw.Writer, _ *queue.Job) error {
+			// this write is rolled back along with the handler's transaction
+			_, err := q.Enqueue(ctx, w, []byte("rolled-back"))
+			require.NoError(err)
+			return handlerErr
+		})
+		require.Error(err)
+		assert.ErrorIs(err, handlerErr)
+		assert.True(processed)
+
+		found := &queue.Job{Id: job.Id}
+		require.NoError(rw.LookupBy(testCtx, found))
+		assert.Equal(queue.Pending, found.Status)
+		assert.Equal(handlerErr.Error(), found.LastError)
+
+		var jobs []*queue.Job
+		require.NoError(rw.SearchWhere(testCtx, &jobs, "", nil))
+		assert.Len(jobs, 1)
+	})
+	t.Run("empty", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		_, q, _ := testQueue(t)
+		processed, err := q.Process(testCtx, "worker", func(context.Context, dbw.Reader, dbw.Writer, *queue.Job) error {
+			return nil
+		})
+		require.NoError(err)
+		assert.False(processed)
+	})
+}
+
+func TestSchema(t *testing.T) {
+	t.Parallel()
+	for _, typ := range []dbw.DbType{dbw.Postgres, dbw.Sqlite} {
+		got, err := queue.Schema(typ)
+		require.NoError(t, err)
+		assert.NotEmpty(t, got)
+	}
+	_, err := queue.Schema(dbw.UnknownDB)
+	assert.ErrorIs(t, err, dbw.ErrInvalidParameter)
+}
@@ -0,0 +1,34 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/go-dbw"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_retryable(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "other", err: errors.New("serialization failure"), want: true},
+		{name: "invalid-parameter", err: fmt.Errorf("wrapped: %w", dbw.ErrInvalidParameter)},
+		{name: "lease-lost", err: fmt.Errorf("wrapped: %w", ErrLeaseLost)},
+		{name: "canceled", err: context.Canceled},
+		{name: "deadline-exceeded", err: context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, retryable(tt.err))
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package queue
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-dbw"
+)
+
+// Schema returns the DDL required to create the queue's table for the db
+// type.  dbw intentionally doesn't manage migrations, so it's up to the
+// caller to include the returned DDL in their own migrations.
+func Schema(dbType dbw.DbType) (string, error) {
+	const op = "queue.Schema"
+	switch dbType {
+	case dbw.Postgres:
+		return schemaPostgres, nil
+	case dbw.Sqlite:
+		return schemaSqlite, nil
+	default:
+		return "", fmt.Errorf("%s: unsupported db type %s: %w", op, dbType, dbw.ErrInvalidParameter)
+	}
+}
+
+const (
+	schemaPostgres = `
+create table if not exists dbw_queue_job (
+  id text constraint dbw_queue_job_pkey primary key,
+  queue text not null,
+  priority int not null default 0,
+  payload bytea,
+  status text not null default 'pending'
+    constraint dbw_queue_job_status_valid
+    check(status in ('pending', 'running', 'completed', 'dead')),
+  attempts int not null default 0,
+  max_attempts int not null,
+  unique_key text,
+  run_at timestamp with time zone not null default current_timestamp,
+  lease_owner text,
+  lease_expires_at timestamp with time zone,
+  last_error text,
+  create_time timestamp with time zone not null default current_timestamp,
+  constraint dbw_queue_job_unique_key_uq
+    unique(queue, unique_key)
+);
+
+create index if not exists dbw_queue_job_claim_ix
+  on dbw_queue_job (queue, status, priority desc, run_at);
+`
+
+	schemaSqlite = `
+create table if not exists dbw_queue_job (
+  id text constraint dbw_queue_job_pkey primary key,
+  queue text not null,
+  priority int not null default 0,
+  payload blob,
+  status text not null default 'pending'
+    constraint dbw_queue_job_status_valid
+    check(status in ('pending', 'running', 'completed', 'dead')),
+  attempts int not null default 0,
+  max_attempts int not null,
+  unique_key text,
+  run_at timestamp not null default current_timestamp,
+  lease_owner text,
+  lease_expires_at timestamp,
+  last_error text,
+  create_time timestamp not null default current_timestamp,
+  constraint dbw_queue_job_unique_key_uq
+    unique(queue, unique_key)
+);
+
+create index if not exists dbw_queue_job_claim_ix
+  on dbw_queue_job (queue, status, priority desc, run_at);
+`
+)
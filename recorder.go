@@ -0,0 +1,118 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// RecordedStatement is a statement captured by a Recorder
+type RecordedStatement struct {
+	// SQL of the statement with placeholders for its Vars
+	SQL string
+
+	// Vars are the statement's args
+	Vars []interface{}
+
+	// Table of the statement's model, which is empty for raw sql
+	Table string
+
+	// RowsAffected by the statement, which isn't set for queries via
+	// Query(...)
+	RowsAffected int64
+
+	// InTx is true if the statement was executed in a transaction
+	InTx bool
+
+	// Err returned by the statement
+	Err error
+}
+
+// Recorder captures every statement executed by a DB, which is useful for
+// asserting the queries issued by tests (see AssertQueryCount(...)).  A
+// Recorder is used with the WithRecorder(...) option and it's safe for
+// concurrent use.
+type Recorder struct {
+	mu         sync.Mutex
+	statements []RecordedStatement
+}
+
+// NewRecorder creates a new Recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Statements returns the statements recorded since the Recorder was created
+// or reset.
+func (r *Recorder) Statements() []RecordedStatement {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedStatement(nil), r.statements...)
+}
+
+// Matching returns the recorded statements with sql matching the regular
+// expression.
+func (r *Recorder) Matching(pattern string) ([]RecordedStatement, error) {
+	const op = "dbw.(Recorder).Matching"
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	var matching []RecordedStatement
+	for _, s := range r.Statements() {
+		if re.MatchString(s.SQL) {
+			matching = append(matching, s)
+		}
+	}
+	return matching, nil
+}
+
+// Reset removes all the recorded statements
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements = nil
+}
+
+func (r *Recorder) record(tx *gorm.DB) {
+	if tx.Statement == nil || tx.Statement.SQL.Len() == 0 {
+		return
+	}
+	_, inTx := tx.Statement.ConnPool.(gorm.TxCommitter)
+	s := RecordedStatement{
+		SQL:          tx.Statement.SQL.String(),
+		Vars:         append([]interface{}(nil), tx.Statement.Vars...),
+		Table:        tx.Statement.Table,
+		RowsAffected: tx.RowsAffected,
+		InTx:         inTx,
+		Err:          tx.Error,
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements = append(r.statements, s)
+}
+
+// register the Recorder's callbacks
+func (r *Recorder) register(db *gorm.DB) error {
+	const op = "dbw.(Recorder).register"
+	const name = "dbw:recorder"
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().After("gorm:create").Register(name, r.record),
+		cb.Query().After("gorm:query").Register(name, r.record),
+		cb.Update().After("gorm:update").Register(name, r.record),
+		cb.Delete().After("gorm:delete").Register(name, r.record),
+		cb.Row().After("gorm:row").Register(name, r.record),
+		cb.Raw().After("gorm:raw").Register(name, r.record),
+	} {
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	return nil
+}
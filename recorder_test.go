@@ -0,0 +1,90 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/go-dbw"
+	"github.com/hashicorp/go-dbw/internal/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	r := dbw.NewRecorder()
+	db, _ := dbw.TestSetup(t, dbw.WithTestRecorder(r))
+	rw := dbw.New(db)
+
+	// migrations aren't recorded
+	dbw.AssertQueryCount(t, r, 0)
+
+	t.Run("create-and-lookup", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		r.Reset()
+		user, err := dbtest.NewTestUser()
+		require.NoError(err)
+		user.Name = "recorded"
+		require.NoError(rw.Create(testCtx, user))
+		found := dbtest.AllocTestUser()
+		found.PublicId = user.PublicId
+		require.NoError(rw.LookupBy(testCtx, &found))
+
+		dbw.AssertQueryCount(t, r, 2)
+		dbw.AssertTableTouched(t, r, "db_test_user")
+		dbw.AssertNoFullScan(t, r, "db_test_user")
+		dbw.AssertNoQueriesMatching(t, r, `(?i)^delete`)
+
+		statements := r.Statements()
+		assert.Regexp(`(?i)^insert into`, statements[0].SQL)
+		assert.Contains(statements[0].Vars, "recorded")
+		assert.Equal(int64(1), statements[0].RowsAffected)
+		assert.False(statements[0].InTx)
+		assert.NoError(statements[0].Err)
+		assert.Regexp(`(?i)^select`, statements[1].SQL)
+	})
+	t.Run("full-scan", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		r.Reset()
+		var users []*dbtest.TestUser
+		require.NoError(rw.SearchWhere(testCtx, &users, "", nil))
+		statements := r.Statements()
+		require.Len(statements, 1)
+		assert.NotRegexp(`(?i)\bwhere\b`, statements[0].SQL)
+	})
+	t.Run("in-tx", func(t *testing.T) {
+		r.Reset()
+		_, err := rw.DoTx(testCtx, func(error) bool { return false }, 0, dbw.ExpBackoff{}, func(_ dbw.Reader, w dbw.Writer) error {
+			user, err := dbtest.NewTestUser()
+			if err != nil {
+				return err
+			}
+			return w.Create(testCtx, user)
+		})
+		require.NoError(t, err)
+		dbw.AssertInTx(t, r, `(?i)^insert`)
+		dbw.AssertQueryCount(t, r, 1)
+	})
+	t.Run("raw", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		r.Reset()
+		_, err := rw.Exec(testCtx, "delete from db_test_user where name = ?", []interface{}{"nobody"})
+		require.NoError(err)
+		rows, err := rw.Query(testCtx, "select count(*) from db_test_user", nil)
+		require.NoError(err)
+		require.NoError(rows.Close())
+
+		matching, err := r.Matching(`(?i)^delete`)
+		require.NoError(err)
+		if assert.Len(matching, 1) {
+			assert.Equal([]interface{}{"nobody"}, matching[0].Vars)
+		}
+		dbw.AssertQueryCount(t, r, 2)
+		_, err = r.Matching(`(`)
+		assert.Error(err)
+	})
+}
@@ -0,0 +1,178 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+)
+
+// LoadRelated loads the children of the parents into each parent's
+// childField, which avoids a query per parent (N+1 queries).  The parents
+// must be a slice of pointers to structs, which are the same type.
+//
+// The childField is the name of a parent's field whose type is a slice of
+// children (has many) or a pointer to a child (has one), and it must be
+// ignored by gorm with a `gorm:"-"` tag, so creating and updating the parents
+// doesn't implicitly write the children.  The foreignKeyColumn is the column
+// of the children which references the parents' primary key, which must be a
+// single column.  Parents without a primary key are skipped.
+//
+// The children are searched with a batched "in" query for every chunk of
+// WithBatchSize parents, and they're assigned to the parent with the matching
+// key.  A parent's childField is reset before its children are assigned, and
+// for a has one relationship the last child found is assigned.
+//
+// Options supported: WithBatchSize, WithTable (the children's table),
+// WithOrder (the children's order), WithDebug and WithTimeout.
+func LoadRelated(ctx context.Context, rw *RW, parents interface{}, childField string, foreignKeyColumn string, opt ...Option) (retErr error) {
+	const op = "dbw.LoadRelated"
+	switch {
+	case rw == nil || rw.underlying == nil:
+		return fmt.Errorf("%s: missing underlying db: %w", op, ErrInvalidParameter)
+	case isNil(parents):
+		return fmt.Errorf("%s: missing parents: %w", op, ErrInvalidParameter)
+	case childField == "":
+		return fmt.Errorf("%s: missing child field: %w", op, ErrInvalidParameter)
+	case foreignKeyColumn == "":
+		return fmt.Errorf("%s: missing foreign key column: %w", op, ErrInvalidParameter)
+	}
+	valParents := reflect.ValueOf(parents)
+	if valParents.Kind() != reflect.Slice {
+		return fmt.Errorf("%s: not a slice: %w", op, ErrInvalidParameter)
+	}
+	if valParents.Len() == 0 {
+		return nil
+	}
+	parentType := valParents.Type().Elem()
+	if parentType.Kind() != reflect.Ptr || parentType.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%s: parents are not pointers to structs: %w", op, ErrInvalidParameter)
+	}
+	opts := GetOpts(opt...)
+
+	pDb := rw.underlying.wrapped.Model(reflect.New(parentType.Elem()).Interface())
+	if err := pDb.Statement.Parse(pDb.Statement.Model); err != nil || pDb.Statement.Schema == nil {
+		return fmt.Errorf("%s: unable to parse parents: %w", op, ErrInvalidParameter)
+	}
+	parentSchema := pDb.Statement.Schema
+	if len(parentSchema.PrimaryFields) != 1 {
+		return fmt.Errorf("%s: %s must have a single primary key: %w", op, parentSchema.Table, ErrInvalidParameter)
+	}
+	parentKey := parentSchema.PrimaryFields[0]
+	field := parentSchema.LookUpField(childField)
+	switch {
+	case field == nil || field.Name != childField:
+		return fmt.Errorf("%s: %s is not a field of %s: %w", op, childField, parentSchema.Name, ErrInvalidParameter)
+	case field.TagSettings["-"] != "-":
+		return fmt.Errorf("%s: %s field %s must be ignored by gorm: %w", op, parentSchema.Name, childField, ErrInvalidParameter)
+	}
+	hasMany := false
+	childType := field.FieldType
+	switch childType.Kind() {
+	case reflect.Slice:
+		hasMany = true
+		childType = childType.Elem()
+	case reflect.Ptr:
+	default:
+		return fmt.Errorf("%s: %s field %s is not a slice or pointer: %w", op, parentSchema.Name, childField, ErrInvalidParameter)
+	}
+	childPtrType := childType
+	if childPtrType.Kind() != reflect.Ptr {
+		childPtrType = reflect.PointerTo(childType)
+	}
+	if childPtrType.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%s: %s field %s is not a struct: %w", op, parentSchema.Name, childField, ErrInvalidParameter)
+	}
+
+	cDb := rw.underlying.wrapped.Model(reflect.New(childPtrType.Elem()).Interface())
+	if err := cDb.Statement.Parse(cDb.Statement.Model); err != nil || cDb.Statement.Schema == nil {
+		return fmt.Errorf("%s: unable to parse children: %w", op, ErrInvalidParameter)
+	}
+	foreignKey := cDb.Statement.Schema.LookUpField(foreignKeyColumn)
+	if foreignKey == nil || foreignKey.DBName != foreignKeyColumn {
+		return fmt.Errorf("%s: %s is not a column of %s: %w", op, foreignKeyColumn, cDb.Statement.Schema.Table, ErrInvalidParameter)
+	}
+
+	rw, ctx, done, err := rw.startTimeout(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { retErr = done(retErr) }()
+
+	// collect the parents by key, resetting their children
+	parentsByKey := map[string][]reflect.Value{}
+	keys := make([]interface{}, 0, valParents.Len())
+	for i := 0; i < valParents.Len(); i++ {
+		p := valParents.Index(i)
+		if p.IsNil() {
+			return fmt.Errorf("%s: parent %d is nil: %w", op, i, ErrInvalidParameter)
+		}
+		p = p.Elem()
+		field.ReflectValueOf(ctx, p).Set(reflect.Zero(field.FieldType))
+		val, isZero := parentKey.ValueOf(ctx, p)
+		if isZero {
+			continue
+		}
+		k := relatedKey(val)
+		if _, ok := parentsByKey[k]; !ok {
+			keys = append(keys, val)
+		}
+		parentsByKey[k] = append(parentsByKey[k], p)
+	}
+
+	batchSize := opts.WithBatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	where := fmt.Sprintf("%s in (?)", rw.underlying.dialect().Quote(foreignKey.DBName))
+	opt = append(opt[:len(opt):len(opt)], WithLimit(-1))
+	for start := 0; start < len(keys); start += batchSize {
+		end := start + batchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		children := reflect.New(reflect.SliceOf(childPtrType))
+		if err := rw.SearchWhere(ctx, children.Interface(), where, []interface{}{keys[start:end]}, opt...); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		for i := 0; i < children.Elem().Len(); i++ {
+			child := children.Elem().Index(i)
+			val, isZero := foreignKey.ValueOf(ctx, child.Elem())
+			if isZero {
+				continue
+			}
+			if childType.Kind() != reflect.Ptr {
+				child = child.Elem()
+			}
+			for _, p := range parentsByKey[relatedKey(val)] {
+				f := field.ReflectValueOf(ctx, p)
+				switch {
+				case hasMany:
+					f.Set(reflect.Append(f, child))
+				default:
+					f.Set(child)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// relatedKey returns the key which matches a parent's primary key with its
+// children's foreign key, even when their types differ (e.g. string and
+// *string).
+func relatedKey(val interface{}) string {
+	v := reflect.ValueOf(val)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if valuer, ok := v.Interface().(driver.Valuer); ok {
+		if dv, err := valuer.Value(); err == nil {
+			return fmt.Sprint(dv)
+		}
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,147 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/go-dbw"
+	"github.com/hashicorp/go-dbw/internal/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testUserWithRentals is a db_test_user which has many rentals
+type testUserWithRentals struct {
+	PublicId string `gorm:"primaryKey"`
+	Name     string
+	Rentals  []*dbtest.TestRental `gorm:"-"`
+}
+
+func (*testUserWithRentals) TableName() string { return "db_test_user" }
+
+// testCarWithRental is a db_test_car which has rentals, loaded as one rental
+// or as values
+type testCarWithRental struct {
+	PublicId string              `gorm:"primaryKey"`
+	Rental   *dbtest.TestRental  `gorm:"-"`
+	Rentals  []dbtest.TestRental `gorm:"-"`
+	Name     string
+}
+
+func (*testCarWithRental) TableName() string { return "db_test_car" }
+
+func TestLoadRelated(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	r := dbw.NewRecorder()
+	conn, _ := dbw.TestSetup(t, dbw.WithTestRecorder(r))
+	rw := dbw.New(conn)
+
+	// users[0] has two rentals, users[1] has one and users[2] has none
+	users := []*testUserWithRentals{}
+	for i := 0; i < 3; i++ {
+		u := testUser(t, rw, "", "", "")
+		users = append(users, &testUserWithRentals{PublicId: u.PublicId})
+	}
+	car1, car2 := testCar(t, rw), testCar(t, rw)
+	testRental(t, rw, users[0].PublicId, car1.PublicId)
+	testRental(t, rw, users[0].PublicId, car2.PublicId)
+	testRental(t, rw, users[1].PublicId, car1.PublicId)
+
+	assertRentals := func(t *testing.T, users []*testUserWithRentals) {
+		t.Helper()
+		assert := assert.New(t)
+		if assert.Len(users[0].Rentals, 2) {
+			carIds := []string{users[0].Rentals[0].CarId, users[0].Rentals[1].CarId}
+			assert.ElementsMatch([]string{car1.PublicId, car2.PublicId}, carIds)
+		}
+		if assert.Len(users[1].Rentals, 1) {
+			assert.Equal(users[1].PublicId, users[1].Rentals[0].UserId)
+		}
+		assert.Empty(users[2].Rentals)
+	}
+	order := dbw.WithOrder("user_id, car_id")
+
+	t.Run("has-many", func(t *testing.T) {
+		require := require.New(t)
+		r.Reset()
+		// the children are reset before they're loaded
+		users[2].Rentals = []*dbtest.TestRental{{}}
+		require.NoError(dbw.LoadRelated(testCtx, rw, users, "Rentals", "user_id", order))
+		assertRentals(t, users)
+		dbw.AssertQueryCount(t, r, 1)
+	})
+	t.Run("batches", func(t *testing.T) {
+		require := require.New(t)
+		r.Reset()
+		require.NoError(dbw.LoadRelated(testCtx, rw, users, "Rentals", "user_id", order, dbw.WithBatchSize(2)))
+		assertRentals(t, users)
+		dbw.AssertQueryCount(t, r, 2)
+	})
+	t.Run("duplicate-and-zero-keys", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		r.Reset()
+		dup := &testUserWithRentals{PublicId: users[0].PublicId}
+		zero := &testUserWithRentals{}
+		parents := append([]*testUserWithRentals{dup, zero}, users...)
+		require.NoError(dbw.LoadRelated(testCtx, rw, parents, "Rentals", "user_id", order))
+		assertRentals(t, users)
+		assert.Len(dup.Rentals, 2)
+		assert.Empty(zero.Rentals)
+		dbw.AssertQueryCount(t, r, 1)
+	})
+	t.Run("has-one-and-values", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		cars := []*testCarWithRental{{PublicId: car1.PublicId}, {PublicId: car2.PublicId}, {PublicId: testCar(t, rw).PublicId}}
+		require.NoError(dbw.LoadRelated(testCtx, rw, cars, "Rentals", "car_id", order))
+		assert.Len(cars[0].Rentals, 2)
+		assert.Len(cars[1].Rentals, 1)
+		assert.Empty(cars[2].Rentals)
+
+		require.NoError(dbw.LoadRelated(testCtx, rw, cars, "Rental", "car_id", order))
+		if assert.NotNil(cars[1].Rental) {
+			assert.Equal(users[0].PublicId, cars[1].Rental.UserId)
+		}
+		assert.Nil(cars[2].Rental)
+	})
+	t.Run("no-parents", func(t *testing.T) {
+		require := require.New(t)
+		r.Reset()
+		require.NoError(dbw.LoadRelated(testCtx, rw, []*testUserWithRentals{}, "Rentals", "user_id"))
+		dbw.AssertQueryCount(t, r, 0)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		type badField struct {
+			PublicId string `gorm:"primaryKey"`
+			Rentals  string `gorm:"-"`
+		}
+		tests := []struct {
+			name       string
+			rw         *dbw.RW
+			parents    interface{}
+			childField string
+			foreignKey string
+		}{
+			{name: "missing-rw", parents: users, childField: "Rentals", foreignKey: "user_id"},
+			{name: "missing-parents", rw: rw, childField: "Rentals", foreignKey: "user_id"},
+			{name: "missing-child-field", rw: rw, parents: users, foreignKey: "user_id"},
+			{name: "missing-foreign-key", rw: rw, parents: users, childField: "Rentals"},
+			{name: "not-a-slice", rw: rw, parents: users[0], childField: "Rentals", foreignKey: "user_id"},
+			{name: "not-pointers", rw: rw, parents: []testUserWithRentals{{}}, childField: "Rentals", foreignKey: "user_id"},
+			{name: "unknown-child-field", rw: rw, parents: users, childField: "Cars", foreignKey: "user_id"},
+			{name: "not-ignored-field", rw: rw, parents: users, childField: "Name", foreignKey: "user_id"},
+			{name: "not-a-slice-or-pointer", rw: rw, parents: []*badField{{}}, childField: "Rentals", foreignKey: "user_id"},
+			{name: "unknown-foreign-key", rw: rw, parents: users, childField: "Rentals", foreignKey: "owner_id"},
+			{name: "nil-parent", rw: rw, parents: []*testUserWithRentals{nil}, childField: "Rentals", foreignKey: "user_id"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				err := dbw.LoadRelated(testCtx, tt.rw, tt.parents, tt.childField, tt.foreignKey)
+				assert.ErrorIs(t, err, dbw.ErrInvalidParameter)
+			})
+		}
+	})
+}
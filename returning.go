@@ -0,0 +1,43 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// withReturning appends the returning clause of the WithReturning option to
+// the statement, which writes the resource i.  The db is returned unchanged
+// when the option isn't used.
+func (rw *RW) withReturning(db *gorm.DB, i interface{}, opts Options) (*gorm.DB, error) {
+	const op = "dbw.withReturning"
+	if opts.WithReturning == nil {
+		return db, nil
+	}
+	if d := rw.underlying.dialect(); !d.SupportsReturning() {
+		return nil, fmt.Errorf("%s: returning is not supported by %s: %w", op, d.Name(), ErrInvalidParameter)
+	}
+	names := opts.WithReturning
+	if len(names) == 0 {
+		// list every column rather than using "returning *", since gorm only
+		// scans the returned columns into existing resources when they're
+		// listed (a "returning *" resets a slice of resources)
+		mDb := rw.underlying.wrapped.Model(i)
+		if err := mDb.Statement.Parse(i); err != nil || mDb.Statement.Schema == nil {
+			return nil, fmt.Errorf("%s: internal error: unable to parse stmt: %w", op, err)
+		}
+		names = mDb.Statement.Schema.DBNames
+	}
+	columns := make([]clause.Column, 0, len(names))
+	for _, name := range names {
+		if name == "" {
+			return nil, fmt.Errorf("%s: missing returning column: %w", op, ErrInvalidParameter)
+		}
+		columns = append(columns, clause.Column{Name: name})
+	}
+	return db.Clauses(clause.Returning{Columns: columns}), nil
+}
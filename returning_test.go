@@ -0,0 +1,111 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-secure-stdlib/base62"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+)
+
+func TestRW_WithReturning(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	url := filepath.Join(t.TempDir(), "returning.db")
+	TestSetup(t, WithTestDatabaseUrl(url))
+	r := NewRecorder()
+	db, err := Open(Sqlite, url, WithRecorder(r))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close(testCtx) })
+	rw := New(db)
+
+	newUser := func(t *testing.T, name string) *testUser {
+		t.Helper()
+		publicId, err := base62.Random(20)
+		require.NoError(t, err)
+		return &testUser{PublicId: publicId, Name: name}
+	}
+
+	t.Run("create", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		u := newUser(t, "create")
+		r.Reset()
+		require.NoError(rw.Create(testCtx, u, WithReturning()))
+		assert.Equal(uint32(1), u.Version)
+		AssertQueryCount(t, r, 1)
+		AssertNoQueriesMatching(t, r, `(?i)^select`)
+	})
+	t.Run("create-columns", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		u := newUser(t, "create-columns")
+		r.Reset()
+		require.NoError(rw.Create(testCtx, u, WithReturning("version")))
+		assert.Equal(uint32(1), u.Version)
+		matching, err := r.Matching(`(?i)returning .version.$`)
+		require.NoError(err)
+		assert.Len(matching, 1)
+	})
+	t.Run("create-items", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		users := []*testUser{newUser(t, "create-items-1"), newUser(t, "create-items-2"), newUser(t, "create-items-3")}
+		r.Reset()
+		require.NoError(rw.CreateItems(testCtx, users, WithReturning()))
+		for _, u := range users {
+			assert.Equal(uint32(1), u.Version)
+		}
+		AssertNoQueriesMatching(t, r, `(?i)^select`)
+	})
+	t.Run("update", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		u := newUser(t, "update")
+		require.NoError(rw.Create(testCtx, u, WithReturning()))
+		u.Name = "updated"
+		r.Reset()
+		rowsUpdated, err := rw.Update(testCtx, u, []string{"Name"}, nil, WithReturning(), WithVersion(&u.Version))
+		require.NoError(err)
+		assert.Equal(1, rowsUpdated)
+		assert.Equal(uint32(2), u.Version)
+		assert.Equal("updated", u.Name)
+		AssertQueryCount(t, r, 1)
+		AssertNoQueriesMatching(t, r, `(?i)^select`)
+	})
+	t.Run("update-stale-version", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		u := newUser(t, "update-stale-version")
+		require.NoError(rw.Create(testCtx, u, WithReturning()))
+		u.Name = "stale"
+		staleVersion := uint32(100)
+		_, err := rw.Update(testCtx, u, []string{"Name"}, nil, WithReturning(), WithVersion(&staleVersion))
+		assert.ErrorIs(err, ErrStaleVersion)
+		// the resource was refreshed by a lookup
+		assert.Equal("update-stale-version", u.Name)
+	})
+	t.Run("invalid-column", func(t *testing.T) {
+		require := require.New(t)
+		err := rw.Create(testCtx, newUser(t, "invalid-column"), WithReturning(""))
+		require.ErrorIs(err, ErrInvalidParameter)
+	})
+	t.Run("unsupported", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		db, err := OpenWith(testDialector{Dialector: sqlite.Open("file::memory:"), name: "test-returning"})
+		require.NoError(err)
+		defer db.Close(testCtx)
+		rw := New(db)
+		_, err = rw.Exec(testCtx, testQueryCreateTablesSqlite, nil)
+		require.NoError(err)
+
+		u := newUser(t, "unsupported")
+		assert.ErrorIs(rw.Create(testCtx, u, WithReturning()), ErrInvalidParameter)
+		assert.ErrorIs(rw.CreateItems(testCtx, []*testUser{u}, WithReturning()), ErrInvalidParameter)
+		require.NoError(rw.Create(testCtx, u))
+		u.Name = "updated"
+		_, err = rw.Update(testCtx, u, []string{"Name"}, nil, WithReturning())
+		assert.ErrorIs(err, ErrInvalidParameter)
+	})
+}
@@ -11,6 +11,7 @@ import (
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
 )
 
 const (
@@ -45,8 +46,9 @@ func (rw *RW) DB() *DB {
 }
 
 // Exec will execute the sql with the values as parameters. The int returned
-// is the number of rows affected by the sql. The WithDebug option is supported.
-func (rw *RW) Exec(ctx context.Context, sql string, values []interface{}, opt ...Option) (int, error) {
+// is the number of rows affected by the sql. The WithDebug and WithTimeout
+// options are supported.
+func (rw *RW) Exec(ctx context.Context, sql string, values []interface{}, opt ...Option) (_ int, retErr error) {
 	const op = "dbw.Exec"
 	if rw.underlying == nil {
 		return 0, fmt.Errorf("%s: missing underlying db: %w", op, ErrInternal)
@@ -55,6 +57,11 @@ func (rw *RW) Exec(ctx context.Context, sql string, values []interface{}, opt ..
 		return noRowsAffected, fmt.Errorf("%s: missing sql: %w", op, ErrInvalidParameter)
 	}
 	opts := GetOpts(opt...)
+	rw, ctx, done, err := rw.startTimeout(ctx, opts)
+	if err != nil {
+		return noRowsAffected, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { retErr = done(retErr) }()
 	db := rw.underlying.wrapped.WithContext(ctx)
 	if opts.WithDebug {
 		db = db.Debug()
@@ -166,6 +173,35 @@ func (rw *RW) IsTx() bool {
 	}
 }
 
+// lockingClause returns the locking clause for the WithLock option, which is
+// only valid within a transaction.  A nil clause is returned when the option
+// isn't specified or the dialect doesn't need row locks (see
+// Dialect.Locking).
+func (rw *RW) lockingClause(opts Options) (clause.Expression, error) {
+	const op = "dbw.lockingClause"
+	if opts.WithLock == nil {
+		return nil, nil
+	}
+	if !rw.IsTx() {
+		return nil, fmt.Errorf("%s: with lock is only valid within a transaction: %w", op, ErrInvalidParameter)
+	}
+	switch opts.WithLock.Strength {
+	case ForUpdate, ForNoKeyUpdate, ForShare, ForKeyShare:
+	default:
+		return nil, fmt.Errorf("%s: invalid lock strength %q: %w", op, opts.WithLock.Strength, ErrInvalidParameter)
+	}
+	switch opts.WithLock.Wait {
+	case "", NoWait, SkipLocked:
+	default:
+		return nil, fmt.Errorf("%s: invalid lock wait %q: %w", op, opts.WithLock.Wait, ErrInvalidParameter)
+	}
+	l, err := rw.underlying.dialect().Locking(*opts.WithLock)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return l, nil
+}
+
 func (rw *RW) whereClausesFromOpts(_ context.Context, i interface{}, opts Options) (string, []interface{}, error) {
 	const op = "dbw.whereClausesFromOpts"
 	var where []string
@@ -278,15 +314,15 @@ func (rw *RW) primaryKeysWhere(ctx context.Context, i interface{}) (string, []in
 	}
 	clauses := make([]string, 0, len(fieldNames))
 	for _, col := range fieldNames {
-		clauses = append(clauses, fmt.Sprintf("%s = ?", col))
+		clauses = append(clauses, fmt.Sprintf("%s = ?", rw.underlying.dialect().Quote(col)))
 	}
 	return strings.Join(clauses, " and "), fieldValues, nil
 }
 
 // LookupWhere will lookup the first resource using a where clause with
-// parameters (it only returns the first one). Supports WithDebug, and
-// WithTable options.
-func (rw *RW) LookupWhere(ctx context.Context, resource interface{}, where string, args []interface{}, opt ...Option) error {
+// parameters (it only returns the first one). Supports WithDebug, WithTable,
+// WithLock and WithTimeout options.
+func (rw *RW) LookupWhere(ctx context.Context, resource interface{}, where string, args []interface{}, opt ...Option) (retErr error) {
 	const op = "dbw.LookupWhere"
 	if rw.underlying == nil {
 		return fmt.Errorf("%s: missing underlying db: %w", op, ErrInvalidParameter)
@@ -298,6 +334,15 @@ func (rw *RW) LookupWhere(ctx context.Context, resource interface{}, where strin
 		return fmt.Errorf("%s: %w", op, err)
 	}
 	opts := GetOpts(opt...)
+	lock, err := rw.lockingClause(opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	rw, ctx, done, err := rw.startTimeout(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { retErr = done(retErr) }()
 	db := rw.underlying.wrapped.WithContext(ctx)
 	if opts.WithTable != "" {
 		db = db.Table(opts.WithTable)
@@ -305,6 +350,9 @@ func (rw *RW) LookupWhere(ctx context.Context, resource interface{}, where strin
 	if opts.WithDebug {
 		db = db.Debug()
 	}
+	if lock != nil {
+		db = db.Clauses(lock)
+	}
 	if err := db.Where(where, args...).First(resource).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return fmt.Errorf("%s: %w", op, ErrRecordNotFound)
@@ -320,8 +368,9 @@ func (rw *RW) LookupWhere(ctx context.Context, resource interface{}, where strin
 //
 // Supports WithTable and WithLimit options.  If WithLimit < 0, then unlimited results are returned.
 // If WithLimit == 0, then default limits are used for results.
-// Supports the WithOrder, WithTable, and WithDebug options.
-func (rw *RW) SearchWhere(ctx context.Context, resources interface{}, where string, args []interface{}, opt ...Option) error {
+// Supports the WithOrder, WithTable, WithDebug, WithLock and WithTimeout
+// options.
+func (rw *RW) SearchWhere(ctx context.Context, resources interface{}, where string, args []interface{}, opt ...Option) (retErr error) {
 	const op = "dbw.SearchWhere"
 	opts := GetOpts(opt...)
 	if rw.underlying == nil {
@@ -336,8 +385,19 @@ func (rw *RW) SearchWhere(ctx context.Context, resources interface{}, where stri
 	if err := validateResourcesInterface(resources); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
-	var err error
+	lock, err := rw.lockingClause(opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	rw, ctx, done, err := rw.startTimeout(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { retErr = done(retErr) }()
 	db := rw.underlying.wrapped.WithContext(ctx)
+	if lock != nil {
+		db = db.Clauses(lock)
+	}
 	if opts.WithOrder != "" {
 		db = db.Order(opts.WithOrder)
 	}
@@ -10,6 +10,7 @@ import (
 	"strconv"
 	"testing"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/hashicorp/go-dbw"
 	"github.com/hashicorp/go-dbw/internal/dbtest"
 	"github.com/stretchr/testify/assert"
@@ -355,6 +356,80 @@ func TestDb_SearchWhere(t *testing.T) {
 	})
 }
 
+func TestDb_WithLock(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	conn, _ := dbw.TestSetup(t)
+	testRw := dbw.New(conn)
+	user := testUser(t, testRw, "", "", "")
+
+	t.Run("not-in-tx", func(t *testing.T) {
+		assert := assert.New(t)
+		opt := dbw.WithLock(dbw.ForUpdate)
+		err := testRw.LookupBy(testCtx, user.Clone(), opt)
+		assert.ErrorIs(err, dbw.ErrInvalidParameter)
+		var found dbtest.TestUser
+		err = testRw.LookupWhere(testCtx, &found, "public_id = ?", []interface{}{user.PublicId}, opt)
+		assert.ErrorIs(err, dbw.ErrInvalidParameter)
+		var users []*dbtest.TestUser
+		err = testRw.SearchWhere(testCtx, &users, "public_id = ?", []interface{}{user.PublicId}, opt)
+		assert.ErrorIs(err, dbw.ErrInvalidParameter)
+	})
+	t.Run("invalid-lock", func(t *testing.T) {
+		assert := assert.New(t)
+		_, err := testRw.DoTx(testCtx, func(error) bool { return false }, 0, dbw.ConstBackoff{}, func(r dbw.Reader, _ dbw.Writer) error {
+			return r.LookupBy(testCtx, user.Clone(), dbw.WithLock("invalid"))
+		})
+		assert.ErrorIs(err, dbw.ErrInvalidParameter)
+		_, err = testRw.DoTx(testCtx, func(error) bool { return false }, 0, dbw.ConstBackoff{}, func(r dbw.Reader, _ dbw.Writer) error {
+			return r.LookupBy(testCtx, user.Clone(), dbw.WithLock(dbw.ForUpdate, "invalid"))
+		})
+		assert.ErrorIs(err, dbw.ErrInvalidParameter)
+	})
+	t.Run("in-tx", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		if typ, _, _ := conn.DbType(); typ == dbw.SqlServer {
+			// sql server uses table hints rather than locking clauses
+			_, err := testRw.DoTx(testCtx, func(error) bool { return false }, 0, dbw.ConstBackoff{}, func(r dbw.Reader, _ dbw.Writer) error {
+				return r.LookupBy(testCtx, user.Clone(), dbw.WithLock(dbw.ForUpdate))
+			})
+			assert.ErrorIs(err, dbw.ErrInvalidParameter)
+			return
+		}
+		_, err := testRw.DoTx(testCtx, func(error) bool { return false }, 0, dbw.ConstBackoff{}, func(r dbw.Reader, w dbw.Writer) error {
+			found := user.Clone().(*dbtest.TestUser)
+			if err := r.LookupBy(testCtx, found, dbw.WithLock(dbw.ForUpdate, dbw.NoWait)); err != nil {
+				return err
+			}
+			assert.Equal(user.PublicId, found.PublicId)
+			var users []*dbtest.TestUser
+			if err := r.SearchWhere(testCtx, &users, "public_id = ?", []interface{}{user.PublicId}, dbw.WithLock(dbw.ForShare, dbw.SkipLocked)); err != nil {
+				return err
+			}
+			assert.Len(users, 1)
+			found.Name = "locked-" + user.PublicId
+			_, err := w.Update(testCtx, found, []string{"Name"}, nil)
+			return err
+		})
+		require.NoError(err)
+	})
+	t.Run("sql", func(t *testing.T) {
+		require := require.New(t)
+		mockConn, mock := dbw.TestSetupWithMock(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT \* FROM "db_test_user" WHERE "public_id" = \$1 .* FOR UPDATE SKIP LOCKED`).
+			WillReturnRows(sqlmock.NewRows([]string{"public_id"}).AddRow(user.PublicId))
+		mock.ExpectQuery(`SELECT \* FROM "db_test_user" WHERE name = \$1 .* FOR SHARE NOWAIT`).
+			WillReturnRows(sqlmock.NewRows([]string{"public_id"}).AddRow(user.PublicId))
+		tx, err := dbw.New(mockConn).Begin(testCtx)
+		require.NoError(err)
+		require.NoError(tx.LookupBy(testCtx, user.Clone(), dbw.WithLock(dbw.ForUpdate, dbw.SkipLocked)))
+		var users []*dbtest.TestUser
+		require.NoError(tx.SearchWhere(testCtx, &users, "name = ?", []interface{}{"alice"}, dbw.WithLock(dbw.ForShare, dbw.NoWait)))
+		require.NoError(mock.ExpectationsWereMet())
+	})
+}
+
 func TestRW_IsTx(t *testing.T) {
 	t.Parallel()
 	testCtx := context.Background()
@@ -0,0 +1,550 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-secure-stdlib/base62"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// sqliteDialect is the Sqlite dialect
+type sqliteDialect struct{}
+
+var (
+	_ Dialect            = sqliteDialect{}
+	_ OpenerDialect      = sqliteDialect{}
+	_ SavepointReleaser  = sqliteDialect{}
+	_ LockDialect        = sqliteDialect{}
+	_ CredentialsDialect = sqliteDialect{}
+)
+
+// Name returns "sqlite"
+func (sqliteDialect) Name() string { return "sqlite" }
+
+// Quote returns `identifier`
+func (sqliteDialect) Quote(identifier string) string {
+	return quoteIdentifier(identifier, "`")
+}
+
+// Excluded returns excluded.column
+func (sqliteDialect) Excluded(column string) interface{} {
+	return clause.Column{Table: excludedTable, Name: column}
+}
+
+// OnConflict supports every on conflict clause
+func (sqliteDialect) OnConflict(*clause.OnConflict, []string) error { return nil }
+
+// Locking returns a nil clause, since sqlite doesn't have row locks because
+// it serializes write transactions
+func (sqliteDialect) Locking(RowLock) (clause.Expression, error) { return nil, nil }
+
+// SupportsReturning returns true
+func (sqliteDialect) SupportsReturning() bool { return true }
+
+// ReleaseSavepoint returns "release savepoint name"
+func (sqliteDialect) ReleaseSavepoint(name string) string { return releaseSavepoint(name) }
+
+// IsAuthError returns true for SQLITE_AUTH errors
+func (sqliteDialect) IsAuthError(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrAuth
+}
+
+// IsStaleStmtError returns true for SQLITE_SCHEMA errors
+func (sqliteDialect) IsStaleStmtError(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrSchema
+}
+
+// IsStatementTimeout returns false, since sqlite doesn't have statement
+// timeouts
+func (sqliteDialect) IsStatementTimeout(error) bool { return false }
+
+// IsBusyError returns true for SQLITE_BUSY errors
+func (sqliteDialect) IsBusyError(err error) bool { return isSqliteBusy(err) }
+
+// TestSchema returns the sqlite test schema
+func (sqliteDialect) TestSchema() (create, drop []string) {
+	return []string{testQueryCreateTablesSqlite}, []string{testQueryDropTablesSqlite}
+}
+
+// Open enables foreign keys, which is required for a DB opened with
+// OpenWith(...), and uses a sqlitePool when it's needed for the options.
+func (sqliteDialect) Open(db *gorm.DB, readers *sql.DB, opts Options) error {
+	const op = "dbw.(sqliteDialect).Open"
+	if err := db.Exec("PRAGMA foreign_keys=ON", nil).Error; err != nil {
+		return fmt.Errorf("%s: unable to enable sqlite foreign keys: %w", op, err)
+	}
+	if err := useSqlitePool(db, readers, opts.WithSqliteBusyRetries); err != nil {
+		return fmt.Errorf("%s: unable to use sqlite pool: %w", op, err)
+	}
+	return nil
+}
+
+// NewSessionLock returns a lock which uses a row in the lock table (dbw_lock)
+// with an expiration, which is refreshed while the lock is held.
+func (sqliteDialect) NewSessionLock(ctx context.Context, db *DB, key string, expiration time.Duration) (SessionLock, error) {
+	const op = "dbw.(sqliteDialect).NewSessionLock"
+	owner, err := base62.Random(20)
+	if err != nil {
+		return nil, fmt.Errorf("%s: unable to generate lock owner: %w", op, err)
+	}
+	if err := ensureLockTable(ctx, New(db)); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &tableLock{rw: New(db), name: key, owner: owner, expiration: expiration}, nil
+}
+
+// LockTx acquires the transaction's write lock, which provides the exclusion
+// since sqlite serializes all write transactions.  ErrLockNotAcquired is
+// returned if the key is held by a session lock, since waiting within the
+// transaction would prevent the session from releasing it.
+func (sqliteDialect) LockTx(ctx context.Context, rw *RW, key string) error {
+	const op = "dbw.(sqliteDialect).LockTx"
+	if err := ensureLockTable(ctx, rw); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	// deleting the key's expired row acquires the write lock, even when
+	// there isn't a row, so the lock doesn't leave a row behind.
+	if _, err := rw.Exec(ctx, "delete from dbw_lock where name = ? and expires_at <= ?", []interface{}{key, time.Now().UTC()}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	var held int64
+	if err := rw.underlying.wrapped.WithContext(ctx).Raw("select count(*) from dbw_lock where name = ?", key).Row().Scan(&held); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if held > 0 {
+		return fmt.Errorf("%s: %s: %w", op, key, ErrLockNotAcquired)
+	}
+	return nil
+}
+
+// tableLock is a lock which uses a row in the lock table with an expiration
+// which is refreshed while the lock is held.
+type tableLock struct {
+	rw         *RW
+	name       string
+	owner      string
+	expiration time.Duration
+}
+
+// TryLock tries to claim the lock's row
+func (l *tableLock) TryLock(ctx context.Context) (bool, error) {
+	now := time.Now().UTC()
+	return upsertLockRow(ctx, l.rw, l.name, l.owner, now.Add(l.expiration), now)
+}
+
+// Lock polls until the lock's row is claimed
+func (l *tableLock) Lock(ctx context.Context) error {
+	pollInterval := l.expiration / 10
+	for {
+		acquired, err := l.TryLock(ctx)
+		if err != nil {
+			return err
+		}
+		if acquired {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// Check refreshes the expiration of the lock's row
+func (l *tableLock) Check(ctx context.Context) error {
+	rowsAffected, err := l.rw.Exec(ctx,
+		"update dbw_lock set expires_at = ? where name = ? and owner = ?",
+		[]interface{}{time.Now().UTC().Add(l.expiration), l.name, l.owner},
+	)
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// Unlock deletes the lock's row
+func (l *tableLock) Unlock(ctx context.Context) error {
+	_, err := l.rw.Exec(ctx, "delete from dbw_lock where name = ? and owner = ?", []interface{}{l.name, l.owner})
+	return err
+}
+
+// upsertLockRow will claim the named row in the lock table for the owner, if
+// the row doesn't exist or it has expired.
+func upsertLockRow(ctx context.Context, rw *RW, name, owner string, expiresAt, now time.Time) (bool, error) {
+	rowsAffected, err := rw.Exec(ctx,
+		`insert into dbw_lock (name, owner, expires_at) values (?, ?, ?)
+		on conflict (name) do update set owner = excluded.owner, expires_at = excluded.expires_at
+		where dbw_lock.expires_at <= ?`,
+		[]interface{}{name, owner, expiresAt, now},
+	)
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected == 1, nil
+}
+
+// ensureLockTable creates the lock table when it doesn't exist.  Once it's
+// known to exist outside of a transaction, which could be rolled back, it's
+// not checked again for the DB.
+func ensureLockTable(ctx context.Context, rw *RW) error {
+	const op = "dbw.ensureLockTable"
+	created := rw.underlying.lockTable
+	if created != nil && created.Load() {
+		return nil
+	}
+	var exists int64
+	if err := rw.underlying.wrapped.WithContext(ctx).
+		Raw("select count(*) from sqlite_master where type = 'table' and name = ?", lockTableName).
+		Row().Scan(&exists); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if exists == 0 {
+		if err := createLockTable(ctx, rw); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	if created != nil && !rw.IsTx() {
+		created.Store(true)
+	}
+	return nil
+}
+
+func createLockTable(ctx context.Context, rw *RW) error {
+	const op = "dbw.createLockTable"
+	if _, err := rw.Exec(ctx, `create table if not exists dbw_lock (
+		name text constraint dbw_lock_pkey primary key,
+		owner text not null,
+		expires_at timestamp not null
+	)`, nil); err != nil {
+		return fmt.Errorf("%s: unable to create %s table: %w", op, lockTableName, err)
+	}
+	return nil
+}
+
+// SqliteJournalMode defines a sqlite journal mode.  See WithSqliteJournalMode
+type SqliteJournalMode string
+
+const (
+	// SqliteJournalDelete deletes the rollback journal at the end of each
+	// transaction, which is sqlite's default
+	SqliteJournalDelete SqliteJournalMode = "DELETE"
+
+	// SqliteJournalTruncate truncates the rollback journal at the end of each
+	// transaction
+	SqliteJournalTruncate SqliteJournalMode = "TRUNCATE"
+
+	// SqliteJournalPersist overwrites the rollback journal's header at the
+	// end of each transaction
+	SqliteJournalPersist SqliteJournalMode = "PERSIST"
+
+	// SqliteJournalMemory stores the rollback journal in memory
+	SqliteJournalMemory SqliteJournalMode = "MEMORY"
+
+	// SqliteJournalWal uses a write-ahead log, which allows reads concurrent
+	// with a write
+	SqliteJournalWal SqliteJournalMode = "WAL"
+
+	// SqliteJournalOff disables the rollback journal
+	SqliteJournalOff SqliteJournalMode = "OFF"
+)
+
+// SqliteSynchronous defines a sqlite synchronous level.  See
+// WithSqliteSynchronous
+type SqliteSynchronous string
+
+const (
+	// SqliteSynchronousOff doesn't sync, leaving durability to the OS
+	SqliteSynchronousOff SqliteSynchronous = "OFF"
+
+	// SqliteSynchronousNormal syncs at critical moments, which is durable in
+	// WAL mode except for the most recent transactions after a power loss
+	SqliteSynchronousNormal SqliteSynchronous = "NORMAL"
+
+	// SqliteSynchronousFull syncs at every critical moment, which is sqlite's
+	// default
+	SqliteSynchronousFull SqliteSynchronous = "FULL"
+
+	// SqliteSynchronousExtra is like SqliteSynchronousFull, and it also syncs
+	// the directory of a deleted rollback journal
+	SqliteSynchronousExtra SqliteSynchronous = "EXTRA"
+)
+
+// validateSqliteOptions validates the sqlite options
+func validateSqliteOptions(opts Options) error {
+	const op = "dbw.validateSqliteOptions"
+	switch opts.WithSqliteJournalMode {
+	case "", SqliteJournalDelete, SqliteJournalTruncate, SqliteJournalPersist, SqliteJournalMemory, SqliteJournalWal, SqliteJournalOff:
+	default:
+		return fmt.Errorf("%s: invalid journal mode %q: %w", op, opts.WithSqliteJournalMode, ErrInvalidParameter)
+	}
+	switch opts.WithSqliteSynchronous {
+	case "", SqliteSynchronousOff, SqliteSynchronousNormal, SqliteSynchronousFull, SqliteSynchronousExtra:
+	default:
+		return fmt.Errorf("%s: invalid synchronous level %q: %w", op, opts.WithSqliteSynchronous, ErrInvalidParameter)
+	}
+	if opts.WithSqliteBusyTimeout < 0 {
+		return fmt.Errorf("%s: busy timeout must not be negative: %w", op, ErrInvalidParameter)
+	}
+	return nil
+}
+
+// sqliteDriver returns a sqlite driver which applies the sqlite options to
+// every connection it opens.  Read-only connections are opened with
+// query_only, and they don't set the journal mode, which is persisted in the
+// database by the writer.
+func sqliteDriver(opts Options, readOnly bool) *sqlite3.SQLiteDriver {
+	pragmas := []string{"PRAGMA foreign_keys=ON"}
+	if opts.WithSqliteBusyTimeout > 0 {
+		pragmas = append(pragmas, fmt.Sprintf("PRAGMA busy_timeout=%d", opts.WithSqliteBusyTimeout.Milliseconds()))
+	}
+	if opts.WithSqliteJournalMode != "" && !readOnly {
+		pragmas = append(pragmas, fmt.Sprintf("PRAGMA journal_mode=%s", opts.WithSqliteJournalMode))
+	}
+	if opts.WithSqliteSynchronous != "" {
+		pragmas = append(pragmas, fmt.Sprintf("PRAGMA synchronous=%s", opts.WithSqliteSynchronous))
+	}
+	if readOnly {
+		pragmas = append(pragmas, "PRAGMA query_only=ON")
+	}
+	return &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			for _, p := range pragmas {
+				if _, err := conn.Exec(p, nil); err != nil {
+					return fmt.Errorf("unable to execute %q: %w", p, err)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// sqliteDBs returns the writer and readers of the sqlite database at the dsn,
+// whose connections are opened by the connectors returned by newConnector for
+// a driver.  The readers are nil, unless WithSqliteSingleWriter is used.
+func sqliteDBs(dsn string, opts Options, newConnector func(*sqlite3.SQLiteDriver) driver.Connector) (writer, readers *sql.DB, _ error) {
+	const op = "dbw.sqliteDBs"
+	if err := validateSqliteOptions(opts); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if opts.WithSqliteSingleWriter && isSqliteInMemory(dsn) {
+		// every connection to an in-memory database opens a separate
+		// database, so the readers wouldn't see the writer's database.
+		return nil, nil, fmt.Errorf("%s: single writer is not supported for an in-memory database: %w", op, ErrInvalidParameter)
+	}
+	writer = sql.OpenDB(newConnector(sqliteDriver(opts, false)))
+	if !opts.WithSqliteSingleWriter {
+		return writer, nil, nil
+	}
+	writer.SetMaxOpenConns(1)
+	writer.SetMaxIdleConns(1)
+	readers = sql.OpenDB(newConnector(sqliteDriver(opts, true)))
+	return writer, readers, nil
+}
+
+// isSqliteInMemory returns true when the dsn is an in-memory or temporary
+// sqlite database
+func isSqliteInMemory(dsn string) bool {
+	switch {
+	case dsn == "", dsn == ":memory:", strings.HasPrefix(dsn, "file::memory:"):
+		return true
+	}
+	if i := strings.IndexByte(dsn, '?'); i >= 0 {
+		if q, err := url.ParseQuery(dsn[i+1:]); err == nil && q.Get("mode") == "memory" {
+			return true
+		}
+	}
+	return false
+}
+
+// sqliteConnector is a driver.Connector which opens sqlite connections for a
+// dsn.
+type sqliteConnector struct {
+	driver *sqlite3.SQLiteDriver
+	dsn    string
+}
+
+var _ driver.Connector = (*sqliteConnector)(nil)
+
+// Connect opens a connection
+func (c *sqliteConnector) Connect(_ context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+// Driver returns the underlying driver
+func (c *sqliteConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// sqlitePool is a gorm.ConnPool for sqlite, which routes reads outside of
+// transactions to its readers (see WithSqliteSingleWriter) and retries
+// statements which fail with SQLITE_BUSY (see WithSqliteBusyRetries).
+// Everything else, including transactions, uses its writer.
+type sqlitePool struct {
+	writer   gorm.ConnPool
+	writerDB *sql.DB
+
+	// readers are nil when there's a single pool
+	readers   gorm.ConnPool
+	readersDB *sql.DB
+
+	busyRetries uint
+	backoff     Backoff
+}
+
+var (
+	_ gorm.ConnPool         = (*sqlitePool)(nil)
+	_ gorm.ConnPoolBeginner = (*sqlitePool)(nil)
+	_ gorm.GetDBConnector   = (*sqlitePool)(nil)
+)
+
+// useSqlitePool replaces the db's connection pool with a sqlitePool when
+// there are readers or busy retries.
+func useSqlitePool(db *gorm.DB, readers *sql.DB, busyRetries uint) error {
+	const op = "dbw.useSqlitePool"
+	if readers == nil && busyRetries == 0 {
+		return nil
+	}
+	writer, ok := db.ConnPool.(*sql.DB)
+	if !ok {
+		return fmt.Errorf("%s: sqlite options require a sql.DB connection pool: %w", op, ErrInvalidParameter)
+	}
+	p := &sqlitePool{
+		writer:      writer,
+		writerDB:    writer,
+		busyRetries: busyRetries,
+		backoff:     ExpBackoff{},
+	}
+	if readers != nil {
+		p.readers, p.readersDB = readers, readers
+	}
+	db.ConnPool = p
+	db.Statement.ConnPool = p
+	return nil
+}
+
+// GetDBConn returns the readers' sql.DB when there are readers, since the
+// pool options configure it, otherwise it returns the writer's sql.DB.
+func (p *sqlitePool) GetDBConn() (*sql.DB, error) {
+	if p.readersDB != nil {
+		return p.readersDB, nil
+	}
+	return p.writerDB, nil
+}
+
+// PrepareContext prepares a statement using the writer
+func (p *sqlitePool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.writer.PrepareContext(ctx, query)
+}
+
+// ExecContext executes a statement using the writer
+func (p *sqlitePool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := p.retryBusy(ctx, func() error {
+		var err error
+		result, err = p.writer.ExecContext(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
+
+// QueryContext executes a query using the readers when it's a read
+func (p *sqlitePool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := p.retryBusy(ctx, func() error {
+		var err error
+		rows, err = p.poolFor(query).QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRowContext executes a query using the readers when it's a read
+func (p *sqlitePool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	var row *sql.Row
+	_ = p.retryBusy(ctx, func() error {
+		row = p.poolFor(query).QueryRowContext(ctx, query, args...)
+		return row.Err()
+	})
+	return row
+}
+
+// BeginTx begins a transaction using the writer
+func (p *sqlitePool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	switch beginner := p.writer.(type) {
+	case gorm.ConnPoolBeginner:
+		return beginner.BeginTx(ctx, opts)
+	case gorm.TxBeginner:
+		return beginner.BeginTx(ctx, opts)
+	default:
+		return nil, gorm.ErrInvalidTransaction
+	}
+}
+
+// Close closes the writer and readers
+func (p *sqlitePool) Close() error {
+	for _, cp := range []gorm.ConnPool{p.writer, p.readers} {
+		if cache, ok := cp.(*stmtCache); ok {
+			cache.Reset()
+		}
+	}
+	err := p.writerDB.Close()
+	if p.readersDB != nil {
+		err = errors.Join(err, p.readersDB.Close())
+	}
+	return err
+}
+
+// poolFor returns the pool for a query, which is the readers for selects when
+// there are readers.
+func (p *sqlitePool) poolFor(query string) gorm.ConnPool {
+	if p.readers != nil && isReadQuery(query) {
+		return p.readers
+	}
+	return p.writer
+}
+
+// retryBusy calls fn, retrying it with a backoff when it fails with
+// SQLITE_BUSY.
+func (p *sqlitePool) retryBusy(ctx context.Context, fn func() error) error {
+	for attempt := uint(1); ; attempt++ {
+		err := fn()
+		if err == nil || attempt > p.busyRetries || !isSqliteBusy(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(p.backoff.Duration(attempt)):
+		}
+	}
+}
+
+// isReadQuery returns true when the query is a select
+func isReadQuery(query string) bool {
+	query = strings.TrimSpace(query)
+	return len(query) >= 6 && strings.EqualFold(query[:6], "select")
+}
+
+// isSqliteBusy returns true when the error is a sqlite SQLITE_BUSY error
+func isSqliteBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrBusy
+}
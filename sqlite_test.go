@@ -0,0 +1,223 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen_SqliteOptions(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	t.Run("pragmas", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		url := filepath.Join(t.TempDir(), "pragmas.db")
+		db, err := Open(Sqlite, url,
+			WithSqliteJournalMode(SqliteJournalWal),
+			WithSqliteBusyTimeout(3*time.Second),
+			WithSqliteSynchronous(SqliteSynchronousNormal),
+		)
+		require.NoError(err)
+		defer db.Close(testCtx)
+		sqlDB, err := db.SqlDB(testCtx)
+		require.NoError(err)
+
+		// every pooled connection has the pragmas
+		conns := make([]*sql.Conn, 0, 3)
+		for i := 0; i < 3; i++ {
+			conn, err := sqlDB.Conn(testCtx)
+			require.NoError(err)
+			defer conn.Close()
+			conns = append(conns, conn)
+		}
+		for _, conn := range conns {
+			var foreignKeys, busyTimeout, synchronous int
+			var journalMode string
+			require.NoError(conn.QueryRowContext(testCtx, "PRAGMA foreign_keys").Scan(&foreignKeys))
+			require.NoError(conn.QueryRowContext(testCtx, "PRAGMA busy_timeout").Scan(&busyTimeout))
+			require.NoError(conn.QueryRowContext(testCtx, "PRAGMA synchronous").Scan(&synchronous))
+			require.NoError(conn.QueryRowContext(testCtx, "PRAGMA journal_mode").Scan(&journalMode))
+			assert.Equal(1, foreignKeys)
+			assert.Equal(3000, busyTimeout)
+			assert.Equal(1, synchronous) // NORMAL
+			assert.Equal("wal", journalMode)
+		}
+	})
+	t.Run("invalid", func(t *testing.T) {
+		url := filepath.Join(t.TempDir(), "invalid.db")
+		for _, opt := range []Option{
+			WithSqliteJournalMode("WALL"),
+			WithSqliteSynchronous("SOMETIMES"),
+			WithSqliteBusyTimeout(-time.Second),
+		} {
+			_, err := Open(Sqlite, url, opt)
+			assert.ErrorIs(t, err, ErrInvalidParameter)
+		}
+	})
+	t.Run("single-writer-in-memory", func(t *testing.T) {
+		for _, dsn := range []string{"", ":memory:", "file::memory:", "file::memory:?cache=shared", "file:dbw?mode=memory"} {
+			_, err := Open(Sqlite, dsn, WithSqliteSingleWriter(true))
+			assert.ErrorIs(t, err, ErrInvalidParameter, dsn)
+		}
+	})
+}
+
+func TestOpen_SqliteSingleWriter(t *testing.T) {
+	t.Parallel()
+	assert, require := assert.New(t), require.New(t)
+	testCtx := context.Background()
+	url := filepath.Join(t.TempDir(), "single-writer.db")
+	db, err := Open(Sqlite, url,
+		WithSqliteSingleWriter(true),
+		WithSqliteJournalMode(SqliteJournalWal),
+		WithMaxOpenConnections(4),
+		WithPreparedStatements(10),
+	)
+	require.NoError(err)
+	defer db.Close(testCtx)
+	rw := New(db)
+	_, err = rw.Exec(testCtx, testQueryCreateTablesSqlite, nil)
+	require.NoError(err)
+
+	// concurrent writes and reads don't return SQLITE_BUSY
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			u, err := NewId("u")
+			if err == nil {
+				err = rw.Create(testCtx, &testUser{PublicId: u, Name: fmt.Sprintf("user-%d", i)})
+			}
+			errs <- err
+		}(i)
+		go func() {
+			defer wg.Done()
+			var users []*testUser
+			errs <- rw.SearchWhere(testCtx, &users, "", nil)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(err)
+	}
+	var users []*testUser
+	require.NoError(rw.SearchWhere(testCtx, &users, "", nil))
+	assert.Len(users, 20)
+
+	pool, ok := db.wrapped.ConnPool.(*sqlitePool)
+	require.True(ok)
+	assert.Equal(1, pool.writerDB.Stats().MaxOpenConnections)
+	stats, err := db.Stats()
+	require.NoError(err)
+	assert.Equal(4, stats.MaxOpenConnections)
+	assert.Greater(stats.OpenConnections, 0)
+
+	// the readers are read-only
+	_, err = pool.readersDB.ExecContext(testCtx, "delete from db_test_user")
+	assert.Error(err)
+
+	// transactions use the writer
+	_, err = rw.DoTx(testCtx, func(error) bool { return false }, 0, ExpBackoff{}, func(r Reader, w Writer) error {
+		return w.Create(testCtx, &testUser{PublicId: "u_tx", Name: "tx"})
+	})
+	require.NoError(err)
+	require.NoError(rw.LookupBy(testCtx, &testUser{PublicId: "u_tx"}))
+}
+
+func TestSqlite_BusyRetries(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	// holdLock holds the database's write lock for the duration, using a
+	// separate db.
+	holdLock := func(t *testing.T, url string, d time.Duration) {
+		t.Helper()
+		holder, err := sql.Open("sqlite3", url)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = holder.Close() })
+		tx, err := holder.BeginTx(testCtx, nil)
+		require.NoError(t, err)
+		_, err = tx.ExecContext(testCtx, "insert into db_test_user(public_id, name) values('u_holder', 'holder')")
+		require.NoError(t, err)
+		time.AfterFunc(d, func() { _ = tx.Rollback() })
+	}
+	// open a db which doesn't wait for locks
+	open := func(t *testing.T, opt ...Option) (*RW, string) {
+		t.Helper()
+		url := filepath.Join(t.TempDir(), "busy.db")
+		db, err := Open(Sqlite, url+"?_busy_timeout=0", opt...)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = db.Close(testCtx) })
+		rw := New(db)
+		_, err = rw.Exec(testCtx, testQueryCreateTablesSqlite, nil)
+		require.NoError(t, err)
+		return rw, url
+	}
+	insert := "insert into db_test_user(public_id, name) values(?, ?)"
+
+	t.Run("busy", func(t *testing.T) {
+		rw, url := open(t)
+		holdLock(t, url, time.Second)
+		_, err := rw.Exec(testCtx, insert, []interface{}{"u_busy", "busy"})
+		require.Error(t, err)
+		assert.True(t, isSqliteBusy(err))
+	})
+	t.Run("retried", func(t *testing.T) {
+		rw, url := open(t, WithSqliteBusyRetries(10))
+		holdLock(t, url, 50*time.Millisecond)
+		_, err := rw.Exec(testCtx, insert, []interface{}{"u_retried", "retried"})
+		require.NoError(t, err)
+	})
+	t.Run("do-tx", func(t *testing.T) {
+		rw, url := open(t)
+		holdLock(t, url, 50*time.Millisecond)
+		info, err := rw.DoTx(testCtx, func(error) bool { return false }, 10, ExpBackoff{}, func(_ Reader, w Writer) error {
+			_, err := w.Exec(testCtx, insert, []interface{}{"u_tx", "tx"})
+			return err
+		})
+		require.NoError(t, err)
+		assert.Greater(t, info.Retries, 0)
+	})
+}
+
+func Test_isSqliteBusy(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	assert.True(isSqliteBusy(fmt.Errorf("wrapped: %w", sqlite3.Error{Code: sqlite3.ErrBusy})))
+	assert.False(isSqliteBusy(sqlite3.Error{Code: sqlite3.ErrLocked}))
+	assert.False(isSqliteBusy(errors.New("busy")))
+}
+
+func Test_isSqliteInMemory(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	for _, dsn := range []string{"", ":memory:", "file::memory:", "file::memory:?cache=shared", "file:dbw?mode=memory&cache=shared"} {
+		assert.True(isSqliteInMemory(dsn), dsn)
+	}
+	for _, dsn := range []string{"dbw.db", "file:dbw.db", "file:dbw.db?mode=rwc", "/tmp/memory.db"} {
+		assert.False(isSqliteInMemory(dsn), dsn)
+	}
+}
+
+func Test_isReadQuery(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	assert.True(isReadQuery("select * from t"))
+	assert.True(isReadQuery("\n  SELECT 1"))
+	assert.False(isReadQuery("insert into t values(1) returning id"))
+	assert.False(isReadQuery("sel"))
+}
@@ -0,0 +1,113 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	mssql "github.com/microsoft/go-mssqldb"
+	"gorm.io/gorm/clause"
+)
+
+// sqlServerDialect is the SQL Server dialect
+type sqlServerDialect struct{}
+
+var _ Dialect = sqlServerDialect{}
+
+// Name returns "sqlserver"
+func (sqlServerDialect) Name() string { return "sqlserver" }
+
+// Quote returns "identifier"
+func (sqlServerDialect) Quote(identifier string) string {
+	return quoteIdentifier(identifier, `"`)
+}
+
+// Excluded returns excluded.column, which is the alias of the merge's source
+// row
+func (sqlServerDialect) Excluded(column string) interface{} {
+	return clause.Column{Table: excludedTable, Name: column}
+}
+
+// OnConflict validates the clause for "merge", which doesn't support a
+// constraint target or a where clause.  The merge always matches on the
+// primary key, so a columns target must be the primary key.
+func (d sqlServerDialect) OnConflict(c *clause.OnConflict, primaryKeys []string) error {
+	const op = "dbw.(sqlServerDialect).OnConflict"
+	switch {
+	case c.OnConstraint != "":
+		return fmt.Errorf("%s: %s doesn't support an on conflict constraint target: %w", op, d.Name(), ErrInvalidParameter)
+	case len(c.Where.Exprs) > 0 || len(c.TargetWhere.Exprs) > 0:
+		return fmt.Errorf("%s: %s doesn't support on conflict with a version or where clause: %w", op, d.Name(), ErrInvalidParameter)
+	case len(c.Columns) == 0:
+		return nil
+	case len(c.Columns) != len(primaryKeys):
+		return fmt.Errorf("%s: %s on conflict target must be the primary key: %w", op, d.Name(), ErrInvalidParameter)
+	}
+	for _, col := range c.Columns {
+		found := false
+		for _, pk := range primaryKeys {
+			if strings.EqualFold(col.Name, pk) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: %s on conflict target must be the primary key: %w", op, d.Name(), ErrInvalidParameter)
+		}
+	}
+	return nil
+}
+
+// Locking returns ErrInvalidParameter, since sql server uses table hints
+// rather than locking clauses
+func (d sqlServerDialect) Locking(RowLock) (clause.Expression, error) {
+	const op = "dbw.(sqlServerDialect).Locking"
+	return nil, fmt.Errorf("%s: with lock is not supported by %s: %w", op, d.Name(), ErrInvalidParameter)
+}
+
+// SupportsReturning returns false, since sql server uses output clauses
+func (sqlServerDialect) SupportsReturning() bool { return false }
+
+// IsAuthError returns true when a login failed
+func (sqlServerDialect) IsAuthError(err error) bool {
+	return isSqlServerError(err, sqlServerLoginFailed)
+}
+
+// IsStaleStmtError returns false
+func (sqlServerDialect) IsStaleStmtError(error) bool { return false }
+
+// IsStatementTimeout returns false, since sql server doesn't have statement
+// timeouts
+func (sqlServerDialect) IsStatementTimeout(error) bool { return false }
+
+// IsBusyError returns false
+func (sqlServerDialect) IsBusyError(error) bool { return false }
+
+// TestSchema returns the sql server test schema
+func (sqlServerDialect) TestSchema() (create, drop []string) {
+	return testQueriesCreateTablesSqlServer, []string{testQueryDropTablesSqlServer}
+}
+
+const (
+	// sqlServerLoginFailed is returned when a login's credentials are
+	// rejected
+	sqlServerLoginFailed int32 = 18456
+)
+
+// isSqlServerError returns true when the error is a sql server error with
+// one of the error numbers
+func isSqlServerError(err error, numbers ...int32) bool {
+	var sqlServerErr mssql.Error
+	if !errors.As(err, &sqlServerErr) {
+		return false
+	}
+	for _, n := range numbers {
+		if sqlServerErr.Number == n {
+			return true
+		}
+	}
+	return false
+}
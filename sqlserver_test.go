@@ -0,0 +1,81 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlserver"
+)
+
+// testSetupSqlServerMock returns a sql server DB which uses a sqlmock
+// connection
+func testSetupSqlServerMock(t *testing.T) (*DB, sqlmock.Sqlmock) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenWith(sqlserver.New(sqlserver.Config{Conn: mockDB}))
+	require.NoError(t, err)
+	return db, mock
+}
+
+func TestSqlServer_Dialect(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	t.Run("db-type", func(t *testing.T) {
+		db, _ := testSetupSqlServerMock(t)
+		typ, rawName, err := New(db).Dialect()
+		require.NoError(t, err)
+		assert.Equal(t, SqlServer, typ)
+		assert.Equal(t, "sqlserver", rawName)
+		assert.Equal(t, `"public_id"`, db.dialect().Quote("public_id"))
+	})
+	t.Run("upsert", func(t *testing.T) {
+		require := require.New(t)
+		db, mock := testSetupSqlServerMock(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`MERGE INTO "db_test_user" USING \(VALUES.*\) AS excluded .* WHEN MATCHED THEN UPDATE SET "name"="excluded"."name" WHEN NOT MATCHED THEN INSERT .* OUTPUT INSERTED`).
+			WillReturnRows(sqlmock.NewRows([]string{"public_id", "name"}).AddRow("u_1234567890", "alice"))
+		mock.ExpectCommit()
+		u := &testUser{PublicId: "u_1234567890", Name: "alice"}
+		err := New(db).Create(testCtx, u, WithOnConflict(&OnConflict{Target: Columns{"public_id"}, Action: SetColumns([]string{"name"})}))
+		require.NoError(err)
+		require.NoError(mock.ExpectationsWereMet())
+	})
+	t.Run("upsert-unsupported", func(t *testing.T) {
+		db, _ := testSetupSqlServerMock(t)
+		rw := New(db)
+		u := &testUser{PublicId: "u_1234567890", Name: "alice"}
+		err := rw.Create(testCtx, u, WithOnConflict(&OnConflict{Target: Columns{"name"}, Action: DoNothing(true)}))
+		assert.ErrorIs(t, err, ErrInvalidParameter)
+		err = rw.Create(testCtx, u, WithOnConflict(&OnConflict{Target: Constraint("db_test_user_pkey"), Action: DoNothing(true)}))
+		assert.ErrorIs(t, err, ErrInvalidParameter)
+		err = rw.Create(testCtx, u, WithOnConflict(&OnConflict{Target: Columns{"public_id"}, Action: UpdateAll(true)}), WithWhere("name = ?", "alice"))
+		assert.ErrorIs(t, err, ErrInvalidParameter)
+	})
+	t.Run("lock", func(t *testing.T) {
+		db, mock := testSetupSqlServerMock(t)
+		mock.ExpectBegin()
+		tx, err := New(db).Begin(testCtx)
+		require.NoError(t, err)
+		err = tx.LookupBy(testCtx, &testUser{PublicId: "u_1234567890"}, WithLock(ForUpdate))
+		assert.ErrorIs(t, err, ErrInvalidParameter)
+	})
+}
+
+func Test_isSqlServerError(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	loginFailed := mssql.Error{Number: sqlServerLoginFailed}
+	assert.True(isSqlServerError(fmt.Errorf("wrapped: %w", loginFailed), sqlServerLoginFailed))
+	assert.False(isSqlServerError(mssql.Error{Number: 1205}, sqlServerLoginFailed))
+	assert.False(isSqlServerError(fmt.Errorf("other"), sqlServerLoginFailed))
+	assert.True(sqlServerDialect{}.IsAuthError(loginFailed))
+}
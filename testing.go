@@ -7,11 +7,14 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io/fs"
+	neturl "net/url"
 	"os"
 	"strings"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	mysqlDriver "github.com/go-sql-driver/mysql"
 	"github.com/xo/dburl"
 	pgDriver "gorm.io/driver/postgres"
 
@@ -23,7 +26,9 @@ import (
 // TestSetup is typically called before starting a test and will setup the
 // database for the test (initialize the database one-time). Do not close the
 // returned db.  Supported test options: WithDebug, WithTestDialect,
-// WithTestDatabaseUrl, WithTestMigration and WithTestMigrationUsingDB.
+// WithTestDatabaseUrl, WithTestMigration, WithTestMigrationUsingDB,
+// WithTestTemplate, WithTestFixtures, WithTestFixtureModels,
+// WithTestFixtureReset and WithTestRecorder.
 func TestSetup(t *testing.T, opt ...TestOption) (*DB, string) {
 	require := require.New(t)
 	var url string
@@ -41,6 +46,10 @@ func TestSetup(t *testing.T, opt ...TestOption) (*DB, string) {
 		opts.withDialect = Postgres.String()
 	case "sqlite":
 		opts.withDialect = Sqlite.String()
+	case "mysql":
+		opts.withDialect = MySQL.String()
+	case "sqlserver":
+		opts.withDialect = SqlServer.String()
 	default:
 		if opts.withDialect == "" {
 			opts.withDialect = Sqlite.String()
@@ -54,7 +63,15 @@ func TestSetup(t *testing.T, opt ...TestOption) (*DB, string) {
 	switch {
 	case opts.withDialect == Postgres.String() && opts.withTestDatabaseUrl == "":
 		t.Fatal("missing postgres test db url")
+	case (opts.withDialect == MySQL.String() || opts.withDialect == SqlServer.String()) && opts.withTestDatabaseUrl == "":
+		t.Fatalf("missing %s test db url", opts.withDialect)
 
+	case opts.withDialect == Sqlite.String() && opts.withTestDatabaseUrl == "" && opts.withTestTemplate:
+		// a uniquely named in-memory database is shared by the db's
+		// connections but not with other tests
+		name, err := NewId("go_db_tmp")
+		require.NoError(err)
+		url = fmt.Sprintf("file:%s?mode=memory&cache=shared", name)
 	case opts.withDialect == Sqlite.String() && opts.withTestDatabaseUrl == "":
 		url = "file::memory:" // just using a temp in-memory sqlite database
 	default:
@@ -71,7 +88,12 @@ func TestSetup(t *testing.T, opt ...TestOption) (*DB, string) {
 		tmpDbName, err := NewId("go_db_tmp")
 		tmpDbName = strings.ToLower(tmpDbName)
 		require.NoError(err)
-		_, err = rw.Exec(ctx, fmt.Sprintf(`create database "%s"`, tmpDbName), nil)
+		if opts.withTestTemplate {
+			tmplName := testPostgresTemplate(t, ctx, db, u, opts)
+			_, err = rw.Exec(ctx, fmt.Sprintf(`create database "%s" template "%s"`, tmpDbName, tmplName), nil)
+		} else {
+			_, err = rw.Exec(ctx, fmt.Sprintf(`create database "%s"`, tmpDbName), nil)
+		}
 		require.NoError(err)
 		t.Cleanup(func() {
 			_, err = rw.Exec(ctx, `select pg_terminate_backend(pid) from pg_stat_activity where datname = ? and pid <> pg_backend_pid()`, []interface{}{tmpDbName})
@@ -88,12 +110,18 @@ func TestSetup(t *testing.T, opt ...TestOption) (*DB, string) {
 		u.Path = strings.Join(namesSegs, "?")
 		url, _, err = dburl.GenPostgres(u)
 		require.NoError(err)
+	case MySQL.String(), SqlServer.String():
+		url = testTmpDatabase(t, ctx, opts.withDialect, opts.withTestDatabaseUrl)
 	}
 
 	dbType, err := StringToDbType(opts.withDialect)
 	require.NoError(err)
 
-	db, err := Open(dbType, url)
+	var openOpts []Option
+	if opts.withTestRecorder != nil {
+		openOpts = append(openOpts, WithRecorder(opts.withTestRecorder))
+	}
+	db, err := Open(dbType, url, openOpts...)
 	require.NoError(err)
 
 	db.wrapped.Logger.LogMode(logger.Error)
@@ -105,12 +133,75 @@ func TestSetup(t *testing.T, opt ...TestOption) (*DB, string) {
 		db.Debug(true)
 	}
 
+	switch {
+	case opts.withTestTemplate && opts.withDialect == Sqlite.String():
+		testSqliteFromTemplate(t, ctx, db, opts)
+	case opts.withTestTemplate && opts.withDialect == Postgres.String():
+		// the postgres database was created from the template
+	default:
+		testMigrate(t, ctx, db, url, opts)
+	}
+	if opts.withTestFixtures != nil {
+		TestFixtures(t, db, opts.withTestFixtures, opts.withTestFixturePatterns, opt...)
+	}
+	if opts.withTestRecorder != nil {
+		// only record the statements issued by the test
+		opts.withTestRecorder.Reset()
+	}
+	return db, url
+}
+
+// testTmpDatabase creates a temporary mysql or sql server database for a test,
+// which is dropped when the test completes, and returns its url.
+func testTmpDatabase(t *testing.T, ctx context.Context, dialect, url string) string {
+	t.Helper()
+	require := require.New(t)
+	dbType, err := StringToDbType(dialect)
+	require.NoError(err)
+	db, err := Open(dbType, url)
+	require.NoError(err)
+	t.Cleanup(func() { assert.NoError(t, db.Close(ctx)) })
+	rw := New(db)
+	tmpDbName, err := NewId("go_db_tmp")
+	require.NoError(err)
+	tmpDbName = strings.ToLower(tmpDbName)
+
+	var tmpUrl, drop string
+	switch dbType {
+	case MySQL:
+		cfg, err := mysqlDriver.ParseDSN(url)
+		require.NoError(err)
+		cfg.DBName = tmpDbName
+		tmpUrl = cfg.FormatDSN()
+		drop = fmt.Sprintf("drop database %s", db.dialect().Quote(tmpDbName))
+	default:
+		u, err := neturl.Parse(url)
+		require.NoError(err)
+		q := u.Query()
+		q.Set("database", tmpDbName)
+		u.RawQuery = q.Encode()
+		tmpUrl = u.String()
+		drop = fmt.Sprintf("alter database %[1]s set single_user with rollback immediate; drop database %[1]s", db.dialect().Quote(tmpDbName))
+	}
+	_, err = rw.Exec(ctx, fmt.Sprintf("create database %s", db.dialect().Quote(tmpDbName)), nil)
+	require.NoError(err)
+	t.Cleanup(func() {
+		_, err := rw.Exec(ctx, drop, nil)
+		assert.NoError(t, err)
+	})
+	return tmpUrl
+}
+
+// testMigrate runs the migrations for TestSetup(...)
+func testMigrate(t *testing.T, ctx context.Context, db *DB, url string, opts testOptions) {
+	t.Helper()
+	require := require.New(t)
 	// we're only going to run one set of migrations.  Either one of the
 	// migration functions passed in as an option or the default
 	// TestCreateTables(...)
 	switch {
 	case opts.withTestMigration != nil:
-		err = opts.withTestMigration(ctx, opts.withDialect, url)
+		err := opts.withTestMigration(ctx, opts.withDialect, url)
 		require.NoError(err)
 
 	case opts.withTestMigrationUsingDb != nil:
@@ -137,12 +228,11 @@ func TestSetup(t *testing.T, opt ...TestOption) (*DB, string) {
 				require.NoError(err)
 			}
 		}
-		err = opts.withTestMigrationUsingDb(ctx, rawDB)
+		err := opts.withTestMigrationUsingDb(ctx, rawDB)
 		require.NoError(err)
 	default:
 		TestCreateTables(t, db)
 	}
-	return db, url
 }
 
 // TestSetupWithMock will return a test DB and an associated Sqlmock which can
@@ -179,6 +269,13 @@ type testOptions struct {
 	withTestMigration        func(ctx context.Context, dialect, url string) error
 	withTestMigrationUsingDb func(ctx context.Context, db *sql.DB) error
 	withTestDebug            bool
+	withTestTemplate         bool
+	withTestTemplateKey      string
+	withTestFixtures         fs.FS
+	withTestFixturePatterns  []string
+	withTestFixtureModels    []interface{}
+	withTestFixtureReset     bool
+	withTestRecorder         *Recorder
 }
 
 func getDefaultTestOptions() testOptions {
@@ -209,6 +306,25 @@ func WithTestMigrationUsingDB(migrationFn func(ctx context.Context, db *sql.DB)
 	}
 }
 
+// WithTestTemplate provides a way to run the test migrations once into a
+// template database, which is then cloned for each test.  This is much faster
+// than running the migrations for every test and it's safe for parallel
+// tests.  The key identifies the migrations and it must be changed whenever
+// the migrations change.  The key may be empty when using the default
+// TestCreateTables(...) migrations.
+//
+// Postgres creates each test database with "create database ... template"
+// and templates are kept, so they can be reused by later test runs.  Sqlite
+// uses a uniquely named in-memory database for each test, which is copied from
+// an in-memory template using the sqlite backup API.  MySQL and SQL Server
+// don't support templates, so the migrations are run for every test.
+func WithTestTemplate(key string) TestOption {
+	return func(o *testOptions) {
+		o.withTestTemplate = true
+		o.withTestTemplateKey = key
+	}
+}
+
 // WithTestDatabaseUrl provides a way to specify an existing database for tests
 func WithTestDatabaseUrl(url string) TestOption {
 	return func(o *testOptions) {
@@ -216,23 +332,21 @@ func WithTestDatabaseUrl(url string) TestOption {
 	}
 }
 
-// TestCreateTables will create the test tables for the dbw pkg
+// TestCreateTables will create the test tables for the dbw pkg, using the
+// test schema of the conn's dialect (see Dialect.TestSchema)
 func TestCreateTables(t *testing.T, conn *DB) {
 	t.Helper()
 	require := require.New(t)
 	testCtx := context.Background()
 	rw := New(conn)
-	var query string
-	switch conn.wrapped.Dialector.Name() {
-	case "sqlite":
-		query = testQueryCreateTablesSqlite
-	case "postgres":
-		query = testQueryCreateTablesPostgres
-	default:
+	queries, _ := conn.dialect().TestSchema()
+	if len(queries) == 0 {
 		t.Fatalf("unknown dialect: %s", conn.wrapped.Dialector.Name())
 	}
-	_, err := rw.Exec(testCtx, query, nil)
-	require.NoError(err)
+	for _, query := range queries {
+		_, err := rw.Exec(testCtx, query, nil)
+		require.NoError(err)
+	}
 }
 
 func testDropTables(t *testing.T, conn *DB) {
@@ -240,17 +354,14 @@ func testDropTables(t *testing.T, conn *DB) {
 	require := require.New(t)
 	testCtx := context.Background()
 	rw := New(conn)
-	var query string
-	switch conn.wrapped.Dialector.Name() {
-	case "sqlite":
-		query = testQueryDropTablesSqlite
-	case "postgres":
-		query = testQueryDropTablesPostgres
-	default:
+	_, queries := conn.dialect().TestSchema()
+	if len(queries) == 0 {
 		t.Fatalf("unknown dialect: %s", conn.wrapped.Dialector.Name())
 	}
-	_, err := rw.Exec(testCtx, query, nil)
-	require.NoError(err)
+	for _, query := range queries {
+		_, err := rw.Exec(testCtx, query, nil)
+		require.NoError(err)
+	}
 }
 
 const (
@@ -742,3 +853,292 @@ drop domain if exists wt_version;
 commit;
 	`
 )
+
+// The mysql and sql server test tables are created one statement at a time,
+// since mysql only executes multiple statements when the DSN enables
+// multiStatements and sql server requires a trigger to be the only statement
+// of its batch.  Their triggers match the sqlite and postgres triggers:
+// create_time is immutable, update_time is set whenever the row changes and
+// version is incremented whenever the row's data changes.
+var (
+	testQueriesCreateTablesMysql = []string{
+		`create table if not exists db_test_user (
+  public_id varchar(255) not null,
+  create_time datetime(6) not null default current_timestamp(6),
+  update_time datetime(6) not null default current_timestamp(6),
+  name varchar(255) unique,
+  phone_number text,
+  email text,
+  version int default 1,
+  constraint db_test_user_pkey primary key (public_id)
+)`,
+		`create trigger default_create_time_column_db_test_user
+before insert on db_test_user
+for each row
+  set new.create_time = current_timestamp(6)`,
+		`create trigger update_columns_db_test_user
+before update on db_test_user
+for each row
+begin
+  if not (new.create_time <=> old.create_time) then
+    signal sqlstate '45000' set message_text = 'immutable column';
+  end if;
+  if not (
+    new.public_id <=> old.public_id and
+    new.name <=> old.name and
+    new.phone_number <=> old.phone_number and
+    new.email <=> old.email
+  ) then
+    set new.version = old.version + 1;
+  end if;
+  if not (
+    new.public_id <=> old.public_id and
+    new.name <=> old.name and
+    new.phone_number <=> old.phone_number and
+    new.email <=> old.email and
+    new.version <=> old.version
+  ) then
+    set new.update_time = current_timestamp(6);
+  end if;
+end`,
+
+		`create table if not exists db_test_car (
+  public_id varchar(255) not null,
+  create_time datetime(6) not null default current_timestamp(6),
+  update_time datetime(6) not null default current_timestamp(6),
+  name varchar(255) unique,
+  model text,
+  mpg smallint,
+  version int default 1,
+  constraint db_test_car_pkey primary key (public_id)
+)`,
+		`create trigger default_create_time_column_db_test_car
+before insert on db_test_car
+for each row
+  set new.create_time = current_timestamp(6)`,
+		`create trigger update_columns_db_test_car
+before update on db_test_car
+for each row
+begin
+  if not (
+    new.public_id <=> old.public_id and
+    new.name <=> old.name and
+    new.model <=> old.model and
+    new.mpg <=> old.mpg
+  ) then
+    set new.version = old.version + 1;
+  end if;
+  if not (
+    new.public_id <=> old.public_id and
+    new.name <=> old.name and
+    new.model <=> old.model and
+    new.mpg <=> old.mpg and
+    new.version <=> old.version
+  ) then
+    set new.update_time = current_timestamp(6);
+  end if;
+end`,
+
+		`create table if not exists db_test_rental (
+  user_id varchar(255) not null,
+  car_id varchar(255) not null,
+  create_time datetime(6) not null default current_timestamp(6),
+  update_time datetime(6) not null default current_timestamp(6),
+  name varchar(255) unique,
+  version int default 1,
+  constraint db_test_rental_pkey primary key (user_id, car_id),
+  foreign key (user_id) references db_test_user(public_id),
+  foreign key (car_id) references db_test_car(public_id)
+)`,
+		`create trigger default_create_time_column_db_test_rental
+before insert on db_test_rental
+for each row
+  set new.create_time = current_timestamp(6)`,
+		`create trigger update_columns_db_test_rental
+before update on db_test_rental
+for each row
+begin
+  if not (new.create_time <=> old.create_time) then
+    signal sqlstate '45000' set message_text = 'immutable column';
+  end if;
+  if not (
+    new.user_id <=> old.user_id and
+    new.car_id <=> old.car_id and
+    new.name <=> old.name
+  ) then
+    set new.version = old.version + 1;
+  end if;
+  if not (
+    new.user_id <=> old.user_id and
+    new.car_id <=> old.car_id and
+    new.name <=> old.name and
+    new.version <=> old.version
+  ) then
+    set new.update_time = current_timestamp(6);
+  end if;
+end`,
+
+		`create table if not exists db_test_scooter (
+  private_id varchar(255) not null,
+  create_time datetime(6) not null default current_timestamp(6),
+  update_time datetime(6) not null default current_timestamp(6),
+  name varchar(255) unique,
+  model text,
+  mpg smallint,
+  version int default 1,
+  constraint db_test_scooter_pkey primary key (private_id)
+)`,
+		`create trigger default_create_time_column_db_test_scooter
+before insert on db_test_scooter
+for each row
+  set new.create_time = current_timestamp(6)`,
+		`create trigger update_columns_db_test_scooter
+before update on db_test_scooter
+for each row
+begin
+  if not (
+    new.private_id <=> old.private_id and
+    new.name <=> old.name and
+    new.model <=> old.model and
+    new.mpg <=> old.mpg
+  ) then
+    set new.version = old.version + 1;
+  end if;
+  if not (
+    new.private_id <=> old.private_id and
+    new.name <=> old.name and
+    new.model <=> old.model and
+    new.mpg <=> old.mpg and
+    new.version <=> old.version
+  ) then
+    set new.update_time = current_timestamp(6);
+  end if;
+end`,
+	}
+
+	testQueriesCreateTablesSqlServer = []string{
+		`create table db_test_user (
+  public_id nvarchar(255) not null constraint db_test_user_pkey primary key,
+  create_time datetime2 not null default sysutcdatetime(),
+  update_time datetime2 not null default sysutcdatetime(),
+  name nvarchar(255),
+  phone_number nvarchar(max),
+  email nvarchar(max),
+  version int default 1
+)`,
+		// sql server unique constraints only allow a single null, so unique
+		// names use a filtered index
+		`create unique index db_test_user_name_key on db_test_user(name) where name is not null`,
+		`create trigger update_columns_db_test_user
+on db_test_user
+after update
+as
+begin
+  set nocount on;
+  if exists (select 1 from inserted i join deleted d on d.public_id = i.public_id where i.create_time <> d.create_time)
+    throw 50000, 'immutable column', 1;
+  update t set
+    version = case
+      when exists (select i.name, i.phone_number, i.email except select d.name, d.phone_number, d.email) then d.version + 1
+      else i.version
+    end,
+    update_time = sysutcdatetime()
+  from db_test_user t
+  join inserted i on i.public_id = t.public_id
+  join deleted d on d.public_id = i.public_id
+  where exists (select i.name, i.phone_number, i.email, i.version except select d.name, d.phone_number, d.email, d.version);
+end`,
+
+		`create table db_test_car (
+  public_id nvarchar(255) not null constraint db_test_car_pkey primary key,
+  create_time datetime2 not null default sysutcdatetime(),
+  update_time datetime2 not null default sysutcdatetime(),
+  name nvarchar(255),
+  model nvarchar(max),
+  mpg smallint,
+  version int default 1
+)`,
+		`create unique index db_test_car_name_key on db_test_car(name) where name is not null`,
+		`create trigger update_columns_db_test_car
+on db_test_car
+after update
+as
+begin
+  set nocount on;
+  update t set
+    version = case
+      when exists (select i.name, i.model, i.mpg except select d.name, d.model, d.mpg) then d.version + 1
+      else i.version
+    end,
+    update_time = sysutcdatetime()
+  from db_test_car t
+  join inserted i on i.public_id = t.public_id
+  join deleted d on d.public_id = i.public_id
+  where exists (select i.name, i.model, i.mpg, i.version except select d.name, d.model, d.mpg, d.version);
+end`,
+
+		`create table db_test_rental (
+  user_id nvarchar(255) not null references db_test_user(public_id),
+  car_id nvarchar(255) not null references db_test_car(public_id),
+  create_time datetime2 not null default sysutcdatetime(),
+  update_time datetime2 not null default sysutcdatetime(),
+  name nvarchar(255),
+  version int default 1,
+  constraint db_test_rental_pkey primary key (user_id, car_id)
+)`,
+		`create unique index db_test_rental_name_key on db_test_rental(name) where name is not null`,
+		`create trigger update_columns_db_test_rental
+on db_test_rental
+after update
+as
+begin
+  set nocount on;
+  if exists (select 1 from inserted i join deleted d on d.user_id = i.user_id and d.car_id = i.car_id where i.create_time <> d.create_time)
+    throw 50000, 'immutable column', 1;
+  update t set
+    version = case
+      when exists (select i.name except select d.name) then d.version + 1
+      else i.version
+    end,
+    update_time = sysutcdatetime()
+  from db_test_rental t
+  join inserted i on i.user_id = t.user_id and i.car_id = t.car_id
+  join deleted d on d.user_id = i.user_id and d.car_id = i.car_id
+  where exists (select i.name, i.version except select d.name, d.version);
+end`,
+
+		`create table db_test_scooter (
+  private_id nvarchar(255) not null constraint db_test_scooter_pkey primary key,
+  create_time datetime2 not null default sysutcdatetime(),
+  update_time datetime2 not null default sysutcdatetime(),
+  name nvarchar(255),
+  model nvarchar(max),
+  mpg smallint,
+  version int default 1
+)`,
+		`create unique index db_test_scooter_name_key on db_test_scooter(name) where name is not null`,
+		`create trigger update_columns_db_test_scooter
+on db_test_scooter
+after update
+as
+begin
+  set nocount on;
+  update t set
+    version = case
+      when exists (select i.name, i.model, i.mpg except select d.name, d.model, d.mpg) then d.version + 1
+      else i.version
+    end,
+    update_time = sysutcdatetime()
+  from db_test_scooter t
+  join inserted i on i.private_id = t.private_id
+  join deleted d on d.private_id = i.private_id
+  where exists (select i.name, i.model, i.mpg, i.version except select d.name, d.model, d.mpg, d.version);
+end`,
+	}
+)
+
+const (
+	testQueryDropTablesMysql = `drop table if exists db_test_rental, db_test_user, db_test_car, db_test_scooter`
+
+	testQueryDropTablesSqlServer = `drop table if exists db_test_rental, db_test_user, db_test_car, db_test_scooter`
+)
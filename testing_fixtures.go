@@ -0,0 +1,471 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// FixtureRefPrefix is the prefix of a fixture value which references a column
+// of another fixture: "@table.label.column".  A value which starts with "@@"
+// is the literal value with a single "@".
+const FixtureRefPrefix = "@"
+
+// DefaultFixturePatterns are the file patterns loaded by TestFixtures(...)
+// when no patterns are provided.
+var DefaultFixturePatterns = []string{"*.yml", "*.yaml", "*.json"}
+
+// Fixtures are the test fixtures loaded by TestFixtures(...)
+type Fixtures struct {
+	rows map[string]map[string]*fixtureRow
+}
+
+// fixtureRow is a single fixture
+type fixtureRow struct {
+	table   string
+	label   string
+	columns map[string]interface{}
+	model   interface{}
+	deps    []fixtureRef
+}
+
+// fixtureRef is a reference to a column of another fixture
+type fixtureRef struct {
+	table  string
+	label  string
+	column string
+}
+
+// Model returns the model of the fixture.  It's nil for fixtures loaded as
+// raw columns.
+func (f *Fixtures) Model(table, label string) interface{} {
+	if r, ok := f.rows[table][label]; ok {
+		return r.model
+	}
+	return nil
+}
+
+// Value returns a column value of the fixture, which includes any values set
+// when the fixture's model was created.
+func (f *Fixtures) Value(table, label, column string) (interface{}, bool) {
+	r, ok := f.rows[table][label]
+	if !ok {
+		return nil, false
+	}
+	v, ok := r.columns[column]
+	return v, ok
+}
+
+// WithTestFixtures provides a way to load test fixtures from the files in
+// fsys which match the patterns (DefaultFixturePatterns when none are
+// provided) after the test migrations are run.  See TestFixtures(...)
+func WithTestFixtures(fsys fs.FS, patterns ...string) TestOption {
+	return func(o *testOptions) {
+		o.withTestFixtures = fsys
+		o.withTestFixturePatterns = patterns
+	}
+}
+
+// WithTestFixtureModels provides a way to specify the models which fixtures
+// are loaded into, by the model's table name.  Fixtures for tables without a
+// model are inserted as raw columns.
+func WithTestFixtureModels(models ...interface{}) TestOption {
+	return func(o *testOptions) {
+		o.withTestFixtureModels = append(o.withTestFixtureModels, models...)
+	}
+}
+
+// WithTestFixtureReset provides a way to delete all the rows of the fixture
+// tables before the fixtures are loaded and again when the test completes.
+// This is only needed when tests share a database (see WithTestDatabaseUrl).
+func WithTestFixtureReset() TestOption {
+	return func(o *testOptions) {
+		o.withTestFixtureReset = true
+	}
+}
+
+// TestFixtures will load test fixtures from the YAML (.yml or .yaml) and JSON
+// (.json) files in fsys which match the patterns (DefaultFixturePatterns when
+// none are provided).  Supported test options: WithTestFixtureModels and
+// WithTestFixtureReset.
+//
+// Fixture files are keyed by table, then by fixture label, then by column:
+//
+//	db_test_user:
+//	  alice:
+//	    public_id: u_1234567890
+//	    name: alice
+//	db_test_rental:
+//	  alice_car:
+//	    user_id: "@db_test_user.alice.public_id"
+//	    car_id: "@db_test_car.sedan.public_id"
+//
+// Values which start with "@" reference a column of another fixture as
+// "@table.label.column" and fixtures are inserted in the order of their
+// references.  Fixtures for a table with a model (see WithTestFixtureModels)
+// are inserted using CreateItems(...), otherwise they are inserted as raw
+// columns.
+func TestFixtures(t *testing.T, db *DB, fsys fs.FS, patterns []string, opt ...TestOption) *Fixtures {
+	t.Helper()
+	require := require.New(t)
+	ctx := context.Background()
+	opts := getTestOpts(opt...)
+
+	f, err := loadFixtures(db, fsys, patterns, opts.withTestFixtureModels)
+	require.NoError(err)
+	order, err := f.insertOrder()
+	require.NoError(err)
+
+	if opts.withTestFixtureReset {
+		require.NoError(f.reset(ctx, db, order))
+		t.Cleanup(func() {
+			require.NoError(f.reset(ctx, db, order))
+		})
+	}
+	require.NoError(f.insert(ctx, db, order))
+	return f
+}
+
+// loadFixtures loads the fixture files and allocates their models.
+func loadFixtures(db *DB, fsys fs.FS, patterns []string, models []interface{}) (*Fixtures, error) {
+	const op = "dbw.loadFixtures"
+	if fsys == nil {
+		return nil, fmt.Errorf("%s: missing file system: %w", op, ErrInvalidParameter)
+	}
+	if len(patterns) == 0 {
+		patterns = DefaultFixturePatterns
+	}
+
+	modelTypes := map[string]reflect.Type{}
+	for _, m := range models {
+		if isNil(m) || reflect.TypeOf(m).Kind() != reflect.Ptr || reflect.TypeOf(m).Elem().Kind() != reflect.Struct {
+			return nil, fmt.Errorf("%s: model %T is not a pointer to a struct: %w", op, m, ErrInvalidParameter)
+		}
+		mDb := db.wrapped.Model(m)
+		if err := mDb.Statement.Parse(m); err != nil || mDb.Statement.Schema == nil {
+			return nil, fmt.Errorf("%s: unable to parse model %T: %w", op, m, ErrInvalidParameter)
+		}
+		modelTypes[mDb.Statement.Schema.Table] = reflect.TypeOf(m).Elem()
+	}
+
+	var files []string
+	seen := map[string]bool{}
+	for _, p := range patterns {
+		matches, err := fs.Glob(fsys, p)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		for _, m := range matches {
+			if !seen[m] {
+				seen[m] = true
+				files = append(files, m)
+			}
+		}
+	}
+	sort.Strings(files)
+
+	f := &Fixtures{rows: map[string]map[string]*fixtureRow{}}
+	for _, name := range files {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		tables := map[string]map[string]map[string]interface{}{}
+		switch strings.ToLower(path.Ext(name)) {
+		case ".json":
+			dec := json.NewDecoder(bytes.NewReader(data))
+			dec.UseNumber()
+			err = dec.Decode(&tables)
+		default:
+			err = yaml.Unmarshal(data, &tables)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: unable to decode %s: %w", op, name, err)
+		}
+		for table, rows := range tables {
+			if f.rows[table] == nil {
+				f.rows[table] = map[string]*fixtureRow{}
+			}
+			for label, columns := range rows {
+				if _, ok := f.rows[table][label]; ok {
+					return nil, fmt.Errorf("%s: %s: duplicate fixture %s.%s: %w", op, name, table, label, ErrInvalidParameter)
+				}
+				r := &fixtureRow{table: table, label: label, columns: map[string]interface{}{}}
+				for col, v := range columns {
+					v = fixtureValue(v)
+					if s, ok := v.(string); ok && strings.HasPrefix(s, FixtureRefPrefix) {
+						if strings.HasPrefix(s, FixtureRefPrefix+FixtureRefPrefix) {
+							v = strings.TrimPrefix(s, FixtureRefPrefix)
+						} else {
+							ref, err := parseFixtureRef(s)
+							if err != nil {
+								return nil, fmt.Errorf("%s: %s: %s.%s.%s: %w", op, name, table, label, col, err)
+							}
+							r.deps = append(r.deps, ref)
+							v = ref
+						}
+					}
+					r.columns[col] = v
+				}
+				if typ, ok := modelTypes[table]; ok {
+					r.model = reflect.New(typ).Interface()
+				}
+				f.rows[table][label] = r
+			}
+		}
+	}
+
+	for _, rows := range f.rows {
+		for _, r := range rows {
+			for _, ref := range r.deps {
+				if _, ok := f.rows[ref.table][ref.label]; !ok {
+					return nil, fmt.Errorf("%s: %s.%s references unknown fixture %s.%s: %w", op, r.table, r.label, ref.table, ref.label, ErrInvalidParameter)
+				}
+			}
+		}
+	}
+	return f, nil
+}
+
+// parseFixtureRef parses a reference of "@table.label.column"
+func parseFixtureRef(s string) (fixtureRef, error) {
+	const op = "dbw.parseFixtureRef"
+	parts := strings.Split(strings.TrimPrefix(s, FixtureRefPrefix), ".")
+	if len(parts) < 3 {
+		return fixtureRef{}, fmt.Errorf("%s: %q is not a reference of @table.label.column: %w", op, s, ErrInvalidParameter)
+	}
+	n := len(parts)
+	ref := fixtureRef{
+		table:  strings.Join(parts[:n-2], "."),
+		label:  parts[n-2],
+		column: parts[n-1],
+	}
+	if ref.table == "" || ref.label == "" || ref.column == "" {
+		return fixtureRef{}, fmt.Errorf("%s: %q is not a reference of @table.label.column: %w", op, s, ErrInvalidParameter)
+	}
+	return ref, nil
+}
+
+// fixtureValue converts decoded JSON numbers to an int64 or float64
+func fixtureValue(v interface{}) interface{} {
+	n, ok := v.(json.Number)
+	if !ok {
+		return v
+	}
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	if f, err := n.Float64(); err == nil {
+		return f
+	}
+	return n.String()
+}
+
+// insertOrder returns batches of fixtures of a single table, ordered so
+// every fixture is inserted after the fixtures it references.
+func (f *Fixtures) insertOrder() ([][]*fixtureRow, error) {
+	const op = "dbw.(Fixtures).insertOrder"
+	tables := make([]string, 0, len(f.rows))
+	remaining := 0
+	for table, rows := range f.rows {
+		tables = append(tables, table)
+		remaining += len(rows)
+	}
+	sort.Strings(tables)
+
+	inserted := map[*fixtureRow]bool{}
+	var order [][]*fixtureRow
+	for remaining > 0 {
+		progress := false
+		for _, table := range tables {
+			labels := make([]string, 0, len(f.rows[table]))
+			for label := range f.rows[table] {
+				labels = append(labels, label)
+			}
+			sort.Strings(labels)
+			var batch []*fixtureRow
+			for _, label := range labels {
+				r := f.rows[table][label]
+				if inserted[r] {
+					continue
+				}
+				ready := true
+				for _, ref := range r.deps {
+					ready = ready && inserted[f.rows[ref.table][ref.label]]
+				}
+				if ready {
+					batch = append(batch, r)
+				}
+			}
+			if len(batch) == 0 {
+				continue
+			}
+			for _, r := range batch {
+				inserted[r] = true
+			}
+			remaining -= len(batch)
+			order = append(order, batch)
+			progress = true
+		}
+		if !progress {
+			return nil, fmt.Errorf("%s: fixtures have a reference cycle: %w", op, ErrInvalidParameter)
+		}
+	}
+	return order, nil
+}
+
+// insert the fixtures in order, resolving their references.
+func (f *Fixtures) insert(ctx context.Context, db *DB, order [][]*fixtureRow) error {
+	const op = "dbw.(Fixtures).insert"
+	rw := New(db)
+	for _, batch := range order {
+		for _, r := range batch {
+			for col, v := range r.columns {
+				if ref, ok := v.(fixtureRef); ok {
+					resolved, ok := f.Value(ref.table, ref.label, ref.column)
+					if !ok {
+						return fmt.Errorf("%s: %s.%s.%s references unknown column %s: %w", op, r.table, r.label, col, ref.column, ErrInvalidParameter)
+					}
+					r.columns[col] = resolved
+				}
+			}
+		}
+
+		if batch[0].model == nil {
+			for _, r := range batch {
+				if err := db.wrapped.WithContext(ctx).Table(r.table).Create(r.columns).Error; err != nil {
+					return fmt.Errorf("%s: %s.%s: %w", op, r.table, r.label, err)
+				}
+			}
+			continue
+		}
+
+		// sqlite doesn't support default values in a multi-row insert, so
+		// fixtures are created in batches with the same columns.
+		var signatures []string
+		batches := map[string]reflect.Value{}
+		for _, r := range batch {
+			if err := setFixtureModel(ctx, db, r); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			cols := make([]string, 0, len(r.columns))
+			for col := range r.columns {
+				cols = append(cols, col)
+			}
+			sort.Strings(cols)
+			sig := strings.Join(cols, ",")
+			items, ok := batches[sig]
+			if !ok {
+				signatures = append(signatures, sig)
+				items = reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(r.model)), 0, len(batch))
+			}
+			batches[sig] = reflect.Append(items, reflect.ValueOf(r.model))
+		}
+		for _, sig := range signatures {
+			if err := rw.CreateItems(ctx, batches[sig].Interface()); err != nil {
+				return fmt.Errorf("%s: %s: %w", op, batch[0].table, err)
+			}
+		}
+		for _, r := range batch {
+			if err := getFixtureModel(ctx, db, r); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+	}
+	return nil
+}
+
+// setFixtureModel sets the fixture's model fields from its columns
+func setFixtureModel(ctx context.Context, db *DB, r *fixtureRow) error {
+	const op = "dbw.setFixtureModel"
+	mDb := db.wrapped.Model(r.model)
+	if err := mDb.Statement.Parse(r.model); err != nil || mDb.Statement.Schema == nil {
+		return fmt.Errorf("%s: unable to parse model %T: %w", op, r.model, ErrInvalidParameter)
+	}
+	if err := allocEmbedded(reflect.ValueOf(r.model)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	for col, v := range r.columns {
+		field := mDb.Statement.Schema.LookUpField(col)
+		if field == nil {
+			return fmt.Errorf("%s: %s.%s: %T does not have a %s column: %w", op, r.table, r.label, r.model, col, ErrInvalidParameter)
+		}
+		if err := field.Set(ctx, reflect.ValueOf(r.model), v); err != nil {
+			return fmt.Errorf("%s: %s.%s: unable to set %s: %w", op, r.table, r.label, col, err)
+		}
+	}
+	return nil
+}
+
+// getFixtureModel sets the fixture's columns from its model fields
+func getFixtureModel(ctx context.Context, db *DB, r *fixtureRow) error {
+	const op = "dbw.getFixtureModel"
+	mDb := db.wrapped.Model(r.model)
+	if err := mDb.Statement.Parse(r.model); err != nil || mDb.Statement.Schema == nil {
+		return fmt.Errorf("%s: unable to parse model %T: %w", op, r.model, ErrInvalidParameter)
+	}
+	for _, field := range mDb.Statement.Schema.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		if v, isZero := field.ValueOf(ctx, reflect.ValueOf(r.model)); !isZero {
+			r.columns[field.DBName] = v
+		}
+	}
+	return nil
+}
+
+// allocEmbedded allocates the nil embedded struct pointers of the model
+func allocEmbedded(v reflect.Value) error {
+	const op = "dbw.allocEmbedded"
+	v = v.Elem()
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		if !sf.Anonymous || sf.Type.Kind() != reflect.Ptr || sf.Type.Elem().Kind() != reflect.Struct {
+			continue
+		}
+		fv := v.Field(i)
+		if !fv.IsNil() {
+			continue
+		}
+		if !fv.CanSet() {
+			return fmt.Errorf("%s: unable to allocate %s: %w", op, sf.Type, ErrInvalidParameter)
+		}
+		fv.Set(reflect.New(sf.Type.Elem()))
+		if err := allocEmbedded(fv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reset deletes all the rows of the fixture tables, in the reverse of the
+// insert order.
+func (f *Fixtures) reset(ctx context.Context, db *DB, order [][]*fixtureRow) error {
+	const op = "dbw.(Fixtures).reset"
+	deleted := map[string]bool{}
+	for i := len(order) - 1; i >= 0; i-- {
+		table := order[i][0].table
+		if deleted[table] {
+			continue
+		}
+		deleted[table] = true
+		if err := db.wrapped.WithContext(ctx).Exec(fmt.Sprintf(`delete from %s`, db.wrapped.Statement.Quote(table))).Error; err != nil {
+			return fmt.Errorf("%s: %s: %w", op, table, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testFixturesFS = fstest.MapFS{
+	"users.yml": &fstest.MapFile{Data: []byte(`
+db_test_user:
+  alice:
+    public_id: u_fixture_alice
+    name: alice
+    email: "@@alice"
+  bob:
+    public_id: u_fixture_bob
+    name: bob
+`)},
+	"cars.json": &fstest.MapFile{Data: []byte(`{
+  "db_test_car": {
+    "sedan": {"public_id": "c_fixture_sedan", "name": "sedan", "mpg": 32}
+  }
+}`)},
+	"rentals.yaml": &fstest.MapFile{Data: []byte(`
+db_test_rental:
+  alice_sedan:
+    user_id: "@db_test_user.alice.public_id"
+    car_id: "@db_test_car.sedan.public_id"
+    name: alice-sedan
+`)},
+}
+
+func TestTestFixtures(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	t.Run("with-test-fixtures", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		db, _ := TestSetup(t, WithTestFixtures(testFixturesFS), WithTestFixtureModels(&testUser{}))
+		rw := New(db)
+
+		u := &testUser{PublicId: "u_fixture_alice"}
+		require.NoError(rw.LookupBy(testCtx, u))
+		assert.Equal("alice", u.Name)
+		assert.Equal("@alice", u.Email)
+
+		rows, err := rw.Query(testCtx, `select user_id, car_id from db_test_rental where name = ?`, []interface{}{"alice-sedan"})
+		require.NoError(err)
+		defer rows.Close()
+		require.True(rows.Next())
+		var userId, carId string
+		require.NoError(rows.Scan(&userId, &carId))
+		assert.Equal("u_fixture_alice", userId)
+		assert.Equal("c_fixture_sedan", carId)
+	})
+	t.Run("models-and-values", func(t *testing.T) {
+		assert := assert.New(t)
+		db, _ := TestSetup(t)
+		f := TestFixtures(t, db, testFixturesFS, nil, WithTestFixtureModels(&testUser{}))
+
+		u, ok := f.Model("db_test_user", "bob").(*testUser)
+		if assert.True(ok) {
+			assert.Equal("bob", u.Name)
+			// the version is set by the database
+			assert.Equal(uint32(1), u.Version)
+		}
+		assert.Nil(f.Model("db_test_car", "sedan"))
+		v, ok := f.Value("db_test_car", "sedan", "mpg")
+		assert.True(ok)
+		assert.Equal(int64(32), v)
+		_, ok = f.Value("db_test_car", "missing", "mpg")
+		assert.False(ok)
+	})
+	t.Run("reset", func(t *testing.T) {
+		require := require.New(t)
+		url := filepath.Join(t.TempDir(), "fixtures.db")
+		db, _ := TestSetup(t, WithTestDatabaseUrl(url))
+		rw := New(db)
+		t.Run("load", func(t *testing.T) {
+			TestFixtures(t, db, testFixturesFS, []string{"users.yml"}, WithTestFixtureReset())
+		})
+		// the fixtures were deleted when the subtest completed
+		var users []*testUser
+		require.NoError(rw.SearchWhere(testCtx, &users, "", nil))
+		require.Empty(users)
+	})
+}
+
+func Test_loadFixtures(t *testing.T) {
+	t.Parallel()
+	db, _ := TestSetup(t)
+	tests := []struct {
+		name            string
+		fs              fstest.MapFS
+		wantErrContains string
+	}{
+		{
+			name: "unknown-reference",
+			fs: fstest.MapFS{"a.yml": &fstest.MapFile{Data: []byte(`
+db_test_rental:
+  r:
+    user_id: "@db_test_user.nobody.public_id"
+`)}},
+			wantErrContains: "references unknown fixture db_test_user.nobody",
+		},
+		{
+			name: "bad-reference",
+			fs: fstest.MapFS{"a.yml": &fstest.MapFile{Data: []byte(`
+db_test_rental:
+  r:
+    user_id: "@db_test_user"
+`)}},
+			wantErrContains: "is not a reference of @table.label.column",
+		},
+		{
+			name: "duplicate",
+			fs: fstest.MapFS{
+				"a.yml": &fstest.MapFile{Data: []byte("db_test_user:\n  alice:\n    name: a\n")},
+				"b.yml": &fstest.MapFile{Data: []byte("db_test_user:\n  alice:\n    name: b\n")},
+			},
+			wantErrContains: "duplicate fixture db_test_user.alice",
+		},
+		{
+			name: "invalid-file",
+			fs: fstest.MapFS{
+				"a.json": &fstest.MapFile{Data: []byte("{")},
+			},
+			wantErrContains: "unable to decode a.json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadFixtures(db, tt.fs, nil, nil)
+			require.Error(t, err)
+			assert.ErrorContains(t, err, tt.wantErrContains)
+		})
+	}
+	t.Run("cycle", func(t *testing.T) {
+		f, err := loadFixtures(db, fstest.MapFS{"a.yml": &fstest.MapFile{Data: []byte(`
+db_test_user:
+  a:
+    name: "@db_test_user.b.name"
+  b:
+    name: "@db_test_user.a.name"
+`)}}, nil, nil)
+		require.NoError(t, err)
+		_, err = f.insertOrder()
+		assert.ErrorIs(t, err, ErrInvalidParameter)
+	})
+}
@@ -0,0 +1,111 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// WithTestRecorder provides a way to capture the statements executed by the
+// test's db with a Recorder.  The statements executed by TestSetup(...), like
+// migrations and fixtures, are not recorded.
+func WithTestRecorder(r *Recorder) TestOption {
+	return func(o *testOptions) {
+		o.withTestRecorder = r
+	}
+}
+
+// AssertQueryCount asserts the number of statements recorded.
+func AssertQueryCount(t *testing.T, r *Recorder, want int, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	statements := r.Statements()
+	if len(statements) != want {
+		return assert.Fail(t, fmt.Sprintf("want %d queries, got %d:\n%s", want, len(statements), formatStatements(statements)), msgAndArgs...)
+	}
+	return true
+}
+
+// AssertNoQueriesMatching asserts that no recorded statement's sql matches the
+// regular expression.
+func AssertNoQueriesMatching(t *testing.T, r *Recorder, pattern string, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	matching, err := r.Matching(pattern)
+	if !assert.NoError(t, err, msgAndArgs...) {
+		return false
+	}
+	if len(matching) > 0 {
+		return assert.Fail(t, fmt.Sprintf("unexpected queries matching %q:\n%s", pattern, formatStatements(matching)), msgAndArgs...)
+	}
+	return true
+}
+
+// AssertTableTouched asserts that at least one statement was recorded for the
+// table.
+func AssertTableTouched(t *testing.T, r *Recorder, table string, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	for _, s := range r.Statements() {
+		if s.Table == table {
+			return true
+		}
+	}
+	return assert.Fail(t, fmt.Sprintf("table %s was not touched", table), msgAndArgs...)
+}
+
+// AssertInTx asserts that every recorded statement with sql matching the
+// regular expression was executed in a transaction.  An empty pattern
+// matches every statement.
+func AssertInTx(t *testing.T, r *Recorder, pattern string, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	matching, err := r.Matching(pattern)
+	if !assert.NoError(t, err, msgAndArgs...) {
+		return false
+	}
+	var notInTx []RecordedStatement
+	for _, s := range matching {
+		if !s.InTx {
+			notInTx = append(notInTx, s)
+		}
+	}
+	if len(notInTx) > 0 {
+		return assert.Fail(t, fmt.Sprintf("queries not in a transaction:\n%s", formatStatements(notInTx)), msgAndArgs...)
+	}
+	return true
+}
+
+// fullScanRegexp matches the where clause of a select
+var fullScanRegexp = regexp.MustCompile(`(?i)\bwhere\b`)
+
+// AssertNoFullScan asserts that every recorded select from the table has a
+// where clause.  This is a heuristic based on the sql and it's not based on
+// the database's query plan.
+func AssertNoFullScan(t *testing.T, r *Recorder, table string, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	var scans []RecordedStatement
+	for _, s := range r.Statements() {
+		if s.Table == table && strings.HasPrefix(strings.ToLower(strings.TrimSpace(s.SQL)), "select") && !fullScanRegexp.MatchString(s.SQL) {
+			scans = append(scans, s)
+		}
+	}
+	if len(scans) > 0 {
+		return assert.Fail(t, fmt.Sprintf("full scan of %s:\n%s", table, formatStatements(scans)), msgAndArgs...)
+	}
+	return true
+}
+
+func formatStatements(statements []RecordedStatement) string {
+	var b strings.Builder
+	for i, s := range statements {
+		b.WriteString("\t")
+		b.WriteString(strings.TrimSpace(s.SQL))
+		if i < len(statements)-1 {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,76 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSnapshotSavepoint is the savepoint restored by Snapshot.Restore(...)
+const testSnapshotSavepoint = "dbw_test_snapshot"
+
+// Snapshot is a snapshot of a test database's state.  See TestSnapshot(...)
+type Snapshot struct {
+	rw *RW
+}
+
+// TestSnapshot will start a transaction with a savepoint, which is a snapshot
+// of the database's state that can be restored between subtests.  Test code
+// must use the snapshot's RW() or DB(), which are bound to the transaction,
+// so nothing leaks between subtests or into the database.  The transaction is
+// rolled back when the test completes.
+//
+// DoTx(...) with the snapshot's RW uses a savepoint rather than a new
+// transaction (which isn't retried), so code which starts its own
+// transactions can be tested, and the snapshot's RW must not be committed.  Since a snapshot
+// is a single transaction, it can't be shared by parallel subtests.
+func TestSnapshot(t *testing.T, db *DB) *Snapshot {
+	t.Helper()
+	require := require.New(t)
+	ctx := context.Background()
+	tx, err := New(db).Begin(ctx)
+	require.NoError(err)
+	t.Cleanup(func() {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			assert.NoError(t, err)
+		}
+	})
+	require.NoError(tx.underlying.wrapped.WithContext(ctx).SavePoint(testSnapshotSavepoint).Error)
+	tx.underlying.txSavepoints = true
+	return &Snapshot{rw: tx}
+}
+
+// RW returns a RW bound to the snapshot's transaction
+func (s *Snapshot) RW() *RW {
+	return s.rw
+}
+
+// DB returns a DB bound to the snapshot's transaction
+func (s *Snapshot) DB() *DB {
+	return s.rw.underlying
+}
+
+// Restore will restore the database to the state when the snapshot was
+// taken.
+func (s *Snapshot) Restore(t *testing.T) {
+	t.Helper()
+	err := s.rw.underlying.wrapped.WithContext(context.Background()).RollbackTo(testSnapshotSavepoint).Error
+	require.NoError(t, err)
+}
+
+// Run fn as a subtest with the snapshot's RW, and restore the snapshot when
+// the subtest completes.
+func (s *Snapshot) Run(t *testing.T, name string, fn func(t *testing.T, rw *RW)) bool {
+	t.Helper()
+	return t.Run(name, func(t *testing.T) {
+		t.Cleanup(func() { s.Restore(t) })
+		fn(t, s.rw)
+	})
+}
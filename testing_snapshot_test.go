@@ -0,0 +1,96 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-secure-stdlib/base62"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTestSnapshot(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	url := filepath.Join(t.TempDir(), "snapshot.db")
+	r := NewRecorder()
+	db, _ := TestSetup(t, WithTestDatabaseUrl(url), WithTestRecorder(r))
+
+	newUser := func(t *testing.T, name string) *testUser {
+		publicId, err := base62.Random(20)
+		require.NoError(t, err)
+		return &testUser{PublicId: publicId, Name: name}
+	}
+	countUsers := func(t *testing.T, rw *RW) int {
+		var users []*testUser
+		require.NoError(t, rw.SearchWhere(testCtx, &users, "", nil))
+		return len(users)
+	}
+
+	existing := newUser(t, "existing")
+	require.NoError(t, New(db).Create(testCtx, existing))
+
+	t.Run("snapshot", func(t *testing.T) {
+		s := TestSnapshot(t, db)
+		s.Run(t, "create", func(t *testing.T, rw *RW) {
+			require.NoError(t, rw.Create(testCtx, newUser(t, "created")))
+			assert.Equal(t, 2, countUsers(t, rw))
+		})
+		s.Run(t, "restored", func(t *testing.T, rw *RW) {
+			assert.Equal(t, 1, countUsers(t, rw))
+			existing := &testUser{PublicId: existing.PublicId}
+			require.NoError(t, rw.LookupBy(testCtx, existing))
+			existing.Name = "updated"
+			_, err := rw.Update(testCtx, existing, []string{"Name"}, nil)
+			require.NoError(t, err)
+		})
+		s.Run(t, "update-restored", func(t *testing.T, rw *RW) {
+			u := &testUser{PublicId: existing.PublicId}
+			require.NoError(t, rw.LookupBy(testCtx, u))
+			assert.Equal(t, "existing", u.Name)
+		})
+		s.Run(t, "do-tx", func(t *testing.T, rw *RW) {
+			assert := assert.New(t)
+			_, err := rw.DoTx(testCtx, func(error) bool { return false }, 0, ExpBackoff{}, func(_ Reader, w Writer) error {
+				if err := w.Create(testCtx, newUser(t, "rolled-back")); err != nil {
+					return err
+				}
+				return errors.New("rollback")
+			})
+			assert.Error(err)
+			assert.Equal(1, countUsers(t, rw))
+
+			r.Reset()
+			_, err = rw.DoTx(testCtx, func(error) bool { return false }, 0, ExpBackoff{}, func(_ Reader, w Writer) error {
+				return w.Create(testCtx, newUser(t, "nested"))
+			})
+			assert.NoError(err)
+			// the savepoint is released
+			released, err := r.Matching(`(?i)^release savepoint dbw_tx_\d+$`)
+			require.NoError(t, err)
+			assert.Len(released, 1)
+
+			// a savepoint isn't retried
+			attempts := 0
+			_, err = rw.DoTx(testCtx, func(error) bool { return true }, 3, ConstBackoff{}, func(Reader, Writer) error {
+				attempts++
+				return errors.New("retry")
+			})
+			assert.Error(err)
+			assert.Equal(1, attempts)
+			assert.Equal(2, countUsers(t, New(s.DB())))
+		})
+		s.Run(t, "restore", func(t *testing.T, rw *RW) {
+			require.NoError(t, rw.Create(testCtx, newUser(t, "before-restore")))
+			s.Restore(t)
+			assert.Equal(t, 1, countUsers(t, rw))
+		})
+	})
+	// nothing leaked into the database
+	assert.Equal(t, 1, countUsers(t, New(db)))
+}
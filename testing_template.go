@@ -0,0 +1,203 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+	"github.com/xo/dburl"
+	"gorm.io/gorm/logger"
+)
+
+// testTemplate is a template database created by this process
+type testTemplate struct {
+	sync.Mutex
+	ready bool
+	// db is the sqlite in-memory template, which must stay open for the
+	// life of the process.
+	db *DB
+}
+
+// testTemplates are the templates by name
+var testTemplates = struct {
+	sync.Mutex
+	m map[string]*testTemplate
+}{
+	m: map[string]*testTemplate{},
+}
+
+// testTemplateFor returns the template for the name, locked.  The caller
+// must unlock it.
+func testTemplateFor(name string) *testTemplate {
+	testTemplates.Lock()
+	tmpl, ok := testTemplates.m[name]
+	if !ok {
+		tmpl = &testTemplate{}
+		testTemplates.m[name] = tmpl
+	}
+	testTemplates.Unlock()
+	tmpl.Lock()
+	return tmpl
+}
+
+// testTemplateName returns the template database name for the options.
+func testTemplateName(t *testing.T, opts testOptions) string {
+	t.Helper()
+	key := opts.withTestTemplateKey
+	if key == "" {
+		if opts.withTestMigration != nil || opts.withTestMigrationUsingDb != nil {
+			t.Fatal("a template key is required with a test migration")
+		}
+		switch opts.withDialect {
+		case Sqlite.String():
+			key = testQueryCreateTablesSqlite
+		default:
+			key = testQueryCreateTablesPostgres
+		}
+	}
+	sum := sha256.Sum256([]byte(opts.withDialect + "\x00" + key))
+	return "go_db_tmpl_" + hex.EncodeToString(sum[:8])
+}
+
+// testPostgresTemplate will create the template database, if it doesn't
+// exist, and return its name.  The template is created using a distributed
+// lock, so it's safe for concurrent test processes.
+func testPostgresTemplate(t *testing.T, ctx context.Context, admin *DB, u *dburl.URL, opts testOptions) string {
+	t.Helper()
+	require := require.New(t)
+	name := testTemplateName(t, opts)
+	tmpl := testTemplateFor(name)
+	defer tmpl.Unlock()
+	if tmpl.ready {
+		return name
+	}
+
+	lock, err := admin.Lock(ctx, name)
+	require.NoError(err)
+	defer func() { _ = lock.Close(context.Background()) }()
+
+	rw := New(admin)
+	rows, err := rw.Query(ctx, `select 1 from pg_database where datname = ?`, []interface{}{name})
+	require.NoError(err)
+	exists := rows.Next()
+	require.NoError(rows.Close())
+	if exists {
+		tmpl.ready = true
+		return name
+	}
+
+	// migrate into a database which is renamed once it's complete, so a
+	// failed migration never leaves behind an incomplete template.
+	buildName := name + "_build"
+	_, err = rw.Exec(ctx, fmt.Sprintf(`drop database if exists "%s"`, buildName), nil)
+	require.NoError(err)
+	_, err = rw.Exec(ctx, fmt.Sprintf(`create database "%s"`, buildName), nil)
+	require.NoError(err)
+
+	buildUrl := u.URL
+	buildUrl.Path = "/" + buildName
+	buildParsed, err := dburl.Parse(buildUrl.String())
+	require.NoError(err)
+	dbUrl, _, err := dburl.GenPostgres(buildParsed)
+	require.NoError(err)
+	db, err := Open(Postgres, dbUrl)
+	require.NoError(err)
+	db.wrapped.Logger.LogMode(logger.Error)
+	testMigrate(t, ctx, db, dbUrl, opts)
+	require.NoError(db.Close(ctx))
+
+	_, err = rw.Exec(ctx, fmt.Sprintf(`alter database "%s" rename to "%s"`, buildName, name), nil)
+	require.NoError(err)
+	_, err = rw.Exec(ctx, fmt.Sprintf(`alter database "%s" is_template true`, name), nil)
+	require.NoError(err)
+	tmpl.ready = true
+	return name
+}
+
+// testSqliteFromTemplate will copy the in-memory template database, creating
+// it if needed, into the db.
+func testSqliteFromTemplate(t *testing.T, ctx context.Context, db *DB, opts testOptions) {
+	t.Helper()
+	require := require.New(t)
+	name := testTemplateName(t, opts)
+	tmpl := testTemplateFor(name)
+	defer tmpl.Unlock()
+	if !tmpl.ready {
+		tmplUrl := fmt.Sprintf("file:%s?mode=memory&cache=shared", name)
+		tmplDb, err := Open(Sqlite, tmplUrl)
+		require.NoError(err)
+		tmplDb.wrapped.Logger.LogMode(logger.Error)
+		testMigrate(t, ctx, tmplDb, tmplUrl, opts)
+		tmpl.db, tmpl.ready = tmplDb, true
+	}
+
+	src, err := tmpl.db.SqlDB(ctx)
+	require.NoError(err)
+	dst, err := db.SqlDB(ctx)
+	require.NoError(err)
+	require.NoError(sqliteBackup(ctx, dst, src))
+}
+
+// sqliteBackup copies the src database into the dst database using the sqlite
+// backup API.
+func sqliteBackup(ctx context.Context, dst, src *sql.DB) error {
+	const op = "dbw.sqliteBackup"
+	dstConn, err := dst.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer dstConn.Close()
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer srcConn.Close()
+
+	err = dstConn.Raw(func(dstDriverConn interface{}) error {
+		return srcConn.Raw(func(srcDriverConn interface{}) error {
+			d, ok := sqliteDriverConn(dstDriverConn)
+			if !ok {
+				return fmt.Errorf("destination is not a sqlite connection: %w", ErrInvalidParameter)
+			}
+			s, ok := sqliteDriverConn(srcDriverConn)
+			if !ok {
+				return fmt.Errorf("source is not a sqlite connection: %w", ErrInvalidParameter)
+			}
+			b, err := d.Backup("main", s, "main")
+			if err != nil {
+				return err
+			}
+			if _, err := b.Step(-1); err != nil {
+				_ = b.Finish()
+				return err
+			}
+			return b.Finish()
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// sqliteDriverConn returns the driver connection's sqlite connection,
+// unwrapping connections like the ones opened with credentials.
+func sqliteDriverConn(driverConn interface{}) (*sqlite3.SQLiteConn, bool) {
+	switch c := driverConn.(type) {
+	case *sqlite3.SQLiteConn:
+		return c, true
+	case interface{ sqliteConn() *sqlite3.SQLiteConn }:
+		return c.sqliteConn(), true
+	default:
+		return nil, false
+	}
+}
@@ -32,6 +32,13 @@ func Test_getTestOpts(t *testing.T) {
 		testOpts.withTestDatabaseUrl = "url"
 		assert.Equal(opts, testOpts)
 	})
+	t.Run("WithTestTemplate", func(t *testing.T) {
+		opts := getTestOpts(WithTestTemplate("v1"))
+		testOpts := getDefaultTestOptions()
+		testOpts.withTestTemplate = true
+		testOpts.withTestTemplateKey = "v1"
+		assert.Equal(opts, testOpts)
+	})
 }
 
 func Test_TestSetup(t *testing.T) {
@@ -101,6 +108,56 @@ func Test_TestSetup(t *testing.T) {
 	}
 }
 
+func Test_TestSetupWithTemplate(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	migrations := 0
+	testMigrationUsingDbFn := func(_ context.Context, db *sql.DB) error {
+		migrations++
+		var sql string
+		switch strings.ToLower(os.Getenv("DB_DIALECT")) {
+		case "postgres":
+			sql = testQueryCreateTablesPostgres
+		default:
+			sql = testQueryCreateTablesSqlite
+		}
+		_, err := db.Exec(sql)
+		return err
+	}
+	key, err := base62.Random(10)
+	require.NoError(t, err)
+
+	for _, name := range []string{"one", "two", "three", "four"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert, require := assert.New(t), require.New(t)
+			db, url := TestSetup(t, WithTestTemplate(key), WithTestMigrationUsingDB(testMigrationUsingDbFn))
+			assert.NotEmpty(url)
+			rw := New(db)
+
+			// every test gets its own database, so the unique name doesn't
+			// conflict.
+			publicId, err := base62.Random(20)
+			require.NoError(err)
+			require.NoError(rw.Create(testCtx, &testUser{PublicId: publicId, Name: "template-user"}))
+			var users []*testUser
+			require.NoError(rw.SearchWhere(testCtx, &users, "name = ?", []interface{}{"template-user"}))
+			assert.Len(users, 1)
+		})
+	}
+	t.Cleanup(func() {
+		// the migration only runs once, for the template
+		assert.LessOrEqual(t, migrations, 1)
+	})
+
+	t.Run("default-migration", func(t *testing.T) {
+		t.Parallel()
+		db, _ := TestSetup(t, WithTestTemplate(""))
+		var users []*testUser
+		assert.NoError(t, New(db).SearchWhere(testCtx, &users, "", nil))
+	})
+}
+
 func Test_TestSetupWithMock(t *testing.T) {
 	assert := assert.New(t)
 	testCtx := context.Background()
@@ -0,0 +1,144 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// timeoutFor returns the timeout of an operation, which is either its
+// WithTimeout option or the DB's default timeout.
+func (rw *RW) timeoutFor(opts Options) (time.Duration, error) {
+	const op = "dbw.timeoutFor"
+	switch {
+	case opts.WithTimeout < 0:
+		return 0, fmt.Errorf("%s: timeout must not be negative: %w", op, ErrInvalidParameter)
+	case opts.WithTimeout > 0:
+		return opts.WithTimeout, nil
+	default:
+		return rw.underlying.timeout, nil
+	}
+}
+
+// startTimeout starts an operation's timeout.  It returns a child context with
+// the timeout and the RW to use for the operation, along with a done func
+// which must be called with the operation's error once it completes.  The done
+// func releases the operation's resources and classifies the error as
+// ErrTimeout when the timeout was exceeded.
+//
+// When the dialect has a statement timeout (see StatementTimeoutDialect), it's
+// set for the operation's statements.  Within a transaction, it's set locally
+// and restored once the operation completes.  Otherwise, the operation uses a
+// dedicated connection, which has its statement timeout reset before it's
+// returned to the pool.  Nested operations, like a write's lookup, use the
+// outer operation's scope.
+//
+// Either way, setting and restoring the statement timeout costs two round
+// trips per operation.  A dedicated connection also bypasses the stmtCache of
+// WithPreparedStatements, since the pool's prepared statements can't be used
+// with a sql.Conn, so the operation's statements aren't prepared.
+func (rw *RW) startTimeout(ctx context.Context, opts Options) (*RW, context.Context, func(error) error, error) {
+	const op = "dbw.startTimeout"
+	d, err := rw.timeoutFor(opts)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if d == 0 {
+		return rw, ctx, func(err error) error { return err }, nil
+	}
+	parent := ctx
+	ctx, cancel := context.WithTimeout(parent, d)
+	dialect := rw.underlying.dialect()
+	classify := func(err error) error {
+		cancel()
+		return timeoutError(dialect, parent, ctx, err)
+	}
+	sd, ok := dialect.(StatementTimeoutDialect)
+	if !ok || rw.underlying.timeoutScope {
+		return rw, ctx, classify, nil
+	}
+
+	var release func()
+	scoped := rw.underlying.wrapped.Session(&gorm.Session{Context: ctx})
+	switch {
+	case rw.IsTx():
+		var prev, current string
+		row := scoped.Raw(sd.SetLocalStatementTimeout(d)).Row()
+		if err := row.Scan(&prev, &current); err != nil {
+			cancel()
+			return nil, nil, nil, fmt.Errorf("%s: unable to set statement timeout: %w", op, err)
+		}
+		release = func() {
+			// the transaction is aborted when the operation failed, so
+			// there's nothing to restore.
+			_ = rw.underlying.wrapped.Session(&gorm.Session{Context: context.Background()}).
+				Exec(sd.RestoreLocalStatementTimeout(), prev).Error
+		}
+	default:
+		sqlDB, err := rw.underlying.wrapped.DB()
+		if err != nil {
+			cancel()
+			return nil, nil, nil, fmt.Errorf("%s: %w", op, err)
+		}
+		conn, err := sqlDB.Conn(ctx)
+		if err != nil {
+			cancel()
+			return nil, nil, nil, fmt.Errorf("%s: %w", op, timeoutError(dialect, parent, ctx, err))
+		}
+		if _, err := conn.ExecContext(ctx, sd.SetStatementTimeout(d)); err != nil {
+			_ = conn.Close()
+			cancel()
+			return nil, nil, nil, fmt.Errorf("%s: unable to set statement timeout: %w", op, timeoutError(dialect, parent, ctx, err))
+		}
+		scoped.Statement.ConnPool = conn
+		release = func() { releaseTimeoutConn(conn, sd) }
+	}
+	scopedDB := rw.underlying.withWrapped(scoped)
+	scopedDB.timeoutScope = true
+	return New(scopedDB), ctx, func(err error) error {
+		release()
+		return classify(err)
+	}, nil
+}
+
+// releaseTimeoutConn resets the connection's statement timeout and returns it
+// to the pool.  If it can't be reset, then the connection is discarded.
+func releaseTimeoutConn(conn *sql.Conn, sd StatementTimeoutDialect) {
+	if _, err := conn.ExecContext(context.Background(), sd.ResetStatementTimeout()); err != nil {
+		_ = conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+	}
+	_ = conn.Close()
+}
+
+// statementTimeout returns the postgres statement_timeout in milliseconds for
+// the timeout, which is at least 1ms since zero disables the timeout.
+func statementTimeout(d time.Duration) string {
+	ms := d.Milliseconds()
+	if d%time.Millisecond != 0 {
+		ms++
+	}
+	return fmt.Sprintf("%d", ms)
+}
+
+// timeoutError classifies an operation's error as ErrTimeout when the
+// operation's ctx exceeded its deadline or the dialect classifies it as a
+// statement timeout.  Errors are never classified as ErrTimeout when the
+// caller's parent context is done, since that's the caller's cancellation
+// rather than the operation's timeout.
+func timeoutError(d Dialect, parent, ctx context.Context, err error) error {
+	if err == nil || parent.Err() != nil {
+		return err
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) || d.IsStatementTimeout(err) {
+		return fmt.Errorf("%w: %w", err, ErrTimeout)
+	}
+	return err
+}
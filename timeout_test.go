@@ -0,0 +1,179 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSlowQuery is a sqlite query which takes much longer than the tests'
+// timeouts.
+const testSlowQuery = "with recursive c(x) as (select 1 union all select x+1 from c where x < 1000000000) select count(*) from c"
+
+func TestRW_WithTimeout(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	conn, _ := TestSetup(t)
+	rw := New(conn)
+	testCreateUser(t, rw, "alice")
+
+	t.Run("exec", func(t *testing.T) {
+		_, err := rw.Exec(testCtx, testSlowQuery, nil, WithTimeout(50*time.Millisecond))
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrTimeout)
+	})
+	t.Run("search-where", func(t *testing.T) {
+		var users []*testUser
+		err := rw.SearchWhere(testCtx, &users, "0 < ("+testSlowQuery+")", nil, WithTimeout(50*time.Millisecond))
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrTimeout)
+	})
+	t.Run("not-exceeded", func(t *testing.T) {
+		var users []*testUser
+		err := rw.SearchWhere(testCtx, &users, "", nil, WithTimeout(time.Minute))
+		require.NoError(t, err)
+		assert.Len(t, users, 1)
+	})
+	t.Run("query", func(t *testing.T) {
+		// the rows outlive Query, so it doesn't support timeouts
+		_, err := rw.Query(testCtx, "select public_id from db_test_user", nil, WithTimeout(time.Minute))
+		assert.ErrorIs(t, err, ErrInvalidParameter)
+	})
+	t.Run("caller-canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(testCtx)
+		time.AfterFunc(50*time.Millisecond, cancel)
+		_, err := rw.Exec(ctx, testSlowQuery, nil, WithTimeout(time.Minute))
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrTimeout)
+	})
+	t.Run("caller-deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(testCtx, 50*time.Millisecond)
+		defer cancel()
+		_, err := rw.Exec(ctx, testSlowQuery, nil, WithTimeout(time.Minute))
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrTimeout)
+	})
+	t.Run("negative", func(t *testing.T) {
+		_, err := rw.Exec(testCtx, "select 1", nil, WithTimeout(-time.Second))
+		assert.ErrorIs(t, err, ErrInvalidParameter)
+		_, err = rw.Query(testCtx, "select 1", nil, WithTimeout(-time.Second))
+		assert.ErrorIs(t, err, ErrInvalidParameter)
+	})
+}
+
+func TestOpen_WithTimeout(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	url := filepath.Join(t.TempDir(), "timeout.db")
+	db, err := Open(Sqlite, url, WithTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+	defer db.Close(testCtx)
+	rw := New(db)
+
+	_, err = rw.Exec(testCtx, testSlowQuery, nil)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrTimeout)
+
+	// an operation's timeout overrides the default
+	ctx, cancel := context.WithTimeout(testCtx, 200*time.Millisecond)
+	defer cancel()
+	_, err = rw.Exec(ctx, testSlowQuery, nil, WithTimeout(time.Minute))
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrTimeout)
+
+	_, err = Open(Sqlite, url, WithTimeout(-time.Second))
+	assert.ErrorIs(t, err, ErrInvalidParameter)
+}
+
+func Test_timeoutError(t *testing.T) {
+	t.Parallel()
+	expired, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	statementTimeout := &pgconn.PgError{Code: "57014", Message: "canceling statement due to statement timeout"}
+	tests := []struct {
+		name        string
+		parent      context.Context
+		ctx         context.Context
+		err         error
+		wantTimeout bool
+	}{
+		{
+			name:   "nil",
+			parent: context.Background(),
+			ctx:    expired,
+		},
+		{
+			name:        "deadline-exceeded",
+			parent:      context.Background(),
+			ctx:         expired,
+			err:         context.DeadlineExceeded,
+			wantTimeout: true,
+		},
+		{
+			name:        "statement-timeout",
+			parent:      context.Background(),
+			ctx:         context.Background(),
+			err:         statementTimeout,
+			wantTimeout: true,
+		},
+		{
+			name:   "user-canceled-statement",
+			parent: context.Background(),
+			ctx:    context.Background(),
+			err:    &pgconn.PgError{Code: "57014", Message: "canceling statement due to user request"},
+		},
+		{
+			name:   "caller-canceled",
+			parent: canceled,
+			ctx:    expired,
+			err:    context.Canceled,
+		},
+		{
+			name:   "caller-deadline",
+			parent: expired,
+			ctx:    expired,
+			err:    statementTimeout,
+		},
+		{
+			name:   "other-error",
+			parent: context.Background(),
+			ctx:    context.Background(),
+			err:    errors.New("other"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			err := timeoutError(postgresDialect{}, tt.parent, tt.ctx, tt.err)
+			if tt.err == nil {
+				assert.NoError(err)
+				return
+			}
+			assert.ErrorIs(err, tt.err)
+			if tt.wantTimeout {
+				assert.ErrorIs(err, ErrTimeout)
+			} else {
+				assert.NotErrorIs(err, ErrTimeout)
+			}
+		})
+	}
+}
+
+func Test_statementTimeout(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	assert.Equal("1500", statementTimeout(1500*time.Millisecond))
+	assert.Equal("2", statementTimeout(1500*time.Microsecond))
+	assert.Equal("1", statementTimeout(time.Nanosecond))
+}
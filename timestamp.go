@@ -0,0 +1,159 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// TimestampTag is the struct tag used to identify managed timestamp
+	// fields.  For example:
+	//
+	//	CreateTime *Timestamp `dbw:"create_timestamp"`
+	//	UpdateTime *Timestamp `dbw:"update_timestamp"`
+	TimestampTag = "dbw"
+
+	// CreateTimestampTagValue is the TimestampTag value for a managed create
+	// timestamp field.
+	CreateTimestampTagValue = "create_timestamp"
+
+	// UpdateTimestampTagValue is the TimestampTag value for a managed update
+	// timestamp field.
+	UpdateTimestampTagValue = "update_timestamp"
+)
+
+var (
+	createTimestampFields atomic.Value
+	updateTimestampFields atomic.Value
+)
+
+// InitCreateTimestampFields sets the names of fields which are managed create
+// timestamps. A managed create timestamp is set to the current time by
+// RW.Create(...) and RW.CreateItems(...).  Fields can also be identified as
+// managed create timestamps using a struct tag of `dbw:"create_timestamp"`
+func InitCreateTimestampFields(fields []string) {
+	storeFieldSet(&createTimestampFields, fields)
+}
+
+// CreateTimestampFields returns the current set of fields which are managed
+// create timestamps.
+func CreateTimestampFields() []string {
+	return loadFieldSet(&createTimestampFields)
+}
+
+// InitUpdateTimestampFields sets the names of fields which are managed update
+// timestamps. A managed update timestamp is set to the current time by
+// RW.Create(...), RW.CreateItems(...), RW.Update(...) and on conflict
+// updates.  Fields can also be identified as managed update timestamps using
+// a struct tag of `dbw:"update_timestamp"`
+func InitUpdateTimestampFields(fields []string) {
+	storeFieldSet(&updateTimestampFields, fields)
+}
+
+// UpdateTimestampFields returns the current set of fields which are managed
+// update timestamps.
+func UpdateTimestampFields() []string {
+	return loadFieldSet(&updateTimestampFields)
+}
+
+func storeFieldSet(v *atomic.Value, fields []string) {
+	m := make(map[string]struct{}, len(fields))
+	for _, f := range fields {
+		m[f] = struct{}{}
+	}
+	v.Store(m)
+}
+
+func loadFieldSet(v *atomic.Value) []string {
+	m := v.Load()
+	if m == nil {
+		return []string{}
+	}
+	fields := make([]string, 0, len(m.(map[string]struct{})))
+	for f := range m.(map[string]struct{}) {
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// timestamps are the managed timestamp fields of a resource's schema
+type timestamps struct {
+	create []*schema.Field
+	update []*schema.Field
+}
+
+// timestampsOf returns the managed timestamp fields for the schema.
+func timestampsOf(s *schema.Schema) timestamps {
+	var ts timestamps
+	createNames, updateNames := CreateTimestampFields(), UpdateTimestampFields()
+	for _, f := range s.Fields {
+		if f.DBName == "" {
+			continue
+		}
+		tagValues := strings.Split(f.Tag.Get(TimestampTag), ",")
+		switch {
+		case contains(createNames, f.Name) || contains(tagValues, CreateTimestampTagValue):
+			ts.create = append(ts.create, f)
+		case contains(updateNames, f.Name) || contains(tagValues, UpdateTimestampTagValue):
+			ts.update = append(ts.update, f)
+		}
+	}
+	return ts
+}
+
+// timestampsFor parses the resource's schema and returns its managed
+// timestamp fields.
+func (rw *RW) timestampsFor(i interface{}) (timestamps, error) {
+	const op = "dbw.timestampsFor"
+	mDb := rw.underlying.wrapped.Model(i)
+	if err := mDb.Statement.Parse(i); err != nil || mDb.Statement.Schema == nil {
+		return timestamps{}, fmt.Errorf("%s: (internal error) unable to parse stmt: %w", op, ErrUnknown)
+	}
+	return timestampsOf(mDb.Statement.Schema), nil
+}
+
+// setCreate sets both the create and update timestamps of the resource to now
+func (ts timestamps) setCreate(ctx context.Context, i interface{}, now time.Time) error {
+	const op = "dbw.(timestamps).setCreate"
+	for _, f := range append(ts.create, ts.update...) {
+		if err := f.Set(ctx, reflect.ValueOf(i), now); err != nil {
+			return fmt.Errorf("%s: unable to set %s: %w", op, f.Name, err)
+		}
+	}
+	return nil
+}
+
+// onConflict adds assignments of now for the update timestamps to the on
+// conflict clause, unless the clause already assigns them.
+func (ts timestamps) onConflict(c *clause.OnConflict, now time.Time) {
+	if c.DoNothing {
+		return
+	}
+	for _, f := range ts.update {
+		assigned := false
+		for _, a := range c.DoUpdates {
+			if strings.EqualFold(a.Column.Name, f.DBName) {
+				assigned = true
+				break
+			}
+		}
+		if c.UpdateAll && (!f.HasDefaultValue || f.DefaultValueInterface != nil || strings.EqualFold(f.DefaultValue, "NULL")) {
+			// gorm will assign the excluded value for the field, which was
+			// set to now before the insert.
+			assigned = true
+		}
+		if !assigned {
+			c.DoUpdates = append(c.DoUpdates, clause.Assignment{Column: clause.Column{Name: f.DBName}, Value: now})
+		}
+	}
+}
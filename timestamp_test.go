@@ -0,0 +1,178 @@
+// Copyright IBM Corp. 2023, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package dbw_test
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-dbw"
+	"github.com/hashicorp/go-dbw/internal/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testTagged struct {
+	PublicId   string    `gorm:"primaryKey"`
+	Name       string    `gorm:"default:null"`
+	CreateTime time.Time `dbw:"create_timestamp"`
+	UpdateTime time.Time `dbw:"update_timestamp"`
+}
+
+func (*testTagged) TableName() string { return "db_test_tagged" }
+
+type testRegistered struct {
+	PublicId string `gorm:"primaryKey"`
+	Name     string `gorm:"default:null"`
+	Created  *dbtest.Timestamp
+	Updated  *dbtest.Timestamp
+}
+
+func (*testRegistered) TableName() string { return "db_test_registered" }
+
+// testClock is a clock which can be injected via dbw.WithNowFunc
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// testTimestampDB returns a db with the clock injected and tables without
+// timestamp triggers
+func testTimestampDB(t *testing.T, clock *testClock) *dbw.DB {
+	t.Helper()
+	require := require.New(t)
+	conn, url := dbw.TestSetup(t, dbw.WithTestDatabaseUrl(filepath.Join(t.TempDir(), "timestamp.db")))
+	typ, _, err := conn.DbType()
+	require.NoError(err)
+	conn, err = dbw.Open(typ, url, dbw.WithNowFunc(clock.Now))
+	require.NoError(err)
+	t.Cleanup(func() { _ = conn.Close(context.Background()) })
+
+	tsType := "timestamp"
+	if typ == dbw.Postgres {
+		tsType = "timestamp with time zone"
+	}
+	rw := dbw.New(conn)
+	for _, table := range []string{
+		"create table db_test_tagged (public_id text primary key, name text unique, create_time " + tsType + ", update_time " + tsType + ")",
+		"create table db_test_registered (public_id text primary key, name text, created " + tsType + ", updated " + tsType + ")",
+	} {
+		_, err := rw.Exec(context.Background(), table, nil)
+		require.NoError(err)
+	}
+	return conn
+}
+
+func TestRW_Timestamps(t *testing.T) {
+	testCtx := context.Background()
+	est := time.FixedZone("EST", -5*60*60)
+	clock := &testClock{now: time.Date(2023, 1, 2, 3, 4, 5, 0, est)}
+	conn := testTimestampDB(t, clock)
+	rw := dbw.New(conn)
+
+	t.Run("tagged", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		created := clock.Now()
+		r := &testTagged{PublicId: "tagged", Name: "tagged"}
+		require.NoError(rw.Create(testCtx, r))
+		assert.True(created.Equal(r.CreateTime))
+		assert.True(created.Equal(r.UpdateTime))
+		assert.Equal(time.UTC, r.CreateTime.Location())
+
+		clock.Add(time.Hour)
+		r.Name = "tagged-updated"
+		rowsUpdated, err := rw.Update(testCtx, r, []string{"Name"}, nil)
+		require.NoError(err)
+		assert.Equal(1, rowsUpdated)
+
+		found := &testTagged{PublicId: r.PublicId}
+		require.NoError(rw.LookupBy(testCtx, found))
+		assert.True(created.Equal(found.CreateTime))
+		assert.True(clock.Now().Equal(found.UpdateTime))
+	})
+	t.Run("registered", func(t *testing.T) {
+		dbw.InitCreateTimestampFields([]string{"Created"})
+		dbw.InitUpdateTimestampFields([]string{"Updated"})
+		t.Cleanup(func() {
+			dbw.InitCreateTimestampFields(nil)
+			dbw.InitUpdateTimestampFields(nil)
+		})
+		assert, require := assert.New(t), require.New(t)
+		assert.Equal([]string{"Created"}, dbw.CreateTimestampFields())
+		assert.Equal([]string{"Updated"}, dbw.UpdateTimestampFields())
+
+		created := clock.Now()
+		r := &testRegistered{PublicId: "registered", Name: "registered"}
+		require.NoError(rw.Create(testCtx, r))
+		require.NotNil(r.Created)
+		assert.True(created.Equal(r.Created.AsTime()))
+
+		clock.Add(time.Hour)
+		r.Name = "registered-updated"
+		_, err := rw.Update(testCtx, r, []string{"Name"}, nil)
+		require.NoError(err)
+
+		found := &testRegistered{PublicId: r.PublicId}
+		require.NoError(rw.LookupBy(testCtx, found))
+		assert.True(created.Equal(found.Created.AsTime()))
+		assert.True(clock.Now().Equal(found.Updated.AsTime()))
+	})
+	t.Run("create-items", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		items := []*testTagged{
+			{PublicId: "item-1", Name: "item-1"},
+			{PublicId: "item-2", Name: "item-2"},
+		}
+		require.NoError(rw.CreateItems(testCtx, items))
+		for _, item := range items {
+			found := &testTagged{PublicId: item.PublicId}
+			require.NoError(rw.LookupBy(testCtx, found))
+			assert.True(clock.Now().Equal(found.CreateTime))
+			assert.True(clock.Now().Equal(found.UpdateTime))
+		}
+	})
+	t.Run("on-conflict", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		created := clock.Now()
+		require.NoError(rw.Create(testCtx, &testTagged{PublicId: "conflict", Name: "conflict"}))
+
+		clock.Add(time.Hour)
+		onConflict := dbw.OnConflict{
+			Target: dbw.Columns{"public_id"},
+			Action: dbw.SetColumns([]string{"name"}),
+		}
+		require.NoError(rw.Create(testCtx, &testTagged{PublicId: "conflict", Name: "conflict-updated"}, dbw.WithOnConflict(&onConflict)))
+
+		found := &testTagged{PublicId: "conflict"}
+		require.NoError(rw.LookupBy(testCtx, found))
+		assert.Equal("conflict-updated", found.Name)
+		assert.True(created.Equal(found.CreateTime))
+		assert.True(clock.Now().Equal(found.UpdateTime))
+	})
+	t.Run("in-tx", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		_, err := rw.DoTx(testCtx, func(error) bool { return false }, 0, dbw.ConstBackoff{}, func(_ dbw.Reader, w dbw.Writer) error {
+			return w.Create(testCtx, &testTagged{PublicId: "in-tx", Name: "in-tx"})
+		})
+		require.NoError(err)
+		found := &testTagged{PublicId: "in-tx"}
+		require.NoError(rw.LookupBy(testCtx, found))
+		assert.True(clock.Now().Equal(found.CreateTime))
+	})
+}
@@ -17,7 +17,7 @@ func (rw *RW) Begin(ctx context.Context) (*RW, error) {
 		return nil, fmt.Errorf("%s: %w", op, newTx.Error)
 	}
 	return New(
-		&DB{wrapped: newTx},
+		rw.underlying.withTx(newTx),
 	), nil
 }
 
@@ -38,5 +38,6 @@ func (rw *RW) Commit(ctx context.Context) error {
 	if err := db.Commit().Error; err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
+	rw.commitLookupCache(ctx)
 	return nil
 }
@@ -7,9 +7,11 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"strings"
 	"sync/atomic"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var nonUpdateFields atomic.Value
@@ -49,15 +51,28 @@ func NonUpdatableFields() []string {
 // returned the caller must decide what to do with the transaction, which almost
 // always should be to rollback.  Update returns the number of rows updated.
 //
+// If the resource has a version column, then Update will increment it (version
+// = version + 1) unless the version is explicitly included in fieldMaskPaths
+// or setToNullPaths, so a database trigger isn't required to maintain the
+// version. Similarly, managed update timestamp fields are set to the current
+// time (see InitUpdateTimestampFields).
+//
 // Supported options: WithBeforeWrite, WithAfterWrite, WithWhere, WithDebug,
-// WithTable and WithVersion. If WithVersion is used, then the update will
-// include the version number in the update where clause, which basically makes
-// the update use optimistic locking and the update will only succeed if the
-// existing rows version matches the WithVersion option. Zero is not a valid
-// value for the WithVersion option and will return an error. WithWhere allows
-// specifying an additional constraint on the operation in addition to the PKs.
+// WithTable, WithVersion, WithReturning and WithTimeout. If WithVersion is
+// used, then the update will include the version number in the update where
+// clause, which basically makes the update use optimistic locking and the
+// update will only succeed if the existing rows version matches the
+// WithVersion option.
+// ErrStaleVersion is returned when the existing row's version doesn't match.
+// Zero is not a valid value for the WithVersion option and will return an
+// error. WithWhere allows specifying an additional constraint on the operation
+// in addition to the PKs.
 // WithDebug will turn on debugging for the update call.
-func (rw *RW) Update(ctx context.Context, i interface{}, fieldMaskPaths []string, setToNullPaths []string, opt ...Option) (int, error) {
+//
+// The resource is refreshed from the db after the update with a lookup, unless
+// WithReturning is used, which scans the columns returned by the update into
+// the resource instead (a lookup is still used when no rows were updated).
+func (rw *RW) Update(ctx context.Context, i interface{}, fieldMaskPaths []string, setToNullPaths []string, opt ...Option) (_ int, retErr error) {
 	const op = "dbw.Update"
 	if rw.underlying == nil {
 		return noRowsAffected, fmt.Errorf("%s: missing underlying db: %w", op, ErrInvalidParameter)
@@ -111,6 +126,18 @@ func (rw *RW) Update(ctx context.Context, i interface{}, fieldMaskPaths []string
 		}
 	}
 
+	versionField := mDb.Statement.Schema.LookUpField("version")
+	if versionField != nil && !contains(fieldMaskPaths, versionField.Name) && !contains(setToNullPaths, versionField.Name) {
+		updateFields[versionField.DBName] = gorm.Expr(versionField.DBName + " + 1")
+	}
+	now := rw.underlying.now()
+	for _, f := range timestampsOf(mDb.Statement.Schema).update {
+		if contains(fieldMaskPaths, f.Name) || contains(setToNullPaths, f.Name) {
+			continue
+		}
+		updateFields[f.DBName] = now
+	}
+
 	if !opts.WithSkipVetForWrite {
 		if vetter, ok := i.(VetForWriter); ok {
 			if err := vetter.VetForWrite(ctx, rw, UpdateOp, WithFieldMaskPaths(fieldMaskPaths), WithNullPaths(setToNullPaths)); err != nil {
@@ -123,13 +150,21 @@ func (rw *RW) Update(ctx context.Context, i interface{}, fieldMaskPaths []string
 			return noRowsAffected, fmt.Errorf("%s: error before write: %w", op, err)
 		}
 	}
-	underlying := rw.underlying.wrapped.Model(i)
+	rw, ctx, done, err := rw.startTimeout(ctx, opts)
+	if err != nil {
+		return noRowsAffected, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { retErr = done(retErr) }()
+	underlying := rw.underlying.wrapped.WithContext(ctx).Model(i)
 	if opts.WithDebug {
 		underlying = underlying.Debug()
 	}
 	if opts.WithTable != "" {
 		underlying = underlying.Table(opts.WithTable)
 	}
+	if underlying, err = rw.withReturning(underlying, i, opts); err != nil {
+		return noRowsAffected, fmt.Errorf("%s: %w", op, err)
+	}
 	switch {
 	case opts.WithVersion != nil || opts.WithWhereClause != "":
 		where, args, err := rw.whereClausesFromOpts(ctx, i, opts)
@@ -147,20 +182,47 @@ func (rw *RW) Update(ctx context.Context, i interface{}, fieldMaskPaths []string
 		return noRowsAffected, fmt.Errorf("%s: %w", op, underlying.Error)
 	}
 	rowsUpdated := int(underlying.RowsAffected)
+	if rowsUpdated > 0 {
+		rw.invalidateLookupCache(ctx, opts.WithTable, i)
+	}
 	if rowsUpdated > 0 && (opts.WithAfterWrite != nil) {
 		if err := opts.WithAfterWrite(i, rowsUpdated); err != nil {
 			return rowsUpdated, fmt.Errorf("%s: error after write: %w", op, err)
 		}
 	}
-	// we need to force a lookupAfterWrite so the resource returned is correctly initialized
-	// from the db
-	opt = append(opt, WithLookup(true))
-	if err := rw.lookupAfterWrite(ctx, i, opt...); err != nil {
-		return noRowsAffected, fmt.Errorf("%s: %w", op, err)
+	// we need to force a lookupAfterWrite so the resource returned is correctly
+	// initialized from the db, unless the updated row was already returned.
+	if opts.WithReturning == nil || rowsUpdated == 0 {
+		opt = append(opt, WithLookup(true))
+		if err := rw.lookupAfterWrite(ctx, i, opt...); err != nil {
+			return noRowsAffected, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	if rowsUpdated == 0 && opts.WithVersion != nil && versionField != nil {
+		// the resource was refreshed by the lookup, so we can tell if the
+		// update missed because of its version rather than another constraint
+		// from WithWhere.
+		current, _ := versionField.ValueOf(ctx, reflect.ValueOf(i))
+		if !versionEquals(current, *opts.WithVersion) {
+			return noRowsAffected, fmt.Errorf("%s: version %d: %w", op, *opts.WithVersion, ErrStaleVersion)
+		}
 	}
 	return rowsUpdated, nil
 }
 
+// versionEquals compares a resource's version field value with a version
+func versionEquals(fieldValue interface{}, version uint32) bool {
+	v := reflect.Indirect(reflect.ValueOf(fieldValue))
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == int64(version)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == uint64(version)
+	default:
+		return false
+	}
+}
+
 // filterPaths will filter out non-updatable fields
 func filterPaths(paths []string) []string {
 	if len(paths) == 0 {
@@ -181,3 +243,161 @@ func filterPaths(paths []string) []string {
 	}
 	return filtered
 }
+
+// UpdateWhere updates the columns of every row of the model's table which
+// matches the where clause, and returns the number of rows updated.  The model
+// is only used for its schema and table, so its field values are ignored.
+// The values must be columns of the model, which may be set to an Expr(...)
+// or a Column{...} of the table.  Columns of NonUpdatableFields are filtered
+// out, and primary key columns can't be updated.
+//
+// The same as Update(...), the version is incremented and managed update
+// timestamp fields are set to the current time, unless they're included in
+// the values.
+//
+// An empty where clause is refused, unless the WithAllRows option is used.
+// When the model is cached by a LookupCache, the primary keys of the matching
+// rows are read before the update, so their cached resources can be
+// invalidated.  Rows which start matching the where clause between that read
+// and the update, because of a concurrent write, aren't invalidated, so their
+// cached resources are stale until their TTL expires.  Supported options: WithAllRows, WithBeforeWrite,
+// WithAfterWrite, WithDebug, WithTable and WithTimeout.  WithBeforeWrite and
+// WithAfterWrite are called with the model.
+func (rw *RW) UpdateWhere(ctx context.Context, model interface{}, values []ColumnValue, where string, args []interface{}, opt ...Option) (_ int, retErr error) {
+	const op = "dbw.UpdateWhere"
+	switch {
+	case rw.underlying == nil:
+		return noRowsAffected, fmt.Errorf("%s: missing underlying db: %w", op, ErrInvalidParameter)
+	case isNil(model):
+		return noRowsAffected, fmt.Errorf("%s: missing model: %w", op, ErrInvalidParameter)
+	case len(values) == 0:
+		return noRowsAffected, fmt.Errorf("%s: missing column values: %w", op, ErrInvalidParameter)
+	}
+	if err := raiseErrorOnHooks(model); err != nil {
+		return noRowsAffected, fmt.Errorf("%s: %w", op, err)
+	}
+	opts := GetOpts(opt...)
+	if err := validateWriteWhere(where, opts); err != nil {
+		return noRowsAffected, fmt.Errorf("%s: %w", op, err)
+	}
+
+	mDb := rw.underlying.wrapped.Model(model)
+	err := mDb.Statement.Parse(model)
+	if err != nil || mDb.Statement.Schema == nil {
+		return noRowsAffected, fmt.Errorf("%s: internal error: unable to parse stmt: %w", op, err)
+	}
+	s := mDb.Statement.Schema
+	updateFields := make(map[string]interface{}, len(values))
+	for _, cv := range values {
+		f := s.LookUpField(cv.Column)
+		switch {
+		case f == nil || f.DBName == "":
+			return noRowsAffected, fmt.Errorf("%s: %s is not a column of %s: %w", op, cv.Column, s.Table, ErrInvalidParameter)
+		case f.PrimaryKey:
+			return noRowsAffected, fmt.Errorf("%s: not allowed on primary key column %s: %w", op, f.DBName, ErrInvalidParameter)
+		case len(filterPaths([]string{f.Name})) == 0:
+			// we need to filter out some non-updatable fields (like: CreateTime, etc)
+			continue
+		}
+		switch v := cv.Value.(type) {
+		case ExprValue:
+			updateFields[f.DBName] = gorm.Expr(v.Sql, v.Vars...)
+		case Column:
+			if v.Table == excludedTable {
+				return noRowsAffected, fmt.Errorf("%s: column %s references an upsert's excluded row: %w", op, f.DBName, ErrInvalidParameter)
+			}
+			updateFields[f.DBName] = clause.Column{Table: v.Table, Name: v.Name}
+		default:
+			updateFields[f.DBName] = v
+		}
+	}
+	if len(updateFields) == 0 {
+		return noRowsAffected, fmt.Errorf("%s: after filtering non-updated fields, there are no column values left: %w", op, ErrInvalidParameter)
+	}
+	if versionField := s.LookUpField("version"); versionField != nil {
+		if _, ok := updateFields[versionField.DBName]; !ok {
+			updateFields[versionField.DBName] = gorm.Expr(versionField.DBName + " + 1")
+		}
+	}
+	now := rw.underlying.now()
+	for _, f := range timestampsOf(s).update {
+		if _, ok := updateFields[f.DBName]; !ok {
+			updateFields[f.DBName] = now
+		}
+	}
+
+	if opts.WithBeforeWrite != nil {
+		if err := opts.WithBeforeWrite(model); err != nil {
+			return noRowsAffected, fmt.Errorf("%s: error before write: %w", op, err)
+		}
+	}
+	rw, ctx, done, err := rw.startTimeout(ctx, opts)
+	if err != nil {
+		return noRowsAffected, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { retErr = done(retErr) }()
+	table := tableOf(model, opts)
+	cached, err := rw.cachedResourcesWhere(ctx, model, table, where, args)
+	if err != nil {
+		return noRowsAffected, fmt.Errorf("%s: %w", op, err)
+	}
+	db := rw.underlying.wrapped.WithContext(ctx)
+	if opts.WithAllRows {
+		db = db.Session(&gorm.Session{AllowGlobalUpdate: true})
+	}
+	// a new model is used, so the model's primary key isn't added to the
+	// where clause
+	db = db.Model(reflect.New(reflect.Indirect(reflect.ValueOf(model)).Type()).Interface())
+	if opts.WithDebug {
+		db = db.Debug()
+	}
+	if table != "" {
+		db = db.Table(table)
+	}
+	if where != "" {
+		db = db.Where(where, args...)
+	}
+	db = db.Updates(updateFields)
+	if db.Error != nil {
+		return noRowsAffected, fmt.Errorf("%s: %w", op, db.Error)
+	}
+	rowsUpdated := int(db.RowsAffected)
+	if rowsUpdated > 0 {
+		rw.invalidateLookupCache(ctx, opts.WithTable, cached...)
+	}
+	if rowsUpdated > 0 && opts.WithAfterWrite != nil {
+		if err := opts.WithAfterWrite(model, rowsUpdated); err != nil {
+			return rowsUpdated, fmt.Errorf("%s: error after write: %w", op, err)
+		}
+	}
+	return rowsUpdated, nil
+}
+
+// validateWriteWhere validates the where clause and options of
+// UpdateWhere(...) and DeleteWhere(...)
+func validateWriteWhere(where string, opts Options) error {
+	const op = "dbw.validateWriteWhere"
+	switch {
+	case strings.TrimSpace(where) == "" && !opts.WithAllRows:
+		return fmt.Errorf("%s: missing where clause (see WithAllRows): %w", op, ErrInvalidParameter)
+	case opts.WithWhereClause != "":
+		return fmt.Errorf("%s: with where is not a supported option: %w", op, ErrInvalidParameter)
+	case opts.WithVersion != nil:
+		return fmt.Errorf("%s: with version is not a supported option: %w", op, ErrInvalidParameter)
+	case opts.WithLookup:
+		return fmt.Errorf("%s: with lookup is not a supported option: %w", op, ErrInvalidParameter)
+	}
+	return nil
+}
+
+// tableOf returns the table of the model for a write, which is the WithTable
+// option or the model's TableName()
+func tableOf(model interface{}, opts Options) string {
+	if opts.WithTable != "" {
+		return opts.WithTable
+	}
+	if tabler, ok := model.(tableNamer); ok {
+		return tabler.TableName()
+	}
+	return ""
+}
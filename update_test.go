@@ -48,6 +48,92 @@ func TestDb_UpdateUnsetField(t *testing.T) {
 	assert.Equal("updated", found.Name)
 }
 
+func TestDb_UpdateVersion(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	conn, _ := dbw.TestSetup(t)
+	rw := dbw.New(conn)
+
+	// drop the version trigger, so the version is only maintained by Update
+	dropTrigger := "drop trigger update_version_column_db_test_user"
+	switch typ, _, _ := conn.DbType(); typ {
+	case dbw.Postgres:
+		dropTrigger = "drop trigger update_version_column on db_test_user"
+	case dbw.MySQL, dbw.SqlServer:
+		// the version is maintained by the same trigger as the immutable
+		// columns, so it can't be dropped on its own
+		t.Skipf("the version trigger can't be dropped for %s", typ)
+	}
+	_, err := rw.Exec(testCtx, dropTrigger, nil)
+	require.NoError(t, err)
+
+	t.Run("increment", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		u := testUser(t, rw, "increment", "", "")
+		require.Equal(uint32(1), u.Version)
+
+		u.Name = "increment-updated"
+		rowsUpdated, err := rw.Update(testCtx, u, []string{"Name"}, nil)
+		require.NoError(err)
+		assert.Equal(1, rowsUpdated)
+		assert.Equal(uint32(2), u.Version)
+
+		u.Name = "increment-updated-again"
+		version := u.Version
+		rowsUpdated, err = rw.Update(testCtx, u, []string{"Name"}, nil, dbw.WithVersion(&version))
+		require.NoError(err)
+		assert.Equal(1, rowsUpdated)
+		assert.Equal(uint32(3), u.Version)
+	})
+	t.Run("stale-version", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		u := testUser(t, rw, "stale-version", "", "")
+		staleVersion := u.Version
+
+		u.Name = "stale-version-updated"
+		_, err := rw.Update(testCtx, u, []string{"Name"}, nil, dbw.WithVersion(&staleVersion))
+		require.NoError(err)
+
+		u.Name = "stale-version-updated-again"
+		rowsUpdated, err := rw.Update(testCtx, u, []string{"Name"}, nil, dbw.WithVersion(&staleVersion))
+		require.Error(err)
+		assert.ErrorIs(err, dbw.ErrStaleVersion)
+		assert.Equal(0, rowsUpdated)
+	})
+	t.Run("where-miss-is-not-stale", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		u := testUser(t, rw, "where-miss", "", "")
+		version := u.Version
+
+		u.Name = "where-miss-updated"
+		rowsUpdated, err := rw.Update(testCtx, u, []string{"Name"}, nil, dbw.WithVersion(&version), dbw.WithWhere("1 = 2"))
+		require.NoError(err)
+		assert.Equal(0, rowsUpdated)
+	})
+	t.Run("not-found", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		u := testUser(t, rw, "not-found", "", "")
+		version := u.Version
+		_, err := rw.Delete(testCtx, u)
+		require.NoError(err)
+
+		u.Name = "not-found-updated"
+		_, err = rw.Update(testCtx, u, []string{"Name"}, nil, dbw.WithVersion(&version))
+		require.Error(err)
+		assert.ErrorIs(err, dbw.ErrRecordNotFound)
+	})
+	t.Run("explicit-version", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		u := testUser(t, rw, "explicit-version", "", "")
+
+		u.Version = 10
+		rowsUpdated, err := rw.Update(testCtx, u, []string{"Version"}, nil)
+		require.NoError(err)
+		assert.Equal(1, rowsUpdated)
+		assert.Equal(uint32(10), u.Version)
+	})
+}
+
 func TestDb_Update(t *testing.T) {
 	conn, _ := dbw.TestSetup(t)
 	now := &dbtest.Timestamp{Timestamp: timestamppb.Now()}
@@ -128,8 +214,8 @@ func TestDb_Update(t *testing.T) {
 				opt:            []dbw.Option{dbw.WithVersion(&badVersion)},
 			},
 			want:       0,
-			wantErr:    false,
-			wantErrMsg: "",
+			wantErr:    true,
+			wantErrMsg: "dbw.Update: version 22: stale version",
 		},
 		{
 			name: "simple-with-zero-version",
@@ -615,3 +701,165 @@ func TestDb_Update(t *testing.T) {
 		}
 	})
 }
+
+func TestDb_UpdateWhere(t *testing.T) {
+	t.Parallel()
+	testCtx := context.Background()
+	conn, _ := dbw.TestSetup(t)
+	rw := dbw.New(conn)
+
+	lookup := func(t *testing.T, publicId string) *dbtest.TestUser {
+		t.Helper()
+		u := dbtest.AllocTestUser()
+		u.PublicId = publicId
+		require.NoError(t, rw.LookupByPublicId(testCtx, &u))
+		return &u
+	}
+
+	t.Run("where", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		u1 := testUser(t, rw, "update-where-1", "update-where@example.com", "")
+		u2 := testUser(t, rw, "update-where-2", "update-where@example.com", "")
+		other := testUser(t, rw, "update-where-3", "other@example.com", "")
+
+		rowsUpdated, err := rw.UpdateWhere(testCtx, &dbtest.TestUser{StoreTestUser: &dbtest.StoreTestUser{}},
+			dbw.SetColumnValues(map[string]interface{}{"phone_number": "555-1212"}),
+			"email = ?", []interface{}{"update-where@example.com"})
+		require.NoError(err)
+		assert.Equal(2, rowsUpdated)
+		for _, u := range []*dbtest.TestUser{u1, u2} {
+			found := lookup(t, u.PublicId)
+			assert.Equal("555-1212", found.PhoneNumber)
+			assert.Equal(u.Version+1, found.Version)
+		}
+		assert.Empty(lookup(t, other.PublicId).PhoneNumber)
+	})
+	t.Run("expr-and-column", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		u := testUser(t, rw, "update-where-expr", "update-where-expr@example.com", "555-1212")
+		rowsUpdated, err := rw.UpdateWhere(testCtx, &dbtest.TestUser{StoreTestUser: &dbtest.StoreTestUser{}},
+			dbw.SetColumnValues(map[string]interface{}{
+				"PhoneNumber": dbw.Expr("NULL"),
+				"email":       dbw.Column{Name: "name"},
+			}),
+			"public_id = ?", []interface{}{u.PublicId})
+		require.NoError(err)
+		assert.Equal(1, rowsUpdated)
+		found := lookup(t, u.PublicId)
+		assert.Empty(found.PhoneNumber)
+		assert.Equal("update-where-expr", found.Email)
+	})
+	t.Run("non-updatable", func(t *testing.T) {
+		assert := assert.New(t)
+		_, err := rw.UpdateWhere(testCtx, &dbtest.TestUser{StoreTestUser: &dbtest.StoreTestUser{}},
+			dbw.SetColumnValues(map[string]interface{}{"create_time": dbw.Expr("NULL")}),
+			"name = ?", []interface{}{"alice"})
+		assert.ErrorIs(err, dbw.ErrInvalidParameter)
+	})
+	t.Run("all-rows", func(t *testing.T) {
+		assert, require := assert.New(t), require.New(t)
+		conn, _ := dbw.TestSetup(t)
+		rw := dbw.New(conn)
+		testUser(t, rw, "all-rows-1", "", "")
+		testUser(t, rw, "all-rows-2", "", "")
+		values := dbw.SetColumnValues(map[string]interface{}{"email": "all@example.com"})
+
+		_, err := rw.UpdateWhere(testCtx, &dbtest.TestUser{StoreTestUser: &dbtest.StoreTestUser{}}, values, "", nil)
+		assert.ErrorIs(err, dbw.ErrInvalidParameter)
+
+		rowsUpdated, err := rw.UpdateWhere(testCtx, &dbtest.TestUser{StoreTestUser: &dbtest.StoreTestUser{}}, values, "", nil, dbw.WithAllRows(true))
+		require.NoError(err)
+		assert.Equal(2, rowsUpdated)
+	})
+
+	tests := []struct {
+		name      string
+		rw        *dbw.RW
+		model     interface{}
+		values    []dbw.ColumnValue
+		where     string
+		opt       []dbw.Option
+		wantErrIs error
+	}{
+		{
+			name:      "missing-underlying",
+			rw:        &dbw.RW{},
+			model:     &dbtest.TestUser{StoreTestUser: &dbtest.StoreTestUser{}},
+			values:    dbw.SetColumnValues(map[string]interface{}{"name": "alice"}),
+			where:     "1=1",
+			wantErrIs: dbw.ErrInvalidParameter,
+		},
+		{
+			name:      "missing-model",
+			rw:        rw,
+			values:    dbw.SetColumnValues(map[string]interface{}{"name": "alice"}),
+			where:     "1=1",
+			wantErrIs: dbw.ErrInvalidParameter,
+		},
+		{
+			name:      "missing-values",
+			rw:        rw,
+			model:     &dbtest.TestUser{StoreTestUser: &dbtest.StoreTestUser{}},
+			where:     "1=1",
+			wantErrIs: dbw.ErrInvalidParameter,
+		},
+		{
+			name:      "missing-where",
+			rw:        rw,
+			model:     &dbtest.TestUser{StoreTestUser: &dbtest.StoreTestUser{}},
+			values:    dbw.SetColumnValues(map[string]interface{}{"name": "alice"}),
+			where:     " ",
+			wantErrIs: dbw.ErrInvalidParameter,
+		},
+		{
+			name:      "unknown-column",
+			rw:        rw,
+			model:     &dbtest.TestUser{StoreTestUser: &dbtest.StoreTestUser{}},
+			values:    dbw.SetColumnValues(map[string]interface{}{"unknown": "alice"}),
+			where:     "1=1",
+			wantErrIs: dbw.ErrInvalidParameter,
+		},
+		{
+			name:      "primary-key",
+			rw:        rw,
+			model:     &dbtest.TestUser{StoreTestUser: &dbtest.StoreTestUser{}},
+			values:    dbw.SetColumnValues(map[string]interface{}{"public_id": "alice"}),
+			where:     "1=1",
+			wantErrIs: dbw.ErrInvalidParameter,
+		},
+		{
+			name:      "excluded-column",
+			rw:        rw,
+			model:     &dbtest.TestUser{StoreTestUser: &dbtest.StoreTestUser{}},
+			values:    dbw.SetColumns([]string{"name"}),
+			where:     "1=1",
+			wantErrIs: dbw.ErrInvalidParameter,
+		},
+		{
+			name:      "with-version",
+			rw:        rw,
+			model:     &dbtest.TestUser{StoreTestUser: &dbtest.StoreTestUser{}},
+			values:    dbw.SetColumnValues(map[string]interface{}{"name": "alice"}),
+			where:     "1=1",
+			opt:       []dbw.Option{dbw.WithVersion(new(uint32))},
+			wantErrIs: dbw.ErrInvalidParameter,
+		},
+		{
+			name:      "with-where",
+			rw:        rw,
+			model:     &dbtest.TestUser{StoreTestUser: &dbtest.StoreTestUser{}},
+			values:    dbw.SetColumnValues(map[string]interface{}{"name": "alice"}),
+			where:     "1=1",
+			opt:       []dbw.Option{dbw.WithWhere("name = ?", "alice")},
+			wantErrIs: dbw.ErrInvalidParameter,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			rowsUpdated, err := tt.rw.UpdateWhere(testCtx, tt.model, tt.values, tt.where, nil, tt.opt...)
+			assert.ErrorIs(err, tt.wantErrIs)
+			assert.Equal(0, rowsUpdated)
+		})
+	}
+}
@@ -38,7 +38,7 @@ type Writer interface {
 	// almost always should be to rollback.
 	// Supported options: WithBatchSize, WithDebug, WithBeforeWrite,
 	// WithAfterWrite, WithReturnRowsAffected, OnConflict, WithVersion,
-	// WithTable, and WithWhere.
+	// WithTable, WithWhere and WithReturning.
 	// WithLookup is not a supported option.
 	CreateItems(ctx context.Context, createItems interface{}, opt ...Option) error
 
@@ -97,3 +97,23 @@ type RetryInfo struct {
 
 // TxHandler defines a handler for a func that writes a transaction for use with DoTx
 type TxHandler func(Reader, Writer) error
+
+// WhereWriter is an optional interface for writers which update and delete
+// the rows matching a where clause, which RW implements.  It's separate from
+// Writer so existing Writer implementations don't need to implement it.
+type WhereWriter interface {
+	// UpdateWhere updates the columns of every row of the model's table which
+	// matches the where clause.  The column values are checked against the
+	// model's schema, and an empty where clause is refused unless the
+	// WithAllRows option is used.  UpdateWhere returns the number of rows
+	// updated or an error.
+	UpdateWhere(ctx context.Context, model interface{}, values []ColumnValue, where string, args []interface{}, opt ...Option) (int, error)
+
+	// DeleteWhere deletes every row of the model's table which matches the
+	// where clause.  An empty where clause is refused unless the WithAllRows
+	// option is used.  DeleteWhere returns the number of rows deleted or an
+	// error.
+	DeleteWhere(ctx context.Context, model interface{}, where string, args []interface{}, opt ...Option) (int, error)
+}
+
+var _ WhereWriter = (*RW)(nil)